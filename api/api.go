@@ -1,95 +1,227 @@
 package api
 
 import (
-	"context"
 	"database/sql"
 	"encoding/json"
-	"log"
 	"net/http"
+	"os"
 
-	"github.com/pedrorochaorg/contactsApi/db"
+	"github.com/pedrorochaorg/contactsApi/auth"
+	"github.com/pedrorochaorg/contactsApi/logging"
+	"github.com/pedrorochaorg/contactsApi/metrics"
 	"github.com/pedrorochaorg/contactsApi/repos"
+	"github.com/pedrorochaorg/contactsApi/tracing"
 )
 
 const (
 	ContentReady     = string("Content Ready")
 	ErrNotFound     = string("Page not found")
+	MethodNotAllowed = "Method not allowed!"
 	JsonContentType = "application/json"
 )
 
 type API struct {
-	db *sql.DB
+	db     *sql.DB
+	tokens *auth.Verifier
+	logger *logging.Logger
+	tracer *tracing.Tracer
+
+	users    repos.UserRepo
+	contacts repos.ContactRepo
+	keys     repos.APIKeyRepo
 	http.Handler
 }
 
-func NewAPI(db *sql.DB) *API {
-	handler := new(API)
+// Option configures the API
+type Option func(a *API)
 
-	handler.db = db
+// WithTokenVerifier accepts the JWT bearer tokens verified by the verifier, besides the API keys stored in the
+// database
+func WithTokenVerifier(tokens *auth.Verifier) Option {
+	return func(a *API) {
+		a.tokens = tokens
+	}
+}
 
-	initDB(db)
+// WithLogger writes the log entries of the requests with the logger, by default they're written to the standard
+// error
+func WithLogger(logger *logging.Logger) Option {
+	return func(a *API) {
+		a.logger = logger
+	}
+}
 
-	router := http.NewServeMux()
+// WithTracer records a trace of every request with the tracer, spanning the handlers and the repositories. Requests
+// aren't traced by default.
+func WithTracer(tracer *tracing.Tracer) Option {
+	return func(a *API) {
+		a.tracer = tracer
+	}
+}
 
-	repository := repos.NewUserRepository(db)
-	router.Handle("/users/", NewUserHandler(&repository))
+// WithRepositories serves the requests from the repositories, like the ones of a repos.MemoryStore, instead of the
+// Postgres repositories of the database given to NewAPI
+func WithRepositories(users repos.UserRepo, contacts repos.ContactRepo, keys repos.APIKeyRepo) Option {
+	return func(a *API) {
+		a.users = users
+		a.contacts = contacts
+		a.keys = keys
+	}
+}
 
+// NewAPI builds the router of the API, every route apart from the health checks and the metrics requires
+// authentication
+func NewAPI(db *sql.DB, opts ...Option) *API {
+	handler := new(API)
 
-	handler.Handler = router
-	return handler
-}
+	handler.db = db
+	handler.logger = logging.New(os.Stderr)
+	for _, opt := range opts {
+		opt(handler)
+	}
 
-func initDB(database *sql.DB) {
+	registry := metrics.NewRegistry()
+	if db != nil {
+		registry.Register(metrics.NewDBStats(db))
+	}
+	// The repositories given by WithRepositories aren't backed by Postgres, their spans don't name a database system
+	system := ""
+	if handler.users == nil {
+		userRepository := repos.NewUserRepository(db)
+		contactRepository := repos.NewContactRepository(db)
+		keyRepository := repos.NewAPIKeyRepository(db)
+		handler.users, handler.contacts, handler.keys = &userRepository, &contactRepository, &keyRepository
+		system = repos.SystemPostgres
+	}
 
-	log.Println("Initializing database")
-	for _, stmt := range db.InitStatements {
-		_, err := database.ExecContext(context.Background(), stmt)
-		if err != nil {
-			log.Fatalf("failed to execute statement in database: %s, %s", stmt, err)
-		}
+	hooks := []repos.Hook{repos.Timed(repositoryObserver(registry))}
+	if handler.tracer != nil {
+		hooks = append(hooks, repos.Traced(system))
 	}
 
-}
+	authenticator := auth.New(
+		auth.WithAPIKeys(auth.NewKeyAuthenticator(repos.ObserveAPIKeys(handler.keys, hooks...))),
+		auth.WithTokens(handler.tokens),
+	)
 
+	router := &Router{}
+	router.Use(RequestID())
+	if handler.tracer != nil {
+		router.Use(Trace(handler.tracer))
+	}
+	router.Use(Logging(handler.logger), Instrument(registry))
+
+	health := NewHealthHandler(db)
+	router.Add("/healthz", http.MethodGet, httpHandler(http.HandlerFunc(health.Liveness)))
+	router.Add("/readyz", http.MethodGet, httpHandler(http.HandlerFunc(health.Readiness)))
+	router.Add("/metrics", http.MethodGet, httpHandler(registry))
+
+	users := NewUserHandler(repos.ObserveUsers(handler.users, hooks...),
+		repos.ObserveContacts(handler.contacts, hooks...))
+	router.Mount("/", users.Routes(), Authenticate(authenticator))
+
+	handler.Handler = router
+	return handler
+}
 
-// FailureReply method that encodes a notFoundReply to
+// FailureReply replies with the error, as an application/problem+json document to the clients that accept it and
+// wrapped in the Response envelope otherwise. The id of the request is echoed in both so that clients can report it.
 func FailureReply(er *Error ,w http.ResponseWriter, r *http.Request) {
-	log.Printf("Path: %s, Method: %s, Msg: %s, Status: %d", r.URL.Path, r.Method, er.msg, er.status)
+	w.Header().Add("Vary", "Accept")
 
-	w.Header().Set("content-type", JsonContentType)
-	w.WriteHeader(er.status)
+	if acceptsProblem(r) {
+		problemReply(er, w, r)
+		return
+	}
 
 	response := Response{
-		Status:  false,
-		Message: er.msg,
-		Result:  nil,
+		Status:    false,
+		Message:   er.msg,
+		Result:    er.details,
+		RequestID: logging.RequestID(r.Context()),
+	}
+	if er.details == nil && len(er.violations) > 0 {
+		response.Result = er.violations
 	}
 
-	err := json.NewEncoder(w).Encode(response)
+	body, err := encodeReply(response, r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Fatalf("Failed to encode object %v", response)
+		if er != internalError {
+			FailureReply(internalError, w, r)
+		}
 		return
 	}
+
+	w.Header().Set("content-type", JsonContentType)
+	w.WriteHeader(er.status)
+	writeReply(body, w, r)
 }
 
 
 func SuccessReply(data *Data, w http.ResponseWriter, r *http.Request) {
-	log.Printf("Path: %s, Method: %s, Msg: %s, Status: %d", r.URL.Path, r.Method, data.message, data.status)
+	var body []byte
+
+	// A 204 response can't carry a body
+	if data.status != http.StatusNoContent {
+		response := Response{
+			Status:     true,
+			Message:    data.message,
+			Result:     data.data,
+			Pagination: data.pagination,
+		}
 
-	w.Header().Set("content-type", JsonContentType)
-	w.WriteHeader(data.status)
+		var err error
+		body, err = encodeReply(response, r)
+		if err != nil {
+			FailureReply(internalError, w, r)
+			return
+		}
+		w.Header().Set("content-type", JsonContentType)
+	}
 
-	response := Response{
-		Status:  true,
-		Message: data.message,
-		Result:  data.data,
+	if data.etag != "" {
+		w.Header().Set("ETag", data.etag)
+	}
+	if data.location != "" {
+		w.Header().Set("Location", data.location)
+	}
+	if data.contentLocation != "" {
+		w.Header().Set("Content-Location", data.contentLocation)
 	}
 
-	err := json.NewEncoder(w).Encode(response)
+	w.WriteHeader(data.status)
+	writeReply(body, w, r)
+}
+
+// encodeReply encodes the body of a reply before anything is written, so that a body that can't be encoded is
+// logged and replied as an internal error instead of a partial reply
+func encodeReply(value interface{}, r *http.Request) ([]byte, error) {
+	_, span := tracing.Start(r.Context(), "encode reply")
+	defer span.End()
+
+	body, err := json.Marshal(value)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Fatalf("Failed to encode object %v", response)
+		logging.FromContext(r.Context()).Error(r.Context(), "failed to encode the reply", logging.Fields{
+			"error": err,
+			"path":  r.URL.Path,
+		})
+		return nil, err
+	}
+	return append(body, '\n'), nil
+}
+
+// writeReply writes the encoded body, the status was already sent so a failure, usually a client that went away,
+// can only be logged
+func writeReply(body []byte, w http.ResponseWriter, r *http.Request) {
+	if len(body) == 0 {
 		return
 	}
+
+	_, err := w.Write(body)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn(r.Context(), "failed to write the reply", logging.Fields{
+			"error": err,
+			"path":  r.URL.Path,
+		})
+	}
 }
@@ -1,9 +1,84 @@
 package api
 
-import "testing"
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/auth"
+	"github.com/pedrorochaorg/contactsApi/logging"
+	"github.com/pedrorochaorg/contactsApi/obj"
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
 
 func TestNewAPI(t *testing.T) {
 
+	handler := NewAPI(nil, WithLogger(logging.New(ioutil.Discard)))
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{"health checks don't require credentials", http.MethodGet, "/healthz", http.StatusOK},
+		{"metrics don't require credentials", http.MethodGet, "/metrics", http.StatusOK},
+		{"the users require credentials", http.MethodGet, "/users/", http.StatusUnauthorized},
+		{"the contacts require credentials", http.MethodGet, "/users/1/contacts/2", http.StatusUnauthorized},
+		{"the methods of a user aren't listed without credentials", http.MethodOptions, "/users/1",
+			http.StatusUnauthorized},
+		{"the methods of the contacts aren't disclosed without credentials", http.MethodTrace, "/users/1/contacts",
+			http.StatusUnauthorized},
+		{"unknown paths are not found", http.MethodGet, "/users/1/unknown", http.StatusNotFound},
+		{"health checks only accept GET", http.MethodPost, "/healthz", http.StatusMethodNotAllowed},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req, _ := http.NewRequest(c.method, c.path, nil)
+			response := httptest.NewRecorder()
+
+			handler.ServeHTTP(response, req)
+
+			assert.Equal(t, c.status, response.Code, "Status Code doesn't match")
+		})
+	}
+}
+
+func TestNewAPI_WithRepositories(t *testing.T) {
+	store := repos.NewMemoryStore()
+	key, hash, _ := auth.GenerateKey()
+	_, _ = store.APIKeys().Create(context.Background(), &obj.APIKey{Name: "admin", Admin: true}, hash)
+
+	handler := NewAPI(nil, WithLogger(logging.New(ioutil.Discard)),
+		WithRepositories(store.Users(), store.Contacts(), store.APIKeys()))
+
+	t.Run("the requests are served from the repositories", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/users/",
+			strings.NewReader(`{"first_name": "Pedro", "last_name": "Rocha"}`))
+		req.Header.Set(auth.APIKeyHeader, key)
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusCreated, response.Code, "Status Code doesn't match")
+
+		user, err := store.Users().Get(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "Pedro", user.FirstName)
+	})
+
+	t.Run("ready without a database", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+		response := httptest.NewRecorder()
 
+		handler.ServeHTTP(response, req)
 
-}
\ No newline at end of file
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+	})
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pedrorochaorg/contactsApi/auth"
+	"github.com/pedrorochaorg/contactsApi/logging"
+)
+
+const (
+	MissingCredentials = "The request must be authenticated with an API key or a bearer token!"
+	InvalidCredentials = "The credentials sent with the request are invalid!"
+
+	CodeMissingCredentials = "missing_credentials"
+	CodeInvalidCredentials = "invalid_credentials"
+
+	// Realm protection space announced in the WWW-Authenticate challenges
+	Realm = "contactsApi"
+)
+
+// Authenticate returns a middleware that authenticates every request before passing it to next, with the principal
+// stored in the request context so that handlers and repositories can find it with auth.FromContext. Requests
+// without valid credentials are rejected with a 401 and the WWW-Authenticate challenges of the accepted schemes.
+func Authenticate(authenticator *auth.Authenticator) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r UrlRequest) {
+			principal, err := authenticator.Authenticate(r.R)
+			switch {
+			case err == nil:
+				r.R = r.R.WithContext(auth.NewContext(r.R.Context(), principal))
+				next(w, r)
+			case errors.Is(err, auth.ErrMissingCredentials):
+				challenge(w, "")
+				FailureReply(&Error{msg: MissingCredentials, status: http.StatusUnauthorized,
+					code: CodeMissingCredentials}, w, r.R)
+			case errors.Is(err, auth.ErrInvalidCredentials):
+				logging.FromContext(r.R.Context()).Info(r.R.Context(), "authentication failed", logging.Fields{
+					"error": err,
+				})
+				challenge(w, `error="invalid_token"`)
+				FailureReply(&Error{msg: InvalidCredentials, status: http.StatusUnauthorized,
+					code: CodeInvalidCredentials}, w, r.R)
+			default:
+				// The credentials couldn't be checked, like when the api keys can't be read from the database
+				repositoryFailure(err, routeNotFound, w, r.R)
+			}
+		}
+	}
+}
+
+// challenge adds the WWW-Authenticate challenges of the bearer token and API key schemes, params are appended to
+// the bearer challenge as defined by RFC 6750
+func challenge(w http.ResponseWriter, params string) {
+	bearer := []string{fmt.Sprintf("realm=%q", Realm)}
+	if params != "" {
+		bearer = append(bearer, params)
+	}
+
+	w.Header().Add("WWW-Authenticate", "Bearer "+strings.Join(bearer, ", "))
+	w.Header().Add("WWW-Authenticate", fmt.Sprintf("APIKey realm=%q, header=%q", Realm, auth.APIKeyHeader))
+}
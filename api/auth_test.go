@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/auth"
+	"github.com/pedrorochaorg/contactsApi/obj"
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+// StubAPIKeyRepo stores api keys by their hash
+type StubAPIKeyRepo struct {
+	keys map[string]obj.APIKey
+	err  error
+}
+
+func (s *StubAPIKeyRepo) Create(ctx context.Context, key *obj.APIKey, hash string) (*obj.APIKey, error) {
+	s.keys[hash] = *key
+	return key, nil
+}
+
+func (s *StubAPIKeyRepo) GetByHash(ctx context.Context, hash string) (*obj.APIKey, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	key, ok := s.keys[hash]
+	if !ok {
+		return nil, fmt.Errorf("api key not found: %w", repos.ErrNotFound)
+	}
+	return &key, nil
+}
+
+func (s *StubAPIKeyRepo) Revoke(ctx context.Context, id int) (bool, error) {
+	return false, fmt.Errorf("not implemented")
+}
+
+func TestAuthenticate(t *testing.T) {
+
+	key, hash, err := auth.GenerateKey()
+	if err != nil {
+		t.Fatalf("error generating an api key: %s", err)
+	}
+
+	var principal *auth.Principal
+	next := func(w http.ResponseWriter, r UrlRequest) {
+		principal, _ = auth.FromContext(r.R.Context())
+		w.WriteHeader(http.StatusOK)
+	}
+
+	newHandler := func(repo repos.APIKeyRepo) http.Handler {
+		router := &Router{}
+		router.Add("/users/{id:int}", http.MethodGet, next,
+			Authenticate(auth.New(auth.WithAPIKeys(auth.NewKeyAuthenticator(repo)))))
+		return router
+	}
+
+	handler := newHandler(&StubAPIKeyRepo{keys: map[string]obj.APIKey{hash: {ID: 1, Name: "mobile", UserID: 3}}})
+
+	t.Run("the principal is passed to the next handler", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/users/3", nil)
+		req.Header.Set(auth.APIKeyHeader, key)
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, &auth.Principal{Subject: "key:mobile", UserID: 3}, principal)
+	})
+
+	t.Run("requests without credentials are challenged", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/users/3", nil)
+		req.Header.Set("Accept", ProblemContentType)
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusUnauthorized, response.Code, "Status Code doesn't match")
+		assert.Equal(t, []string{`Bearer realm="contactsApi"`, `APIKey realm="contactsApi", header="X-API-Key"`},
+			response.Header()["Www-Authenticate"])
+		assert.Contains(t, response.Body.String(), CodeMissingCredentials)
+	})
+
+	t.Run("requests with invalid credentials are challenged with the error", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/users/3", nil)
+		req.Header.Set("Authorization", "Bearer abc.def.ghi")
+		req.Header.Set("Accept", ProblemContentType)
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusUnauthorized, response.Code, "Status Code doesn't match")
+		assert.Equal(t, `Bearer realm="contactsApi", error="invalid_token"`, response.Header().Get("WWW-Authenticate"))
+		assert.Contains(t, response.Body.String(), CodeInvalidCredentials)
+	})
+
+	t.Run("credentials that can't be checked aren't rejected as invalid", func(t *testing.T) {
+		unavailable := newHandler(&StubAPIKeyRepo{err: &repos.Error{Kind: repos.ErrUnavailable,
+			Message: "failed to fetch api key from database", Err: fmt.Errorf("connection refused")}})
+
+		req, _ := http.NewRequest(http.MethodGet, "/users/3", nil)
+		req.Header.Set(auth.APIKeyHeader, key)
+		response := httptest.NewRecorder()
+
+		unavailable.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, response.Code, "Status Code doesn't match")
+		assert.Empty(t, response.Header()["Www-Authenticate"])
+	})
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	PreconditionRequired = "The request must send the ETag of the resource in the If-Match header!"
+	PreconditionFailed   = "The resource was changed since the ETag sent in If-Match was obtained!"
+
+	CodePreconditionRequired = "precondition_required"
+	CodePreconditionFailed   = "precondition_failed"
+)
+
+// Replies sent when a write isn't conditional or its condition doesn't hold
+var (
+	preconditionRequired = &Error{msg: PreconditionRequired, status: http.StatusPreconditionRequired,
+		code: CodePreconditionRequired}
+	preconditionFailed = &Error{msg: PreconditionFailed, status: http.StatusPreconditionFailed,
+		code: CodePreconditionFailed}
+)
+
+// etag returns the strong entity tag of a version of a user or contact, the version is increased by every update so
+// it changes whenever the representation does
+func etag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// checkIfMatch verifies the If-Match header of a write against the current version of the resource. Writes without
+// the header are rejected so that clients can't overwrite changes they haven't seen, weak tags never match.
+func checkIfMatch(r *http.Request, version int64) *Error {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return preconditionRequired
+	}
+
+	current := etag(version)
+	for _, tag := range entityTags(header) {
+		if tag == "*" || tag == current {
+			return nil
+		}
+	}
+
+	return preconditionFailed
+}
+
+// notModified reports whether the If-None-Match header of a read matches the entity tag of the current
+// representation, using the weak comparison so that a weak tag sent back by a cache also matches
+func notModified(r *http.Request, current string) bool {
+	for _, tag := range entityTags(r.Header.Get("If-None-Match")) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == current {
+			return true
+		}
+	}
+	return false
+}
+
+// NotModifiedReply replies to a conditional read whose representation didn't change, without a body
+func NotModifiedReply(tag string, w http.ResponseWriter) {
+	w.Header().Set("ETag", tag)
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// entityTags splits the comma separated list of entity tags of an If-Match or If-None-Match header
+func entityTags(header string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
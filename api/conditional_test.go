@@ -0,0 +1,173 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/obj"
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+func TestUserHandler_Conditional(t *testing.T) {
+
+	parsedTime, _ := time.Parse(time.RFC3339, "2019-11-22T10:00:00Z")
+
+	newHandler := func() (http.Handler, repos.ContactRepo) {
+		store := newStore(t, []obj.User{
+			{ID: 1, FirstName: "John", LastName: "Cena", CreatedAt: parsedTime, UpdatedAt: parsedTime, Version: 3},
+		}, []obj.Contact{
+			{ID: 1, UserID: 1, FirstName: "Ana", LastName: "Silva", CreatedAt: parsedTime, UpdatedAt: parsedTime,
+				Version: 7},
+		})
+		return asAdmin(NewUserHandler(store.Users(), store.Contacts())), store.Contacts()
+	}
+
+	t.Run("reads send the version of the resource as a strong ETag", func(t *testing.T) {
+		handler, _ := newHandler()
+
+		req, _ := http.NewRequest(http.MethodGet, "/users/1", nil)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, `"3"`, response.Header().Get("ETag"))
+
+		req, _ = http.NewRequest(http.MethodGet, "/users/1/contacts/1", nil)
+		response = httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, `"7"`, response.Header().Get("ETag"))
+	})
+
+	t.Run("users with embedded contacts have no ETag", func(t *testing.T) {
+		handler, _ := newHandler()
+
+		req, _ := http.NewRequest(http.MethodGet, "/users/1?include=contacts", nil)
+		req.Header.Set("If-None-Match", `"3"`)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Empty(t, response.Header().Get("ETag"))
+	})
+
+	t.Run("If-None-Match", func(t *testing.T) {
+		cases := []struct {
+			header string
+			status int
+		}{
+			{`"3"`, http.StatusNotModified},
+			{`W/"3"`, http.StatusNotModified},
+			{`"1", "3"`, http.StatusNotModified},
+			{`*`, http.StatusNotModified},
+			{`"2"`, http.StatusOK},
+		}
+
+		for _, c := range cases {
+			handler, _ := newHandler()
+
+			req, _ := http.NewRequest(http.MethodGet, "/users/1", nil)
+			req.Header.Set("If-None-Match", c.header)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+
+			assert.Equal(t, c.status, response.Code, "Status Code doesn't match for %s", c.header)
+			assert.Equal(t, `"3"`, response.Header().Get("ETag"))
+			if c.status == http.StatusNotModified {
+				assert.Empty(t, response.Body.String(), "A 304 response shouldn't have a body")
+			}
+		}
+	})
+
+	t.Run("If-Match", func(t *testing.T) {
+		cases := []struct {
+			name   string
+			header string
+			status int
+		}{
+			{"missing", "", http.StatusPreconditionRequired},
+			{"stale", `"2"`, http.StatusPreconditionFailed},
+			{"weak", `W/"3"`, http.StatusPreconditionFailed},
+			{"current", `"3"`, http.StatusOK},
+			{"one of many", `"2", "3"`, http.StatusOK},
+			{"any", `*`, http.StatusOK},
+		}
+
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				handler, _ := newHandler()
+
+				req, _ := http.NewRequest(http.MethodPut, "/users/1",
+					bytes.NewBufferString(`{"first_name": "Dave", "last_name": "Bautista"}`))
+				if c.header != "" {
+					req.Header.Set("If-Match", c.header)
+				}
+				response := httptest.NewRecorder()
+				handler.ServeHTTP(response, req)
+
+				assert.Equal(t, c.status, response.Code, "Status Code doesn't match")
+			})
+		}
+	})
+
+	t.Run("a successful write sends the new ETag", func(t *testing.T) {
+		handler, _ := newHandler()
+
+		req, _ := http.NewRequest(http.MethodPut, "/users/1/contacts/1", bytes.NewBufferString(`{"first_name": "Eva"}`))
+		req.Header.Set("If-Match", `"7"`)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, `"8"`, response.Header().Get("ETag"))
+
+		// The previous ETag no longer matches
+		req, _ = http.NewRequest(http.MethodPut, "/users/1/contacts/1", bytes.NewBufferString(`{"first_name": "Ana"}`))
+		req.Header.Set("If-Match", `"7"`)
+		response = httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, response.Code, "Status Code doesn't match")
+	})
+
+	t.Run("a stale delete keeps the resource", func(t *testing.T) {
+		handler, contacts := newHandler()
+
+		req, _ := http.NewRequest(http.MethodDelete, "/users/1/contacts/1", nil)
+		req.Header.Set("If-Match", `"6"`)
+		req.Header.Set("Accept", ProblemContentType)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		problem, err := getProblemFromResponse(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusPreconditionFailed, response.Code, "Status Code doesn't match")
+		assert.Equal(t, CodePreconditionFailed, problem.Code)
+		_, err = contacts.Get(context.Background(), 1, 1)
+		assert.NoError(t, err, "the contact shouldn't have been deleted")
+	})
+
+	t.Run("a patch without If-Match isn't applied", func(t *testing.T) {
+		handler, contacts := newHandler()
+
+		req, _ := http.NewRequest(http.MethodPatch, "/users/1/contacts/1", bytes.NewBufferString(`{"last_name": null}`))
+		req.Header.Set("content-type", "application/merge-patch+json")
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusPreconditionRequired, response.Code, "Status Code doesn't match")
+		contact, err := contacts.Get(context.Background(), 1, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "Silva", contact.LastName)
+	})
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/pedrorochaorg/contactsApi/obj"
+)
+
+const (
+	ContactCreatedSuccessfully = "Contact successfully created!"
+	ContactUpdatedSuccessfully = "Contact successfully updated!"
+	ContactDeletedSuccessfully = "Contact successfully deleted!"
+	ContactNotFound            = "Contact not found!"
+)
+
+// ownerID returns the user id present in the request path after verifying that the user exists and that the principal of
+// the request can act on it, replying to the client and returning false when it doesn't so that the calling handler
+// can stop processing the request.
+func (u *UserHandler) ownerID(w http.ResponseWriter, r UrlRequest) (int, bool) {
+	userId := r.Int("id")
+
+	if !authorizeUser(w, r.R, userId) {
+		return 0, false
+	}
+
+	_, err := u.repo.Get(r.R.Context(), userId)
+	if err != nil {
+		repositoryFailure(err, userNotFound, w, r.R)
+		return 0, false
+	}
+
+	return userId, true
+}
+
+func (u *UserHandler) listContacts(w http.ResponseWriter, r UrlRequest) {
+
+	userId, ok := u.ownerID(w, r)
+	if !ok {
+		return
+	}
+
+	opts, listErr := listOptions(r)
+	if listErr != nil {
+		FailureReply(listErr, w, r.R)
+		return
+	}
+
+	contacts, page, err := u.contacts.List(r.R.Context(), userId, opts)
+	if err != nil {
+		repositoryFailure(err, routeNotFound, w, r.R)
+		return
+	}
+
+	SuccessReply(
+		&Data{status: http.StatusOK, message: ContentReady, data: contacts, pagination: paginationLinks(r, page)},
+		w,
+		r.R,
+	)
+}
+
+func (u *UserHandler) createContact(w http.ResponseWriter, r UrlRequest) {
+
+	userId, ok := u.ownerID(w, r)
+	if !ok {
+		return
+	}
+
+	contact := obj.Contact{}
+	decodeErr := decodeBody(r.R, &contact, contactWritableFields)
+	if decodeErr != nil {
+		FailureReply(decodeErr, w, r.R)
+		return
+	}
+
+	finalContact, err := u.contacts.Create(r.R.Context(), userId, &contact)
+	if err != nil {
+		repositoryFailure(err, contactNotFound, w, r.R)
+		return
+	}
+
+	location := resourceURL(r, RouteContact, "id", strconv.Itoa(userId), "contactId", strconv.FormatInt(finalContact.ID, 10))
+
+	SuccessReply(
+		&Data{status: http.StatusCreated, message: ContactCreatedSuccessfully, data: finalContact,
+			etag: etag(finalContact.Version), location: location, contentLocation: location},
+		w,
+		r.R,
+	)
+}
+
+func (u *UserHandler) getContact(w http.ResponseWriter, r UrlRequest) {
+
+	userId, ok := u.ownerID(w, r)
+	if !ok {
+		return
+	}
+
+	contactId := r.Int("contactId")
+
+	contact, err := u.contacts.Get(r.R.Context(), userId, contactId)
+	if err != nil {
+		repositoryFailure(err, contactNotFound, w, r.R)
+		return
+	}
+
+	tag := etag(contact.Version)
+	if notModified(r.R, tag) {
+		NotModifiedReply(tag, w)
+		return
+	}
+
+	SuccessReply(
+		&Data{status: http.StatusOK, message: ContentReady, data: contact, etag: tag},
+		w,
+		r.R,
+	)
+}
+
+// updateContact replaces every writable field of the contact with the values in the request body, fields missing
+// from the body are cleared
+func (u *UserHandler) updateContact(w http.ResponseWriter, r UrlRequest) {
+
+	userId, ok := u.ownerID(w, r)
+	if !ok {
+		return
+	}
+
+	contactId := r.Int("contactId")
+
+	stored, err := u.contacts.Get(r.R.Context(), userId, contactId)
+	if err != nil {
+		repositoryFailure(err, contactNotFound, w, r.R)
+		return
+	}
+
+	matchErr := checkIfMatch(r.R, stored.Version)
+	if matchErr != nil {
+		FailureReply(matchErr, w, r.R)
+		return
+	}
+
+	contact := obj.Contact{}
+	decodeErr := decodeBody(r.R, &contact, contactWritableFields)
+	if decodeErr != nil {
+		FailureReply(decodeErr, w, r.R)
+		return
+	}
+	contact.ID = int64(contactId)
+	contact.Version = stored.Version
+
+	finalContact, err := u.contacts.Update(r.R.Context(), userId, &contact)
+	if err != nil {
+		repositoryFailure(err, contactNotFound, w, r.R)
+		return
+	}
+
+	SuccessReply(
+		&Data{status: http.StatusOK, message: ContactUpdatedSuccessfully, data: finalContact,
+			etag: etag(finalContact.Version), contentLocation: r.Path()},
+		w,
+		r.R,
+	)
+}
+
+// patchContact applies a merge patch or JSON patch to the contact, persisting only the fields it changed
+func (u *UserHandler) patchContact(w http.ResponseWriter, r UrlRequest) {
+
+	userId, ok := u.ownerID(w, r)
+	if !ok {
+		return
+	}
+
+	contactId := r.Int("contactId")
+
+	contact, err := u.contacts.Get(r.R.Context(), userId, contactId)
+	if err != nil {
+		repositoryFailure(err, contactNotFound, w, r.R)
+		return
+	}
+
+	matchErr := checkIfMatch(r.R, contact.Version)
+	if matchErr != nil {
+		FailureReply(matchErr, w, r.R)
+		return
+	}
+
+	patched := obj.Contact{}
+	changed, patchErr := applyPatch(r.R, contact, &patched, contactWritableFields)
+	if patchErr != nil {
+		FailureReply(patchErr, w, r.R)
+		return
+	}
+	patched.ID = int64(contactId)
+	patched.Version = contact.Version
+
+	finalContact, err := u.contacts.Patch(r.R.Context(), userId, &patched, changed)
+	if err != nil {
+		repositoryFailure(err, contactNotFound, w, r.R)
+		return
+	}
+
+	SuccessReply(
+		&Data{status: http.StatusOK, message: ContactUpdatedSuccessfully, data: finalContact,
+			etag: etag(finalContact.Version), contentLocation: r.Path()},
+		w,
+		r.R,
+	)
+}
+
+func (u *UserHandler) deleteContact(w http.ResponseWriter, r UrlRequest) {
+
+	userId, ok := u.ownerID(w, r)
+	if !ok {
+		return
+	}
+
+	contactId := r.Int("contactId")
+
+	contact, err := u.contacts.Get(r.R.Context(), userId, contactId)
+	if err != nil {
+		repositoryFailure(err, contactNotFound, w, r.R)
+		return
+	}
+
+	matchErr := checkIfMatch(r.R, contact.Version)
+	if matchErr != nil {
+		FailureReply(matchErr, w, r.R)
+		return
+	}
+
+	_, err = u.contacts.Delete(r.R.Context(), userId, contactId, contact.Version)
+	if err != nil {
+		repositoryFailure(err, contactNotFound, w, r.R)
+		return
+	}
+
+	SuccessReply(
+		&Data{status: http.StatusNoContent, message: ContactDeletedSuccessfully, data: nil},
+		w,
+		r.R,
+	)
+}
@@ -0,0 +1,303 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/obj"
+)
+
+func TestUserHandler_Contacts(t *testing.T) {
+
+	parsedTime, _ := time.Parse(time.RFC3339, "2019-11-22T10:00:00Z")
+
+	userList := []obj.User{
+		{ID: 1, FirstName: "John", LastName: "Cena", CreatedAt: parsedTime, UpdatedAt: parsedTime},
+		{ID: 2, FirstName: "João", LastName: "Cenas", CreatedAt: parsedTime, UpdatedAt: parsedTime},
+	}
+
+	contactList := []obj.Contact{
+		{ID: 1, UserID: 1, FirstName: "Ana", LastName: "Silva", Email: "ana@example.com", Phone: "919236587",
+			CreatedAt: parsedTime, UpdatedAt: parsedTime},
+		{ID: 2, UserID: 1, FirstName: "Rui", LastName: "Sousa", Email: "rui@example.com", Phone: "919236588",
+			CreatedAt: parsedTime, UpdatedAt: parsedTime},
+		{ID: 3, UserID: 2, FirstName: "Eva", LastName: "Costa", Email: "eva@example.com", Phone: "919236589",
+			CreatedAt: parsedTime, UpdatedAt: parsedTime},
+	}
+
+	store := newStore(t, userList, contactList)
+	userHandler := asAdmin(NewUserHandler(store.Users(), store.Contacts()))
+
+	t.Run("list the contacts of a user", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/users/1/contacts", nil)
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		contacts, err := getContactsFromResponse(response.Body)
+
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.ElementsMatch(t, contactList[:2], contacts)
+	})
+
+	t.Run("list the contacts of an unexisting user", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/users/4/contacts", nil)
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		message, err := getResponseMessage(response.Body)
+
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match")
+		assert.Equal(t, UserNotFound, message)
+	})
+
+	t.Run("fetch a contact by id", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/users/1/contacts/2", nil)
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		contact, err := getContactFromResponse(response.Body)
+
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, contactList[1], *contact)
+	})
+
+	t.Run("fetch a contact through a user that doesn't own it", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/users/2/contacts/1", nil)
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		message, err := getResponseMessage(response.Body)
+
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match")
+		assert.Equal(t, ContactNotFound, message)
+	})
+
+	t.Run("fetch a contact using an invalid id format", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/users/1/contacts/invalid", nil)
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		message, err := getResponseMessage(response.Body)
+
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match")
+		assert.Equal(t, ErrNotFound, message)
+	})
+
+	t.Run("create a new contact", func(t *testing.T) {
+		bodyData, err := json.Marshal(map[string]string{
+			"first_name": "José",
+			"last_name":  "Santos",
+			"email":      "jose@example.com",
+			"phone":      "919236580",
+		})
+		if err != nil {
+			t.Fatalf("error marshling request body %s", err)
+		}
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/2/contacts", bytes.NewBuffer(bodyData))
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		contact, err := getContactFromResponse(response.Body)
+
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusCreated, response.Code, "Status Code doesn't match")
+		assert.Equal(t, "José", contact.FirstName)
+		assert.Equal(t, int64(2), contact.UserID)
+		assert.Equal(t, fmt.Sprintf("/users/2/contacts/%d", contact.ID), response.Header().Get("Location"))
+		assert.Equal(t, fmt.Sprintf("/users/2/contacts/%d", contact.ID), response.Header().Get("Content-Location"))
+
+		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/users/2/contacts/%d", contact.ID), nil)
+		response = httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		storedContact, err := getContactFromResponse(response.Body)
+
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, *contact, *storedContact)
+	})
+
+	t.Run("create a new contact with an invalid request body", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/users/1/contacts", bytes.NewBuffer([]byte("some string")))
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		message, err := getResponseMessage(response.Body)
+
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusBadRequest, response.Code, "Status Code doesn't match")
+		assert.Contains(t, message, "invalid character", "Message should contain the string 'invalid character'")
+	})
+
+	t.Run("update an existing contact", func(t *testing.T) {
+		bodyData, err := json.Marshal(map[string]string{
+			"first_name": "Mário",
+			"last_name":  "Figueira",
+			"email":      "mario@example.com",
+			"phone":      "919236581",
+		})
+		if err != nil {
+			t.Fatalf("error marshling request body %s", err)
+		}
+
+		req, _ := http.NewRequest(http.MethodPut, "/users/1/contacts/1", bytes.NewBuffer(bodyData))
+		req.Header.Set("If-Match", "*")
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		contact, err := getContactFromResponse(response.Body)
+
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, "Mário", contact.FirstName)
+		assert.Equal(t, "mario@example.com", contact.Email)
+		assert.Equal(t, int64(1), contact.ID)
+		assert.Equal(t, int64(1), contact.UserID)
+		assert.Equal(t, "/users/1/contacts/1", response.Header().Get("Content-Location"))
+	})
+
+	t.Run("update a contact through a user that doesn't own it", func(t *testing.T) {
+		bodyData, _ := json.Marshal(map[string]string{"first_name": "Mário"})
+
+		req, _ := http.NewRequest(http.MethodPut, "/users/1/contacts/3", bytes.NewBuffer(bodyData))
+		req.Header.Set("If-Match", "*")
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		message, err := getResponseMessage(response.Body)
+
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match")
+		assert.Equal(t, ContactNotFound, message)
+	})
+
+	t.Run("delete a contact by id", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodDelete, "/users/1/contacts/2", nil)
+		req.Header.Set("If-Match", "*")
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusNoContent, response.Code, "Status Code doesn't match")
+		assert.Empty(t, response.Body.String(), "A 204 response shouldn't have a body")
+
+		req, _ = http.NewRequest(http.MethodGet, "/users/1/contacts/2", nil)
+		response = httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match")
+	})
+
+	t.Run("delete a contact through a user that doesn't own it", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodDelete, "/users/1/contacts/3", nil)
+		req.Header.Set("If-Match", "*")
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		message, err := getResponseMessage(response.Body)
+
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match")
+		assert.Equal(t, ContactNotFound, message)
+	})
+}
+
+func getContactFromResponse(response *bytes.Buffer) (*obj.Contact, error) {
+	responseObject := Response{}
+
+	err := json.NewDecoder(response).Decode(&responseObject)
+	if err != nil {
+		return nil, err
+	}
+
+	responseData, err := json.Marshal(responseObject.Result)
+	if err != nil {
+		return nil, err
+	}
+
+	contact := obj.Contact{}
+	err = json.Unmarshal(responseData, &contact)
+	if err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+func getContactsFromResponse(response *bytes.Buffer) ([]obj.Contact, error) {
+	responseObject := Response{}
+
+	err := json.NewDecoder(response).Decode(&responseObject)
+	if err != nil {
+		return nil, err
+	}
+
+	responseData, err := json.Marshal(responseObject.Result)
+	if err != nil {
+		return nil, err
+	}
+
+	contacts := []obj.Contact{}
+	err = json.Unmarshal(responseData, &contacts)
+	if err != nil {
+		return nil, err
+	}
+	return contacts, nil
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/pedrorochaorg/contactsApi/logging"
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+const (
+	ConflictingData    = "The request conflicts with existing data!"
+	InvalidData        = "The request contains invalid values!"
+	ServiceUnavailable = "Service temporarily unavailable!"
+	InternalError      = "Internal server error!"
+)
+
+// Replies sent when the resource a request refers to doesn't exist
+var (
+	routeNotFound   = &Error{msg: ErrNotFound, status: http.StatusNotFound, code: CodeNotFound}
+	userNotFound    = &Error{msg: UserNotFound, status: http.StatusNotFound, code: CodeUserNotFound}
+	contactNotFound = &Error{msg: ContactNotFound, status: http.StatusNotFound, code: CodeContactNotFound}
+)
+
+// internalError reply sent when a request fails for an unexpected reason
+var internalError = &Error{msg: InternalError, status: http.StatusInternalServerError, code: CodeInternalError}
+
+// repositoryFailure replies to a request whose repository call failed, notFound is the reply sent when the requested
+// resource doesn't exist. The failures replied with a server error are logged with their cause.
+func repositoryFailure(err error, notFound *Error, w http.ResponseWriter, r *http.Request) {
+	reply := repositoryError(err, notFound)
+
+	level := logging.LevelDebug
+	if reply.status >= http.StatusInternalServerError {
+		level = logging.LevelError
+	}
+	logging.FromContext(r.Context()).Log(r.Context(), level, "repository call failed", logging.Fields{
+		"error": err,
+		"path":  r.URL.Path,
+	})
+
+	FailureReply(reply, w, r)
+}
+
+// repositoryError maps the kinds of repository errors to the reply sent to the client, this is the only place where
+// the status code of a failed repository call is decided. The cause of unexpected failures isn't sent to the client.
+func repositoryError(err error, notFound *Error) *Error {
+	var fieldErrors repos.FieldErrors
+
+	switch {
+	case errors.Is(err, repos.ErrNotFound):
+		return notFound
+	case errors.Is(err, repos.ErrPreconditionFailed):
+		return preconditionFailed
+	case errors.Is(err, repos.ErrConflict):
+		return &Error{msg: ConflictingData, status: http.StatusConflict, code: CodeConflict}
+	case errors.As(err, &fieldErrors):
+		return &Error{msg: BadListParameters, status: http.StatusBadRequest, code: CodeBadListParameters,
+			violations: fieldViolations(fieldErrors)}
+	case errors.Is(err, repos.ErrValidation):
+		return &Error{msg: InvalidData, status: http.StatusUnprocessableEntity, code: CodeInvalidData}
+	case errors.Is(err, repos.ErrUnavailable):
+		return &Error{msg: ServiceUnavailable, status: http.StatusServiceUnavailable, code: CodeServiceUnavailable}
+	default:
+		return internalError
+	}
+}
+
+// fieldViolations converts the list parameters rejected by a repository into violations
+func fieldViolations(fieldErrors repos.FieldErrors) []Violation {
+	violations := make([]Violation, len(fieldErrors))
+	for i, fieldError := range fieldErrors {
+		violations[i] = Violation{Field: fieldError.Field, Reason: fieldError.Reason}
+	}
+	return violations
+}
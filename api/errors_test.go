@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/obj"
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+func TestRepositoryError(t *testing.T) {
+
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		msg    string
+	}{
+		{"not found", fmt.Errorf("no rows: %w", repos.ErrNotFound), http.StatusNotFound, UserNotFound},
+		{"conflict", fmt.Errorf("duplicated: %w", repos.ErrConflict), http.StatusConflict, ConflictingData},
+		{"precondition failed", fmt.Errorf("changed: %w", repos.ErrPreconditionFailed), http.StatusPreconditionFailed,
+			PreconditionFailed},
+		{"validation", fmt.Errorf("too long: %w", repos.ErrValidation), http.StatusUnprocessableEntity, InvalidData},
+		{"field errors", repos.FieldErrors{{Field: "age", Reason: "unknown field"}}, http.StatusBadRequest,
+			BadListParameters},
+		{"unavailable", fmt.Errorf("refused: %w", repos.ErrUnavailable), http.StatusServiceUnavailable,
+			ServiceUnavailable},
+		{"unexpected", fmt.Errorf("pq: syntax error"), http.StatusInternalServerError, InternalError},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			replyErr := repositoryError(c.err, userNotFound)
+
+			assert.Equal(t, c.status, replyErr.status, "Status Code doesn't match")
+			assert.Equal(t, c.msg, replyErr.msg, "Message doesn't match")
+		})
+	}
+}
+
+func TestUserHandler_RepositoryErrors(t *testing.T) {
+
+	t.Run("a database outage isn't reported as a missing user", func(t *testing.T) {
+		store := repos.NewMemoryStore()
+		userHandler := asAdmin(NewUserHandler(&unavailableUserRepo{store.Users()}, store.Contacts()))
+
+		req, _ := http.NewRequest(http.MethodGet, "/users/1", nil)
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		message, err := getResponseMessage(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusServiceUnavailable, response.Code, "Status Code doesn't match")
+		assert.Equal(t, ServiceUnavailable, message, "Message doesn't match")
+	})
+}
+
+// unavailableUserRepo user repository whose database can't be reached
+type unavailableUserRepo struct {
+	repos.UserRepo
+}
+
+func (s *unavailableUserRepo) Get(ctx context.Context, id int) (*obj.User, error) {
+	return nil, fmt.Errorf("dial tcp: connection refused: %w", repos.ErrUnavailable)
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pedrorochaorg/contactsApi/db"
+)
+
+const (
+	Alive    = "Alive"
+	Ready    = "Ready"
+	NotReady = "Not ready"
+
+	CheckOk     = "ok"
+	CheckFailed = "failed"
+
+	// ReadinessTimeout maximum duration of the readiness checks, a database that takes longer to answer is
+	// considered unavailable
+	ReadinessTimeout = 2 * time.Second
+)
+
+// database subset of *sql.DB used by the readiness checks
+type database interface {
+	PingContext(ctx context.Context) error
+	Stats() sql.DBStats
+}
+
+// Check result of a single readiness check
+type Check struct {
+	Status   string      `json:"status"`
+	Duration string      `json:"duration"`
+	Error    string      `json:"error,omitempty"`
+	Details  interface{} `json:"details,omitempty"`
+}
+
+// Health breakdown of every readiness check sent by /readyz
+type Health struct {
+	Checks map[string]Check `json:"checks"`
+}
+
+// PoolStats connection pool statistics reported by the readiness check
+type PoolStats struct {
+	MaxOpenConnections int    `json:"maxOpenConnections"`
+	OpenConnections    int    `json:"openConnections"`
+	InUse              int    `json:"inUse"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"waitCount"`
+	WaitDuration       string `json:"waitDuration"`
+}
+
+// HealthHandler serves the liveness and readiness endpoints
+type HealthHandler struct {
+	db            database
+	version       func(ctx context.Context) (int, error)
+	latestVersion int
+	timeout       time.Duration
+}
+
+// NewHealthHandler instantiates the health handler checking the database connection and it's schema version, without
+// a database, like when the data is kept in memory, it's always ready
+func NewHealthHandler(conn *sql.DB) *HealthHandler {
+	if conn == nil {
+		return &HealthHandler{timeout: ReadinessTimeout}
+	}
+
+	return &HealthHandler{
+		db:            conn,
+		version:       db.NewMigrator(conn, db.Migrations).Version,
+		latestVersion: db.LatestVersion(),
+		timeout:       ReadinessTimeout,
+	}
+}
+
+// Liveness replies successfully as long as the process is able to serve requests
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	SuccessReply(&Data{status: http.StatusOK, message: Alive}, w, r)
+}
+
+// Readiness replies successfully when the database is reachable and the schema is at the expected version, otherwise
+// it replies with 503 so that no traffic is sent to this replica. Every check is reported in the response.
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	health := Health{Checks: map[string]Check{}}
+	if h.db != nil {
+		health.Checks["database"] = timeCheck(func() (interface{}, error) { return nil, h.db.PingContext(ctx) })
+		health.Checks["migrations"] = timeCheck(func() (interface{}, error) { return h.checkVersion(ctx) })
+		health.Checks["pool"] = timeCheck(func() (interface{}, error) { return poolStats(h.db.Stats()), nil })
+	}
+
+	for _, check := range health.Checks {
+		if check.Status != CheckOk {
+			FailureReply(&Error{msg: NotReady, status: http.StatusServiceUnavailable, code: CodeNotReady,
+				details: health}, w, r)
+			return
+		}
+	}
+
+	SuccessReply(&Data{status: http.StatusOK, message: Ready, data: health}, w, r)
+}
+
+// checkVersion fails when the schema isn't at the latest migration version known by this build
+func (h *HealthHandler) checkVersion(ctx context.Context) (interface{}, error) {
+	version, err := h.version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]int{"version": version, "expected": h.latestVersion}
+	if version != h.latestVersion {
+		return details, fmt.Errorf("schema is at version %d but version %d is expected", version, h.latestVersion)
+	}
+
+	return details, nil
+}
+
+// timeCheck runs a check measuring how long it took
+func timeCheck(fn func() (interface{}, error)) Check {
+	start := time.Now()
+	details, err := fn()
+
+	check := Check{Status: CheckOk, Duration: time.Since(start).String(), Details: details}
+	if err != nil {
+		check.Status = CheckFailed
+		check.Error = err.Error()
+	}
+
+	return check
+}
+
+func poolStats(stats sql.DBStats) PoolStats {
+	return PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDuration:       stats.WaitDuration.String(),
+	}
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthHandler(t *testing.T) {
+
+	t.Run("liveness doesn't depend on the database", func(t *testing.T) {
+		handler := &HealthHandler{db: &StubDatabase{err: fmt.Errorf("connection refused")}}
+
+		req, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+		response := httptest.NewRecorder()
+
+		handler.Liveness(response, req)
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+	})
+
+	t.Run("ready when the database answers and the schema is up to date", func(t *testing.T) {
+		handler := newStubHealthHandler(&StubDatabase{stats: sql.DBStats{OpenConnections: 3, InUse: 1, Idle: 2}}, 2)
+
+		req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+		response := httptest.NewRecorder()
+
+		handler.Readiness(response, req)
+
+		health, err := getHealthFromResponse(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, CheckOk, health.Checks["database"].Status)
+		assert.Equal(t, CheckOk, health.Checks["migrations"].Status)
+		assert.Equal(t, CheckOk, health.Checks["pool"].Status)
+		assert.Equal(t, float64(3), health.Checks["pool"].Details.(map[string]interface{})["openConnections"])
+	})
+
+	t.Run("not ready when the database can't be reached", func(t *testing.T) {
+		handler := newStubHealthHandler(&StubDatabase{err: fmt.Errorf("connection refused")}, 2)
+
+		req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+		response := httptest.NewRecorder()
+
+		handler.Readiness(response, req)
+
+		health, err := getHealthFromResponse(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusServiceUnavailable, response.Code, "Status Code doesn't match")
+		assert.Equal(t, CheckFailed, health.Checks["database"].Status)
+		assert.Equal(t, "connection refused", health.Checks["database"].Error)
+	})
+
+	t.Run("not ready when the database doesn't answer in time", func(t *testing.T) {
+		handler := newStubHealthHandler(&StubDatabase{delay: time.Second}, 2)
+		handler.timeout = 10 * time.Millisecond
+
+		req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+		response := httptest.NewRecorder()
+
+		handler.Readiness(response, req)
+
+		health, err := getHealthFromResponse(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusServiceUnavailable, response.Code, "Status Code doesn't match")
+		assert.Equal(t, context.DeadlineExceeded.Error(), health.Checks["database"].Error)
+	})
+
+	t.Run("not ready when the schema isn't at the expected version", func(t *testing.T) {
+		handler := newStubHealthHandler(&StubDatabase{}, 1)
+
+		req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+		response := httptest.NewRecorder()
+
+		handler.Readiness(response, req)
+
+		health, err := getHealthFromResponse(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusServiceUnavailable, response.Code, "Status Code doesn't match")
+		assert.Equal(t, CheckOk, health.Checks["database"].Status)
+		assert.Equal(t, CheckFailed, health.Checks["migrations"].Status)
+		assert.Contains(t, health.Checks["migrations"].Error, "version 1 but version 2 is expected")
+	})
+}
+
+// newStubHealthHandler instantiates a health handler expecting schema version 2 with the database at version
+func newStubHealthHandler(database database, version int) *HealthHandler {
+	return &HealthHandler{
+		db: database,
+		version: func(ctx context.Context) (int, error) {
+			return version, nil
+		},
+		latestVersion: 2,
+		timeout:       ReadinessTimeout,
+	}
+}
+
+type StubDatabase struct {
+	err   error
+	delay time.Duration
+	stats sql.DBStats
+}
+
+func (s *StubDatabase) PingContext(ctx context.Context) error {
+	select {
+	case <-time.After(s.delay):
+		return s.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *StubDatabase) Stats() sql.DBStats {
+	return s.stats
+}
+
+func getHealthFromResponse(response *bytes.Buffer) (*Health, error) {
+	body := struct {
+		Result Health `json:"result"`
+	}{}
+
+	err := json.NewDecoder(response).Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &body.Result, nil
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/pedrorochaorg/contactsApi/logging"
+	"github.com/pedrorochaorg/contactsApi/tracing"
+)
+
+const (
+	// RequestIDHeader header that carries the id of a request, it's propagated from the client when present and
+	// always sent back in the reply
+	RequestIDHeader = "X-Request-ID"
+
+	// maxRequestIDLength longest request id accepted from a client
+	maxRequestIDLength = 128
+)
+
+// RequestID returns a middleware that identifies every request, with the id sent by the client in the X-Request-ID
+// header or a random one when it's missing or invalid. The id is stored in the request context, where it's found with
+// logging.RequestID, and sent back in the X-Request-ID header of the reply.
+func RequestID() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r UrlRequest) {
+			id := r.R.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+
+			w.Header().Set(RequestIDHeader, id)
+			r.R = r.R.WithContext(logging.WithRequestID(r.R.Context(), id))
+			next(w, r)
+		}
+	}
+}
+
+// validRequestID reports whether a request id sent by a client can be used, it must be made of printable ASCII
+// characters without spaces so that it can't forge log entries or headers
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random 128 bit id encoded as hex
+func newRequestID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// Logging returns a middleware that writes an entry for every request once it's served, with the method, the route
+// template, the status, latency and size of the reply, the address of the client and the id of the trace when the
+// request is traced. The logger is stored in the request context, where handlers find it with logging.FromContext.
+//
+// Replies with a server error status are logged with the error level and the rest with the info level.
+func Logging(logger *logging.Logger) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r UrlRequest) {
+			start := time.Now()
+
+			r.R = r.R.WithContext(logging.NewContext(r.R.Context(), logger))
+			recorder := &statusRecorder{ResponseWriter: w}
+			next(recorder, r)
+
+			fields := logging.Fields{
+				"method":      r.R.Method,
+				"path":        r.R.URL.Path,
+				"status":      recorder.Status(),
+				"latency_ms":  float64(time.Since(start)) / float64(time.Millisecond),
+				"bytes":       recorder.bytes,
+				"remote_addr": r.R.RemoteAddr,
+			}
+			if r.route != nil {
+				fields["route"] = r.route.Pattern()
+			}
+			if span := tracing.SpanFromContext(r.R.Context()).SpanContext(); span.IsValid() {
+				fields["trace_id"] = span.TraceID.String()
+			}
+
+			level := logging.LevelInfo
+			if recorder.Status() >= http.StatusInternalServerError {
+				level = logging.LevelError
+			}
+			logger.Log(r.R.Context(), level, "request served", fields)
+		}
+	}
+}
+
+// statusRecorder keeps the status and the number of bytes of the body written in the reply
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(p)
+	s.bytes += n
+	return n, err
+}
+
+// Status returns the status written in the reply, a reply without a status is sent as a 200
+func (s *statusRecorder) Status() int {
+	if s.status == 0 {
+		return http.StatusOK
+	}
+	return s.status
+}
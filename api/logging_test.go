@@ -0,0 +1,174 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/logging"
+)
+
+func TestRequestID(t *testing.T) {
+
+	router := &Router{}
+	router.Use(RequestID())
+	router.Add("/users/{id:int}", http.MethodGet, func(w http.ResponseWriter, r UrlRequest) {
+		FailureReply(userNotFound, w, r.R)
+	})
+
+	serve := func(id, accept string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, "/users/1", nil)
+		if id != "" {
+			req.Header.Set(RequestIDHeader, id)
+		}
+		req.Header.Set("Accept", accept)
+		response := httptest.NewRecorder()
+
+		router.ServeHTTP(response, req)
+
+		return response
+	}
+
+	t.Run("propagate the id sent by the client", func(t *testing.T) {
+		response := serve("client-id-1", JsonContentType)
+
+		body := Response{}
+		err := json.NewDecoder(response.Body).Decode(&body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, "client-id-1", response.Header().Get(RequestIDHeader))
+		assert.Equal(t, "client-id-1", body.RequestID, "The id isn't echoed in the error")
+	})
+
+	t.Run("echo the id in problem documents", func(t *testing.T) {
+		response := serve("client-id-2", ProblemContentType)
+
+		problem := Problem{}
+		err := json.NewDecoder(response.Body).Decode(&problem)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, "client-id-2", problem.RequestID, "The id isn't echoed in the problem")
+	})
+
+	t.Run("generate an id when the client doesn't send one", func(t *testing.T) {
+		first := serve("", JsonContentType).Header().Get(RequestIDHeader)
+		second := serve("", JsonContentType).Header().Get(RequestIDHeader)
+
+		assert.Len(t, first, 32)
+		assert.NotEqual(t, first, second, "Every request must get a different id")
+	})
+
+	t.Run("replace ids that can't be logged safely", func(t *testing.T) {
+		for _, id := range []string{"with space", "new\nline", strings.Repeat("a", maxRequestIDLength+1)} {
+			response := serve(id, JsonContentType)
+
+			assert.Len(t, response.Header().Get(RequestIDHeader), 32, "The id %q was accepted", id)
+		}
+	})
+}
+
+func TestLogging(t *testing.T) {
+
+	out := &bytes.Buffer{}
+	router := &Router{}
+	router.Use(RequestID(), Logging(logging.New(out)))
+	router.Add("/users/{id:int}", http.MethodGet, func(w http.ResponseWriter, r UrlRequest) {
+		SuccessReply(&Data{status: http.StatusOK, message: ContentReady, data: r.Vars["id"]}, w, r.R)
+	})
+	router.Add("/broken", http.MethodGet, func(w http.ResponseWriter, r UrlRequest) {
+		SuccessReply(&Data{status: http.StatusOK, message: ContentReady, data: make(chan int)}, w, r.R)
+	})
+
+	serve := func(method, path string) (*httptest.ResponseRecorder, []map[string]interface{}) {
+		out.Reset()
+
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set(RequestIDHeader, "abc")
+		req.RemoteAddr = "192.0.2.1:1234"
+		response := httptest.NewRecorder()
+
+		router.ServeHTTP(response, req)
+
+		entries := []map[string]interface{}{}
+		decoder := json.NewDecoder(out)
+		for decoder.More() {
+			entry := map[string]interface{}{}
+			err := decoder.Decode(&entry)
+			if err != nil {
+				t.Fatalf("error while unmarshling the log entry %s", err)
+			}
+			entries = append(entries, entry)
+		}
+
+		return response, entries
+	}
+
+	t.Run("log a served request", func(t *testing.T) {
+		response, entries := serve(http.MethodGet, "/users/12")
+
+		if !assert.Len(t, entries, 1) {
+			return
+		}
+		entry := entries[0]
+
+		assert.Equal(t, "info", entry["level"])
+		assert.Equal(t, "abc", entry["request_id"])
+		assert.Equal(t, http.MethodGet, entry["method"])
+		assert.Equal(t, "/users/{id:int}", entry["route"])
+		assert.Equal(t, "/users/12", entry["path"])
+		assert.Equal(t, float64(http.StatusOK), entry["status"])
+		assert.Equal(t, float64(response.Body.Len()), entry["bytes"])
+		assert.Equal(t, "192.0.2.1:1234", entry["remote_addr"])
+		assert.Contains(t, entry, "latency_ms")
+	})
+
+	t.Run("log HEAD requests without the body that was dropped", func(t *testing.T) {
+		response, entries := serve(http.MethodHead, "/users/12")
+
+		if !assert.Len(t, entries, 1) {
+			return
+		}
+
+		assert.Empty(t, response.Body.String())
+		assert.Equal(t, float64(http.StatusOK), entries[0]["status"])
+		assert.Equal(t, float64(0), entries[0]["bytes"])
+	})
+
+	t.Run("log requests that don't match a route without one", func(t *testing.T) {
+		_, entries := serve(http.MethodGet, "/unknown")
+
+		if !assert.Len(t, entries, 1) {
+			return
+		}
+
+		assert.Equal(t, float64(http.StatusNotFound), entries[0]["status"])
+		assert.NotContains(t, entries[0], "route")
+	})
+
+	t.Run("reply an internal error when the reply can't be encoded", func(t *testing.T) {
+		response, entries := serve(http.MethodGet, "/broken")
+
+		message, err := getResponseMessage(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusInternalServerError, response.Code, "Status Code doesn't match")
+		assert.Equal(t, InternalError, message)
+
+		if !assert.Len(t, entries, 2) {
+			return
+		}
+		assert.Equal(t, "failed to encode the reply", entries[0]["msg"])
+		assert.Equal(t, "error", entries[1]["level"])
+	})
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pedrorochaorg/contactsApi/metrics"
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+// UnmatchedRoute route label of the requests that didn't match any route, so that unknown paths don't create a series
+// each
+const UnmatchedRoute = "unmatched"
+
+// Instrument returns a middleware that counts the requests and observes their latency, labelled by method, route
+// template and status, in metrics registered in the registry
+func Instrument(registry *metrics.Registry) Middleware {
+	requests := metrics.NewCounterVec("http_requests_total", "Total number of HTTP requests served.",
+		"method", "route", "status")
+	durations := metrics.NewHistogramVec("http_request_duration_seconds", "Latency of the HTTP requests served.",
+		metrics.DefaultBuckets, "method", "route", "status")
+	registry.Register(requests)
+	registry.Register(durations)
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r UrlRequest) {
+			start := time.Now()
+
+			recorder := &statusRecorder{ResponseWriter: w}
+			next(recorder, r)
+
+			route := UnmatchedRoute
+			if r.route != nil {
+				route = r.route.Pattern()
+			}
+			status := strconv.Itoa(recorder.Status())
+
+			requests.Inc(r.R.Method, route, status)
+			durations.Observe(time.Since(start).Seconds(), r.R.Method, route, status)
+		}
+	}
+}
+
+// repositoryObserver returns an observer that records the duration of the repository calls, labelled by repository
+// and method, in a histogram registered in the registry
+func repositoryObserver(registry *metrics.Registry) repos.Observer {
+	durations := metrics.NewHistogramVec("repository_query_duration_seconds",
+		"Duration of the calls to the repository methods.", metrics.DefaultBuckets, "repository", "method")
+	registry.Register(durations)
+
+	return func(repository, method string, duration time.Duration, err error) {
+		durations.Observe(duration.Seconds(), repository, method)
+	}
+}
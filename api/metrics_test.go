@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/metrics"
+)
+
+func TestInstrument(t *testing.T) {
+
+	registry := metrics.NewRegistry()
+	router := &Router{}
+	router.Use(Instrument(registry))
+	router.Add("/users/{id:int}", http.MethodGet, func(w http.ResponseWriter, r UrlRequest) {
+		w.WriteHeader(http.StatusOK)
+	})
+	router.Add("/metrics", http.MethodGet, httpHandler(registry))
+
+	for _, path := range []string{"/users/1", "/users/2", "/users/3/unknown"} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	for _, method := range []string{http.MethodDelete, http.MethodOptions} {
+		req, _ := http.NewRequest(method, "/users/4", nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, req)
+
+	body := response.Body.String()
+	assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/users/{id:int}",status="200"} 2`,
+		"Requests are counted by route template")
+	assert.Contains(t, body, `http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `http_requests_total{method="DELETE",route="/users/{id:int}",status="405"} 1`,
+		"Methods the path doesn't accept are counted by it's route template")
+	assert.Contains(t, body, `http_requests_total{method="OPTIONS",route="/users/{id:int}",status="204"} 1`)
+	assert.Contains(t, body,
+		`http_request_duration_seconds_count{method="GET",route="/users/{id:int}",status="200"} 2`)
+	assert.NotContains(t, body, "/users/1", "Raw paths can't be used as labels")
+}
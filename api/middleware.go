@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+)
+
+// HandlerFunc handles a request matched by the router, with the variables of the matched path
+type HandlerFunc func(w http.ResponseWriter, r UrlRequest)
+
+// Middleware wraps a handler function with logic that runs before and after it. A middleware short-circuits the
+// request by replying without calling next.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Chain composes the middlewares into a single one, the first middleware is the outermost so it's the first to see
+// the request and the last to see the reply
+func Chain(middlewares ...Middleware) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+		return next
+	}
+}
+
+// httpHandler adapts a standard library handler to the router
+func httpHandler(handler http.Handler) HandlerFunc {
+	return func(w http.ResponseWriter, r UrlRequest) {
+		handler.ServeHTTP(w, r.R)
+	}
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recording returns a middleware that records it's name in the calls before and after calling next
+func recording(name string, calls *[]string) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r UrlRequest) {
+			*calls = append(*calls, name)
+			next(w, r)
+			*calls = append(*calls, "/"+name)
+		}
+	}
+}
+
+func TestRouter_Middlewares(t *testing.T) {
+
+	serve := func(router *Router, method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/users/"+path, nil)
+		response := httptest.NewRecorder()
+
+		router.Serve(response, req, path)
+
+		return response
+	}
+
+	t.Run("middlewares run from the global ones to the ones of the route", func(t *testing.T) {
+		calls := []string{}
+		router := &Router{}
+
+		users := router.Group("/", recording("users", &calls))
+		contacts := users.Group("/{id}/contacts", recording("contacts", &calls))
+		contacts.Add("/{contactId}", http.MethodGet, func(w http.ResponseWriter, r UrlRequest) {
+			calls = append(calls, "handler "+r.Vars["id"]+" "+r.Vars["contactId"])
+			w.WriteHeader(http.StatusOK)
+		}, recording("route", &calls))
+
+		// Global middlewares apply to the routes registered before them
+		router.Use(recording("global", &calls))
+
+		response := serve(router, http.MethodGet, "1/contacts/2")
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, []string{"global", "users", "contacts", "route", "handler 1 2", "/route", "/contacts",
+			"/users", "/global"}, calls)
+	})
+
+	t.Run("a middleware short-circuits the request by not calling next", func(t *testing.T) {
+		calls := []string{}
+		router := &Router{}
+
+		deny := func(next HandlerFunc) HandlerFunc {
+			return func(w http.ResponseWriter, r UrlRequest) {
+				FailureReply(routeNotFound, w, r.R)
+			}
+		}
+
+		router.Add("", http.MethodGet, func(w http.ResponseWriter, r UrlRequest) {
+			calls = append(calls, "handler")
+		}, deny, recording("route", &calls))
+		router.Use(recording("global", &calls))
+
+		response := serve(router, http.MethodGet, "")
+
+		assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match")
+		assert.Equal(t, []string{"global", "/global"}, calls)
+	})
+
+	t.Run("global middlewares see the requests that don't match any route", func(t *testing.T) {
+		calls := []string{}
+		router := &Router{}
+		router.Use(recording("global", &calls))
+
+		response := serve(router, http.MethodGet, "unknown")
+
+		assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match")
+		assert.Equal(t, []string{"global", "/global"}, calls)
+	})
+
+	t.Run("group middlewares only wrap the routes added after them", func(t *testing.T) {
+		calls := []string{}
+		router := &Router{}
+		group := router.Group("/users")
+
+		ok := func(w http.ResponseWriter, r UrlRequest) {}
+		group.Add("/before", http.MethodGet, ok)
+		group.Use(recording("group", &calls))
+		group.Add("/after", http.MethodGet, ok)
+
+		serve(router, http.MethodGet, "users/before")
+		assert.Empty(t, calls)
+
+		serve(router, http.MethodGet, "users/after")
+		assert.Equal(t, []string{"group", "/group"}, calls)
+	})
+
+	t.Run("group middlewares wrap the 405 and OPTIONS answers of their paths", func(t *testing.T) {
+		calls := []string{}
+		sub := &Router{}
+		sub.Add("/{id}", http.MethodGet, func(w http.ResponseWriter, r UrlRequest) {}, recording("route", &calls))
+
+		router := &Router{}
+		router.Mount("/", sub, recording("mount", &calls))
+		router.Use(recording("global", &calls))
+
+		response := serve(router, http.MethodPatch, "1")
+		assert.Equal(t, http.StatusMethodNotAllowed, response.Code, "Status Code doesn't match")
+		assert.Equal(t, []string{"global", "mount", "/mount", "/global"}, calls)
+
+		calls = calls[:0]
+		response = serve(router, http.MethodOptions, "1")
+		assert.Equal(t, http.StatusNoContent, response.Code, "Status Code doesn't match")
+		assert.Equal(t, []string{"global", "mount", "/mount", "/global"}, calls)
+
+		calls = calls[:0]
+		response = serve(router, http.MethodOptions, "1/unknown")
+		assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match")
+		assert.Equal(t, []string{"global", "/global"}, calls)
+	})
+}
+
+func TestChain(t *testing.T) {
+
+	t.Run("the first middleware is the outermost", func(t *testing.T) {
+		calls := []string{}
+
+		fn := Chain(recording("a", &calls), recording("b", &calls))(func(w http.ResponseWriter, r UrlRequest) {
+			calls = append(calls, "handler")
+		})
+		fn(httptest.NewRecorder(), UrlRequest{})
+
+		assert.Equal(t, []string{"a", "b", "handler", "/b", "/a"}, calls)
+	})
+
+	t.Run("an empty chain calls the handler", func(t *testing.T) {
+		called := false
+
+		Chain()(func(w http.ResponseWriter, r UrlRequest) { called = true })(httptest.NewRecorder(), UrlRequest{})
+
+		assert.True(t, called)
+	})
+}
+
+func TestRouter_Methods(t *testing.T) {
+
+	router := &Router{}
+	router.Add("/{id}", http.MethodGet, func(w http.ResponseWriter, r UrlRequest) {
+		SuccessReply(&Data{status: http.StatusOK, message: ContentReady, data: r.Vars["id"], etag: `"1"`}, w, r.R)
+	})
+	router.Add("/{id}", http.MethodDelete, func(w http.ResponseWriter, r UrlRequest) {})
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/users/"+path, nil)
+		req.Header.Set("Accept", ProblemContentType)
+		response := httptest.NewRecorder()
+
+		router.Serve(response, req, path)
+
+		return response
+	}
+
+	t.Run("methods the path doesn't accept are answered with a 405 and the Allow header", func(t *testing.T) {
+		response := serve(http.MethodPatch, "1")
+
+		assert.Equal(t, http.StatusMethodNotAllowed, response.Code, "Status Code doesn't match")
+		assert.Equal(t, "DELETE, GET, HEAD, OPTIONS", response.Header().Get("Allow"))
+		assert.Contains(t, response.Body.String(), CodeMethodNotAllowed)
+	})
+
+	t.Run("unknown paths are still answered with a 404", func(t *testing.T) {
+		response := serve(http.MethodPatch, "1/unknown")
+
+		assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match")
+		assert.Empty(t, response.Header().Get("Allow"))
+	})
+
+	t.Run("OPTIONS requests are answered from the registered methods", func(t *testing.T) {
+		response := serve(http.MethodOptions, "1")
+
+		assert.Equal(t, http.StatusNoContent, response.Code, "Status Code doesn't match")
+		assert.Equal(t, "DELETE, GET, HEAD, OPTIONS", response.Header().Get("Allow"))
+		assert.Empty(t, response.Body.String())
+	})
+
+	t.Run("HEAD requests receive the headers of the GET reply without the body", func(t *testing.T) {
+		get := serve(http.MethodGet, "1")
+		response := serve(http.MethodHead, "1")
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, get.Header(), response.Header())
+		assert.NotEmpty(t, get.Body.String())
+		assert.Empty(t, response.Body.String())
+	})
+}
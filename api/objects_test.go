@@ -259,4 +259,37 @@ func TestHandlers_GetByMethodAndType(t *testing.T) {
 		assert.Equal(t, err.Error(), ErrNotFound)
 
 	})
-}
\ No newline at end of file
+}
+func TestHandlers_MethodNotAllowed(t *testing.T) {
+	handlers := Handlers{}
+
+	handler := func(w http.ResponseWriter, r UrlRequest) {
+	}
+
+	handlers.Add("/{id}", http.MethodGet, handler)
+	handlers.Add("/{id}", http.MethodDelete, handler)
+	handlers.Add("/{id}/contacts", http.MethodPost, handler)
+
+	t.Run("paths that only match handlers of other methods should return a 405", func(t *testing.T) {
+		handler, err := handlers.GetByMethodAndType("/1", http.MethodPatch)
+
+		assert.Nil(t, handler, "handler should be nil")
+		assert.NotNil(t, err, "err should not be nil")
+		assert.Equal(t, http.StatusMethodNotAllowed, err.status)
+		assert.Equal(t, CodeMethodNotAllowed, err.code)
+	})
+
+	t.Run("HEAD requests should be served by the GET handler", func(t *testing.T) {
+		handler, err := handlers.GetByMethodAndType("/1", http.MethodHead)
+
+		assert.Nil(t, err, "error should be nil")
+		assert.Equal(t, http.MethodGet, handler.H.method)
+		assert.Equal(t, map[string]string{"id": "1"}, handler.Vars)
+	})
+
+	t.Run("HEAD requests to paths without a GET handler should return a 405", func(t *testing.T) {
+		_, err := handlers.GetByMethodAndType("/1/contacts", http.MethodHead)
+
+		assert.Equal(t, http.StatusMethodNotAllowed, err.status)
+	})
+}
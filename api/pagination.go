@@ -0,0 +1,129 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+const (
+	BadLimit  = "Limit must be a number between 1 and 500!"
+	BadOffset = "Offset must be a positive number!"
+	BadCursor = "Invalid cursor!"
+
+	BadListParameters = "Invalid list parameters!"
+)
+
+// reservedParameters query parameters of list requests that aren't filters
+var reservedParameters = map[string]bool{
+	"limit":   true,
+	"offset":  true,
+	"cursor":  true,
+	"sort":    true,
+	"include": true,
+}
+
+// listOptions reads the query parameters of a list request. 'limit' sets the page size, 'cursor' resumes a listing
+// from one of the links previously returned to the client and 'offset' skips a number of rows. 'sort' is a comma
+// separated list of fields, prefixed with '-' for a descending order. Every other parameter is a filter, 'field=value'
+// matches the exact value while 'created_after' or 'updated_before' compare dates against the 'created_at' and
+// 'updated_at' fields. The repositories are responsible for rejecting fields that can't be used.
+func listOptions(r UrlRequest) (repos.ListOptions, *Error) {
+	opts := repos.ListOptions{}
+	query := r.R.URL.Query()
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > repos.MaxLimit {
+			return opts, &Error{msg: BadLimit, status: http.StatusBadRequest, code: CodeBadLimit}
+		}
+		opts.Limit = limit
+	}
+
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return opts, &Error{msg: BadOffset, status: http.StatusBadRequest, code: CodeBadOffset}
+		}
+		opts.Offset = offset
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := repos.DecodeCursor(value)
+		if err != nil {
+			return opts, &Error{msg: BadCursor, status: http.StatusBadRequest, code: CodeBadCursor}
+		}
+		opts.Cursor = cursor
+		opts.Offset = 0
+	}
+
+	if value := query.Get("sort"); value != "" {
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			opts.Sort = append(opts.Sort, repos.Sort{Field: strings.TrimPrefix(field, "-"),
+				Desc: strings.HasPrefix(field, "-")})
+		}
+	}
+
+	// Filters are added in a predictable order so that the same request always results in the same statement
+	names := []string{}
+	for name := range query {
+		if !reservedParameters[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		filter := repos.Filter{Field: name, Op: repos.Equal, Value: query.Get(name)}
+
+		if strings.HasSuffix(name, "_after") {
+			filter.Field, filter.Op = strings.TrimSuffix(name, "_after")+"_at", repos.After
+		} else if strings.HasSuffix(name, "_before") {
+			filter.Field, filter.Op = strings.TrimSuffix(name, "_before")+"_at", repos.Before
+		}
+
+		opts.Filters = append(opts.Filters, filter)
+	}
+
+	return opts, nil
+}
+
+// paginationLinks builds the pagination section of a list response. The links keep every query parameter of the
+// original request replacing only the ones that select the page, cursor links are used whenever the repository
+// returned cursors and offset links otherwise.
+func paginationLinks(r UrlRequest, info *repos.PageInfo) *Pagination {
+	pagination := &Pagination{Total: info.Total, Limit: info.Limit}
+
+	link := func(cursor *repos.Cursor, offset int) string {
+		query := r.R.URL.Query()
+		query.Del("cursor")
+		query.Del("offset")
+		query.Set("limit", strconv.Itoa(info.Limit))
+
+		if cursor != nil {
+			query.Set("cursor", cursor.Encode())
+		} else {
+			query.Set("offset", strconv.Itoa(offset))
+		}
+
+		return r.Path() + "?" + query.Encode()
+	}
+
+	if info.HasNext {
+		pagination.Next = link(info.Next, info.Offset+info.Limit)
+	}
+
+	if info.HasPrev {
+		offset := info.Offset - info.Limit
+		if offset < 0 {
+			offset = 0
+		}
+		pagination.Prev = link(info.Prev, offset)
+	}
+
+	return pagination
+}
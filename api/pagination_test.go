@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/obj"
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+func TestUserHandler_Pagination(t *testing.T) {
+
+	parsedTime, _ := time.Parse(time.RFC3339, "2019-11-22T10:00:00Z")
+
+	userList := []obj.User{
+		{ID: 1, FirstName: "John", LastName: "Cena", CreatedAt: parsedTime, UpdatedAt: parsedTime},
+		{ID: 2, FirstName: "João", LastName: "Cenas", CreatedAt: parsedTime, UpdatedAt: parsedTime},
+		{ID: 3, FirstName: "Pedro", LastName: "Costas", CreatedAt: parsedTime, UpdatedAt: parsedTime},
+	}
+
+	store := newStore(t, userList, nil)
+	users := NewUserHandler(store.Users(), store.Contacts())
+	userHandler := asAdmin(users)
+
+	usersURL, err := users.Routes().URL(RouteUsers)
+	if err != nil {
+		t.Fatalf("error building the url of the users %s", err)
+	}
+
+	list := func(t *testing.T, url string) (*httptest.ResponseRecorder, []obj.User, *Pagination) {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		responseObject := struct {
+			Result     []obj.User  `json:"result"`
+			Pagination *Pagination `json:"pagination"`
+		}{}
+
+		err := json.NewDecoder(response.Body).Decode(&responseObject)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		return response, responseObject.Result, responseObject.Pagination
+	}
+
+	t.Run("walk through every page using the cursor links", func(t *testing.T) {
+		response, users, pagination := list(t, "/users/?limit=2")
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, userList[:2], users)
+		assert.Equal(t, 3, pagination.Total)
+		assert.Equal(t, 2, pagination.Limit)
+		assert.Empty(t, pagination.Prev, "The first page shouldn't have a previous page")
+		assert.Contains(t, pagination.Next, "cursor=")
+
+		response, users, pagination = list(t, pagination.Next)
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, userList[2:], users)
+		assert.Empty(t, pagination.Next, "The last page shouldn't have a next page")
+		assert.Contains(t, pagination.Prev, "cursor=")
+
+		response, users, _ = list(t, pagination.Prev)
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, userList[:2], users)
+	})
+
+	t.Run("offset listings should return offset links", func(t *testing.T) {
+		response, users, pagination := list(t, "/users/?limit=1&offset=1&include=contacts")
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, userList[1:2], users)
+		assert.Equal(t, usersURL+"?include=contacts&limit=1&offset=2", pagination.Next)
+		assert.Equal(t, usersURL+"?include=contacts&limit=1&offset=0", pagination.Prev)
+	})
+
+	t.Run("invalid pagination parameters should return a bad request", func(t *testing.T) {
+		for url, message := range map[string]string{
+			"/users/?limit=0":        BadLimit,
+			"/users/?limit=501":      BadLimit,
+			"/users/?offset=-1":      BadOffset,
+			"/users/?cursor=invalid": BadCursor,
+		} {
+			req, _ := http.NewRequest(http.MethodGet, url, nil)
+			response := httptest.NewRecorder()
+
+			userHandler.ServeHTTP(response, req)
+
+			responseMessage, err := getResponseMessage(response.Body)
+			if err != nil {
+				t.Fatalf("error while unmarshling the response body %s", err)
+			}
+
+			assert.Equal(t, http.StatusBadRequest, response.Code, "Status Code doesn't match")
+			assert.Equal(t, message, responseMessage)
+		}
+	})
+}
+
+func TestUserHandler_Filters(t *testing.T) {
+
+	t.Run("filters and sort are read from the query parameters", func(t *testing.T) {
+		store := repos.NewMemoryStore()
+		repo := &recordingUserRepo{UserRepo: store.Users()}
+		userHandler := asAdmin(NewUserHandler(repo, store.Contacts()))
+
+		req, _ := http.NewRequest(http.MethodGet, "/users/?last_name=Cena&first_name=John&created_after=2019-11-22T10"+
+			":00:00Z&updated_before=2019-11-23T10:00:00Z&sort=-created_at,last_name&limit=10", nil)
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, []repos.Filter{
+			{Field: "created_at", Op: repos.After, Value: "2019-11-22T10:00:00Z"},
+			{Field: "first_name", Op: repos.Equal, Value: "John"},
+			{Field: "last_name", Op: repos.Equal, Value: "Cena"},
+			{Field: "updated_at", Op: repos.Before, Value: "2019-11-23T10:00:00Z"},
+		}, repo.opts.Filters)
+		assert.Equal(t, []repos.Sort{{Field: "created_at", Desc: true}, {Field: "last_name"}}, repo.opts.Sort)
+		assert.Equal(t, 10, repo.opts.Limit)
+	})
+
+	t.Run("fields rejected by the repository return a detailed bad request", func(t *testing.T) {
+		store := repos.NewMemoryStore()
+		repo := &recordingUserRepo{UserRepo: store.Users(), err: repos.FieldErrors{
+			{Field: "password", Reason: "filtering by this field isn't supported"},
+		}}
+		userHandler := asAdmin(NewUserHandler(repo, store.Contacts()))
+
+		req, _ := http.NewRequest(http.MethodGet, "/users/?password=secret", nil)
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		responseObject := struct {
+			Message string             `json:"message"`
+			Result  []repos.FieldError `json:"result"`
+		}{}
+
+		err := json.NewDecoder(response.Body).Decode(&responseObject)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusBadRequest, response.Code, "Status Code doesn't match")
+		assert.Equal(t, BadListParameters, responseObject.Message)
+		assert.Equal(t, []repos.FieldError(repo.err.(repos.FieldErrors)), responseObject.Result)
+	})
+}
+
+// recordingUserRepo keeps the options of the last List call, returning err instead of the stored users when it's set
+type recordingUserRepo struct {
+	repos.UserRepo
+	opts repos.ListOptions
+	err  error
+}
+
+func (s *recordingUserRepo) List(ctx context.Context, opts repos.ListOptions) ([]obj.User, *repos.PageInfo, error) {
+	s.opts = opts
+	if s.err != nil {
+		return nil, nil, s.err
+	}
+	return s.UserRepo.List(ctx, opts)
+}
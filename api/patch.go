@@ -0,0 +1,123 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/pedrorochaorg/contactsApi/patch"
+)
+
+const (
+	UnsupportedPatch = "PATCH requests must be sent as application/merge-patch+json or application/json-patch+json!"
+	PatchConflict    = "The patch can't be applied to the current state of the resource!"
+
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodePatchConflict        = "patch_conflict"
+)
+
+// Fields that clients are allowed to change, every other field is read-only
+var (
+	userWritableFields    = []string{"first_name", "last_name"}
+	contactWritableFields = []string{"first_name", "last_name", "email", "phone"}
+)
+
+// applyPatch applies the merge patch or JSON patch sent in the request body to the stored object, the patched object
+// is decoded into patched and validated. It returns the writable fields whose value was changed by the patch, changes
+// to read-only fields are reported as violations.
+func applyPatch(r *http.Request, stored interface{}, patched validatable, writable []string) ([]string, *Error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("content-type"))
+
+	var apply func(document, patch []byte) ([]byte, error)
+	switch mediaType {
+	case patch.MergePatchContentType:
+		apply = patch.Merge
+	case patch.JSONPatchContentType:
+		apply = patch.Apply
+	default:
+		return nil, &Error{msg: UnsupportedPatch, status: http.StatusUnsupportedMediaType,
+			code: CodeUnsupportedMediaType}
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, &Error{msg: err.Error(), status: http.StatusBadRequest, code: CodeMalformedBody}
+	}
+
+	original, err := json.Marshal(stored)
+	if err != nil {
+		return nil, internalError
+	}
+
+	result, err := apply(original, body)
+	switch {
+	case errors.Is(err, patch.ErrConflict):
+		return nil, &Error{msg: PatchConflict, status: http.StatusConflict, code: CodePatchConflict,
+			details: err.Error()}
+	case err != nil:
+		return nil, &Error{msg: err.Error(), status: http.StatusBadRequest, code: CodeMalformedBody}
+	}
+
+	changed, violations := changedFields(original, result, writable, jsonFields(patched))
+
+	decodeErr := decodeValid(bytes.NewReader(result), patched, nil, violations)
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+
+	return changed, nil
+}
+
+// changedFields compares the original and patched documents returning the writable fields that were changed and a
+// violation for every read-only field that was changed, added or removed. Fields that are omitted from the original
+// document when they're empty, like the contacts of a user, are compared too. Fields that the object doesn't have
+// are left for the decoder to report.
+func changedFields(original, patched []byte, writable []string, fields map[string]reflect.Value) ([]string,
+	[]Violation) {
+	before, after := map[string]interface{}{}, map[string]interface{}{}
+	_ = json.Unmarshal(original, &before)
+	_ = json.Unmarshal(patched, &after)
+
+	names := []string{}
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			if _, known := fields[name]; known {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	changed := []string{}
+	violations := []Violation{}
+	for _, name := range names {
+		if reflect.DeepEqual(before[name], after[name]) {
+			continue
+		}
+
+		if contains(writable, name) {
+			changed = append(changed, name)
+		} else {
+			violations = append(violations, Violation{Field: name, Reason: "is read-only"})
+		}
+	}
+
+	return changed, violations
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/obj"
+	"github.com/pedrorochaorg/contactsApi/patch"
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+func TestUserHandler_Patch(t *testing.T) {
+
+	parsedTime, _ := time.Parse(time.RFC3339, "2019-11-22T10:00:00Z")
+
+	newHandler := func() (http.Handler, *patchingUserRepo, *patchingContactRepo) {
+		store := newStore(t, []obj.User{
+			{ID: 1, FirstName: "John", LastName: "Cena", CreatedAt: parsedTime, UpdatedAt: parsedTime, Version: 3},
+		}, []obj.Contact{
+			{ID: 1, UserID: 1, FirstName: "Ana", LastName: "Silva", Email: "ana@example.com", Phone: "919236587",
+				CreatedAt: parsedTime, UpdatedAt: parsedTime, Version: 3},
+		})
+		users, contacts := &patchingUserRepo{UserRepo: store.Users()}, &patchingContactRepo{ContactRepo: store.Contacts()}
+		return asAdmin(NewUserHandler(users, contacts)), users, contacts
+	}
+
+	patchRequest := func(path, contentType, body string) *http.Request {
+		req, _ := http.NewRequest(http.MethodPatch, path, bytes.NewBufferString(body))
+		req.Header.Set("content-type", contentType)
+		req.Header.Set("Accept", ProblemContentType)
+		req.Header.Set("If-Match", `"3"`)
+		return req
+	}
+
+	t.Run("a merge patch changes only the fields it sets", func(t *testing.T) {
+		handler, users, _ := newHandler()
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, patchRequest("/users/1", patch.MergePatchContentType, `{"first_name": "Dave"}`))
+
+		user, err := getUserFromResponse(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, "Dave", user.FirstName)
+		assert.Equal(t, "Cena", user.LastName, "fields missing from the patch should be kept")
+		assert.Equal(t, []string{"first_name"}, users.fields, "only the changed fields should be persisted")
+	})
+
+	t.Run("a JSON patch is applied to a contact", func(t *testing.T) {
+		handler, _, contacts := newHandler()
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, patchRequest("/users/1/contacts/1", patch.JSONPatchContentType,
+			`[{"op": "test", "path": "/email", "value": "ana@example.com"},
+			  {"op": "replace", "path": "/email", "value": "ana.silva@example.com"},
+			  {"op": "remove", "path": "/phone"}]`))
+
+		contact, err := getContactFromResponse(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, "ana.silva@example.com", contact.Email)
+		assert.Equal(t, "", contact.Phone)
+		assert.Equal(t, "Silva", contact.LastName)
+		assert.Equal(t, []string{"email", "phone"}, contacts.fields)
+	})
+
+	t.Run("patches that change read-only or required fields are rejected", func(t *testing.T) {
+		handler, users, _ := newHandler()
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, patchRequest("/users/1", patch.MergePatchContentType,
+			`{"id": 7, "first_name": null, "age": 30}`))
+
+		problem, err := getProblemFromResponse(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusUnprocessableEntity, response.Code, "Status Code doesn't match")
+		assert.Equal(t, []Violation{
+			{Field: "id", Reason: "is read-only"},
+			{Field: "age", Reason: "unknown field"},
+			{Field: "first_name", Reason: "is required"},
+		}, problem.Errors)
+		assert.Nil(t, users.fields, "nothing should have been persisted")
+	})
+
+	t.Run("patches that add fields omitted from the resource are rejected", func(t *testing.T) {
+		cases := []struct {
+			name        string
+			contentType string
+			body        string
+		}{
+			{"merge patch", patch.MergePatchContentType, `{"contacts": [{"first_name": "Ana"}]}`},
+			{"JSON patch", patch.JSONPatchContentType,
+				`[{"op": "add", "path": "/contacts", "value": [{"first_name": "Ana"}]}]`},
+		}
+
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				handler, users, _ := newHandler()
+				response := httptest.NewRecorder()
+
+				handler.ServeHTTP(response, patchRequest("/users/1", c.contentType, c.body))
+
+				problem, err := getProblemFromResponse(response.Body)
+				if err != nil {
+					t.Fatalf("error while unmarshling the response body %s", err)
+				}
+
+				assert.Equal(t, http.StatusUnprocessableEntity, response.Code, "Status Code doesn't match")
+				assert.Equal(t, []Violation{{Field: "contacts", Reason: "is read-only"}}, problem.Errors)
+				assert.Nil(t, users.fields, "nothing should have been persisted")
+			})
+		}
+	})
+
+	t.Run("a failed test operation is a conflict", func(t *testing.T) {
+		handler, _, _ := newHandler()
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, patchRequest("/users/1", patch.JSONPatchContentType,
+			`[{"op": "test", "path": "/last_name", "value": "Doe"}, {"op": "remove", "path": "/last_name"}]`))
+
+		problem, err := getProblemFromResponse(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusConflict, response.Code, "Status Code doesn't match")
+		assert.Equal(t, CodePatchConflict, problem.Code)
+	})
+
+	t.Run("other content types are unsupported", func(t *testing.T) {
+		handler, _, _ := newHandler()
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, patchRequest("/users/1", JsonContentType, `{"first_name": "Dave"}`))
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, response.Code, "Status Code doesn't match")
+	})
+
+	t.Run("patching an unexisting user", func(t *testing.T) {
+		handler, _, _ := newHandler()
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, patchRequest("/users/9", patch.MergePatchContentType, `{"first_name": "Dave"}`))
+
+		assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match")
+	})
+
+	t.Run("PUT replaces the whole contact", func(t *testing.T) {
+		handler, _, _ := newHandler()
+		response := httptest.NewRecorder()
+
+		req, _ := http.NewRequest(http.MethodPut, "/users/1/contacts/1", bytes.NewBufferString(`{"first_name": "Ana"}`))
+		req.Header.Set("If-Match", `"3"`)
+		handler.ServeHTTP(response, req)
+
+		contact, err := getContactFromResponse(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, obj.Contact{ID: 1, UserID: 1, FirstName: "Ana", CreatedAt: parsedTime},
+			obj.Contact{ID: contact.ID, UserID: contact.UserID, FirstName: contact.FirstName,
+				LastName: contact.LastName, Email: contact.Email, Phone: contact.Phone, CreatedAt: contact.CreatedAt},
+			"fields missing from the body should be cleared")
+	})
+}
+
+// patchingUserRepo records the fields of the last patch made to a user
+type patchingUserRepo struct {
+	repos.UserRepo
+	fields []string
+}
+
+func (p *patchingUserRepo) Patch(ctx context.Context, user *obj.User, fields []string) (*obj.User, error) {
+	p.fields = fields
+	return p.UserRepo.Patch(ctx, user, fields)
+}
+
+// patchingContactRepo records the fields of the last patch made to a contact
+type patchingContactRepo struct {
+	repos.ContactRepo
+	fields []string
+}
+
+func (p *patchingContactRepo) Patch(ctx context.Context, userID int, contact *obj.Contact, fields []string) (
+	*obj.Contact, error) {
+	p.fields = fields
+	return p.ContactRepo.Patch(ctx, userID, contact, fields)
+}
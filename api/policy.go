@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/pedrorochaorg/contactsApi/auth"
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+// canActOn reports whether the principal that authenticated the request may read and modify the user and it's
+// contacts. Admins can act on every user and everyone else only on the user they authenticated as, requests without
+// a principal can't act on anyone.
+//
+// Handlers reply to the requests that fail this check as if the user didn't exist, so that the ids of other users
+// don't leak.
+func canActOn(r *http.Request, userID int) bool {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		return false
+	}
+
+	return principal.Admin || (principal.UserID != 0 && principal.UserID == userID)
+}
+
+// isAdmin reports whether the principal that authenticated the request can act on every user
+func isAdmin(r *http.Request) bool {
+	principal, ok := auth.FromContext(r.Context())
+	return ok && principal.Admin
+}
+
+// ownedUsers restricts a listing of users to the ones the principal of the request can act on, listings done by
+// admins aren't changed
+func ownedUsers(r *http.Request, opts repos.ListOptions) repos.ListOptions {
+	if isAdmin(r) {
+		return opts
+	}
+
+	userID := 0
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		userID = principal.UserID
+	}
+
+	opts.IDs = []int{userID}
+	return opts
+}
+
+// authorizeUser replies as if the user didn't exist and returns false when the principal of the request can't act on
+// it, so that the calling handler can stop processing the request
+func authorizeUser(w http.ResponseWriter, r *http.Request, userID int) bool {
+	if canActOn(r, userID) {
+		return true
+	}
+
+	FailureReply(userNotFound, w, r)
+	return false
+}
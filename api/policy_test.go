@@ -0,0 +1,144 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/auth"
+	"github.com/pedrorochaorg/contactsApi/obj"
+	"github.com/pedrorochaorg/contactsApi/patch"
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+// asPrincipal authenticates every request sent to the handler as the principal
+func asPrincipal(principal *auth.Principal, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+	})
+}
+
+// asAdmin authenticates every request sent to the handler as an admin
+func asAdmin(handler http.Handler) http.Handler {
+	return asPrincipal(&auth.Principal{Subject: "admin", Admin: true}, handler)
+}
+
+func TestUserHandler_Ownership(t *testing.T) {
+
+	users := []obj.User{
+		{ID: 1, FirstName: "John", LastName: "Cena", Version: 1},
+		{ID: 2, FirstName: "João", LastName: "Cenas", Version: 1},
+	}
+	contacts := []obj.Contact{
+		{ID: 1, UserID: 2, FirstName: "Ana", LastName: "Silva", Version: 1},
+	}
+
+	john := &auth.Principal{Subject: "john", UserID: 1}
+
+	send := func(principal *auth.Principal, method, path string) *httptest.ResponseRecorder {
+		store := newStore(t, users, contacts)
+		handler := NewUserHandler(store.Users(), store.Contacts())
+
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(`{"first_name": "Rui", "last_name": "Sousa"}`))
+		req.Header.Set("If-Match", "*")
+		req.Header.Set("Content-Type", patch.MergePatchContentType)
+		response := httptest.NewRecorder()
+
+		if principal == nil {
+			handler.ServeHTTP(response, req)
+		} else {
+			asPrincipal(principal, handler).ServeHTTP(response, req)
+		}
+		return response
+	}
+
+	t.Run("users can act on their own record", func(t *testing.T) {
+		response := send(john, http.MethodGet, "/users/1")
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+	})
+
+	t.Run("users of other users can't be told apart from missing ones", func(t *testing.T) {
+		missing := send(john, http.MethodGet, "/users/3")
+
+		for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			response := send(john, method, "/users/2")
+
+			assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match for %s", method)
+			assert.Equal(t, missing.Body.String(), response.Body.String(), "Body doesn't match for %s", method)
+		}
+	})
+
+	t.Run("contacts of other users aren't reachable", func(t *testing.T) {
+		paths := []struct {
+			method string
+			path   string
+		}{
+			{http.MethodGet, "/users/2/contacts"},
+			{http.MethodPost, "/users/2/contacts"},
+			{http.MethodGet, "/users/2/contacts/1"},
+			{http.MethodPut, "/users/2/contacts/1"},
+			{http.MethodPatch, "/users/2/contacts/1"},
+			{http.MethodDelete, "/users/2/contacts/1"},
+		}
+
+		for _, p := range paths {
+			response := send(john, p.method, p.path)
+
+			assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match for %s %s", p.method,
+				p.path)
+			assert.Contains(t, response.Body.String(), UserNotFound)
+		}
+	})
+
+	t.Run("admins can act on every user", func(t *testing.T) {
+		response := send(&auth.Principal{Subject: "ops", Admin: true}, http.MethodGet, "/users/2/contacts/1")
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+	})
+
+	t.Run("only admins can create users", func(t *testing.T) {
+		response := send(john, http.MethodPost, "/users/")
+
+		assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match")
+	})
+
+	t.Run("requests without a principal can't act on anyone", func(t *testing.T) {
+		response := send(nil, http.MethodGet, "/users/1")
+
+		assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match")
+	})
+
+	t.Run("principals that aren't a user can't act on anyone", func(t *testing.T) {
+		response := send(&auth.Principal{Subject: "service"}, http.MethodGet, "/users/1")
+
+		assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match")
+	})
+
+	t.Run("listings only return the users the principal can act on", func(t *testing.T) {
+		for _, c := range []struct {
+			principal *auth.Principal
+			ids       []int
+		}{
+			{john, []int{1}},
+			{&auth.Principal{Subject: "ops", Admin: true}, nil},
+		} {
+			store := newStore(t, users, nil)
+			repo := &recordingUserRepo{UserRepo: store.Users()}
+			handler := asPrincipal(c.principal, NewUserHandler(repo, store.Contacts()))
+
+			req, _ := http.NewRequest(http.MethodGet, "/users/?first_name=John", nil)
+			response := httptest.NewRecorder()
+
+			handler.ServeHTTP(response, req)
+
+			assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+			assert.Equal(t, []repos.Filter{{Field: "first_name", Op: repos.Equal, Value: "John"}}, repo.opts.Filters,
+				"Filters don't match for %s", c.principal.Subject)
+			assert.Equal(t, c.ids, repo.opts.IDs, "IDs don't match for %s", c.principal.Subject)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pedrorochaorg/contactsApi/logging"
+)
+
+const (
+	ProblemContentType = "application/problem+json"
+
+	// ProblemTypeBase prefix of the type URI of every problem, the code of the problem is appended to it
+	ProblemTypeBase = "https://github.com/pedrorochaorg/contactsApi/blob/master/docs/problems.md#"
+)
+
+// Stable machine readable codes of the errors replied by the API, clients can rely on them to tell errors apart
+const (
+	CodeNotFound           = "not_found"
+	CodeMethodNotAllowed   = "method_not_allowed"
+	CodeUserNotFound       = "user_not_found"
+	CodeContactNotFound    = "contact_not_found"
+	CodeBadInclude         = "bad_include"
+	CodeBadLimit           = "bad_limit"
+	CodeBadOffset          = "bad_offset"
+	CodeBadCursor          = "bad_cursor"
+	CodeBadListParameters  = "bad_list_parameters"
+	CodeMalformedBody      = "malformed_body"
+	CodeConflict           = "conflict"
+	CodeInvalidData        = "invalid_data"
+	CodeServiceUnavailable = "service_unavailable"
+	CodeNotReady           = "not_ready"
+	CodeInternalError      = "internal_error"
+)
+
+// Problem RFC 7807 representation of an error, sent to the clients that accept application/problem+json
+type Problem struct {
+	Type     string      `json:"type"`
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Detail   string      `json:"detail,omitempty"`
+	Instance string      `json:"instance,omitempty"`
+	Code     string      `json:"code"`
+	Errors   []Violation `json:"errors,omitempty"`
+
+	// RequestID id of the request that failed, an extension member clients can report to find it in the logs
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Violation a single invalid field of the request
+type Violation struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// newProblem builds the problem describing the error that occurred while serving the request
+func newProblem(er *Error, r *http.Request) Problem {
+	return Problem{
+		Type:     ProblemTypeBase + er.code,
+		Title:    http.StatusText(er.status),
+		Status:   er.status,
+		Detail:   er.msg,
+		Instance: r.URL.Path,
+		Code:     er.code,
+		Errors:   er.violations,
+
+		RequestID: logging.RequestID(r.Context()),
+	}
+}
+
+// problemReply writes the error as an application/problem+json document
+func problemReply(er *Error, w http.ResponseWriter, r *http.Request) {
+	body, err := encodeReply(newProblem(er, r), r)
+	if err != nil {
+		if er != internalError {
+			problemReply(internalError, w, r)
+		}
+		return
+	}
+
+	w.Header().Set("content-type", ProblemContentType)
+	w.WriteHeader(er.status)
+	writeReply(body, w, r)
+}
+
+// acceptsProblem reports whether the client opted in to application/problem+json error responses, that is the
+// Accept header lists it with a quality at least as high as application/json. Wildcards don't opt in.
+func acceptsProblem(r *http.Request) bool {
+	problemQuality, jsonQuality := 0.0, 0.0
+
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case ProblemContentType:
+			problemQuality = quality
+		case JsonContentType:
+			jsonQuality = quality
+		}
+	}
+
+	return problemQuality > 0 && problemQuality >= jsonQuality
+}
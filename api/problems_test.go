@@ -0,0 +1,118 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+func TestAcceptsProblem(t *testing.T) {
+
+	cases := []struct {
+		accept   string
+		expected bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", false},
+		{"application/problem+json", true},
+		{"application/json, application/problem+json", true},
+		{"application/problem+json;q=0.5, application/json", false},
+		{"application/problem+json, application/json;q=0.9", true},
+		{"application/problem+json;q=0", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.accept, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/users/1", nil)
+			req.Header.Set("Accept", c.accept)
+
+			assert.Equal(t, c.expected, acceptsProblem(req))
+		})
+	}
+}
+
+func TestFailureReply_Problem(t *testing.T) {
+
+	store := repos.NewMemoryStore()
+	userHandler := asAdmin(NewUserHandler(store.Users(), store.Contacts()))
+
+	t.Run("clients that accept problems get a problem document", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/users/abc", nil)
+		req.Header.Set("Accept", ProblemContentType)
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		problem, err := getProblemFromResponse(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match")
+		assert.Equal(t, ProblemContentType, response.Header().Get("content-type"))
+		assert.Equal(t, Problem{
+			Type:     ProblemTypeBase + CodeNotFound,
+			Title:    "Not Found",
+			Status:   http.StatusNotFound,
+			Detail:   ErrNotFound,
+			Instance: "/users/abc",
+			Code:     CodeNotFound,
+		}, *problem)
+	})
+
+	t.Run("rejected list parameters are reported as field violations", func(t *testing.T) {
+		repo := &recordingUserRepo{UserRepo: store.Users(), err: repos.FieldErrors{
+			{Field: "age", Reason: "sorting by this field isn't supported"},
+		}}
+
+		req, _ := http.NewRequest(http.MethodGet, "/users/?sort=age", nil)
+		req.Header.Set("Accept", ProblemContentType)
+		response := httptest.NewRecorder()
+
+		asAdmin(NewUserHandler(repo, store.Contacts())).ServeHTTP(response, req)
+
+		problem, err := getProblemFromResponse(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusBadRequest, response.Code, "Status Code doesn't match")
+		assert.Equal(t, CodeBadListParameters, problem.Code)
+		assert.Equal(t, []Violation{{Field: "age", Reason: "sorting by this field isn't supported"}}, problem.Errors)
+	})
+
+	t.Run("other clients keep getting the response envelope", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/users/abc", nil)
+		req.Header.Set("Accept", "application/json")
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		message, err := getResponseMessage(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match")
+		assert.Equal(t, JsonContentType, response.Header().Get("content-type"))
+		assert.Equal(t, ErrNotFound, message)
+	})
+}
+
+func getProblemFromResponse(response *bytes.Buffer) (*Problem, error) {
+	problem := &Problem{}
+
+	err := json.NewDecoder(response).Decode(problem)
+	if err != nil {
+		return nil, err
+	}
+
+	return problem, nil
+}
@@ -1,7 +1,34 @@
 package api
 
+import "github.com/pedrorochaorg/contactsApi/logging"
+
 type Response struct {
-	Status  bool        `json:"status"`
-	Message string      `json:"message"`
-	Result  interface{} `json:"result"`
+	Status     bool        `json:"status"`
+	Message    string      `json:"message"`
+	Result     interface{} `json:"result"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+	RequestID  string      `json:"request_id,omitempty"`
+}
+
+// Pagination is sent along with list results, next and prev hold the links to the adjacent pages and are omitted
+// when there's no such page
+type Pagination struct {
+	Total int    `json:"total"`
+	Limit int    `json:"limit"`
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+}
+
+// resourceURL builds the URL of a named route for the Location headers of a reply. The request already succeeded
+// when it's called, so a failure is logged and leaves the header out instead of failing the request.
+func resourceURL(r UrlRequest, name string, vars ...string) string {
+	url, err := r.URL(name, vars...)
+	if err != nil {
+		logging.FromContext(r.R.Context()).Error(r.R.Context(), "failed to build the location", logging.Fields{
+			"error": err,
+			"route": name,
+		})
+		return ""
+	}
+	return url
 }
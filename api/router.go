@@ -0,0 +1,579 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Router dispatches requests to the handlers registered in it, matching the paths with a radix tree.
+//
+// Patterns are made of static segments and variables that take a whole segment:
+//
+//	{name}         any non empty segment
+//	{name:int}     a segment of digits that fits in an int
+//	{name:regexp}  a segment fully matched by the regular expression, which can't contain a '/'
+//	{name...}      the rest of the path, slashes included, only allowed as the last segment
+//
+// Static segments take precedence over variables, variables with a constraint over the ones without it and those
+// over catch-all variables, the router backtracks when the path can't be matched by the preferred branch.
+//
+// Middlewares run in a fixed order: the global ones registered with Use, then the ones of each group from the
+// outermost to the innermost and last the ones of the route.
+type Router struct {
+	root        *node
+	routes      []*Route
+	names       map[string]*Route
+	middlewares []Middleware
+}
+
+// Route a handler registered in the router, the middlewares include the ones of the groups it was added to, which
+// are also kept apart to answer the requests the router replies to on behalf of the routes of a path
+type Route struct {
+	router      *Router
+	pattern     string
+	method      string
+	name        string
+	params      []*param
+	handler     HandlerFunc
+	middlewares []Middleware
+	group       []Middleware
+}
+
+// Pattern returns the pattern the route was registered with, like /users/{id:int}
+func (r *Route) Pattern() string {
+	return r.pattern
+}
+
+// Name names the route so that it's URL can be built with Router.URL, names are unique within a router
+func (r *Route) Name(name string) *Route {
+	if registered, ok := r.router.names[name]; ok {
+		panic(fmt.Sprintf("route %s %s is already named %s", registered.method, registered.pattern, name))
+	}
+
+	if r.router.names == nil {
+		r.router.names = map[string]*Route{}
+	}
+	r.router.names[name] = r
+	r.name = name
+
+	return r
+}
+
+// Add registers the handler for the requests with the method whose path matches the pattern, the middlewares wrap
+// the handler in the order they are given. Registering a pattern that conflicts with another one panics.
+func (rt *Router) Add(pattern, method string, fn HandlerFunc, middlewares ...Middleware) *Route {
+	if rt.root == nil {
+		rt.root = &node{}
+	}
+
+	pattern = cleanPath(pattern)
+	n, params := rt.root.insert(pattern)
+
+	for _, registered := range n.routes {
+		if registered.method == method {
+			panic(fmt.Sprintf("route %s %s conflicts with %s %s", method, pattern, method, registered.pattern))
+		}
+	}
+
+	r := &Route{router: rt, pattern: pattern, method: method, params: params, handler: fn, middlewares: middlewares}
+	n.routes = append(n.routes, r)
+	rt.routes = append(rt.routes, r)
+
+	return r
+}
+
+// Mount registers every route of the sub router under the prefix, keeping their names. The global middlewares of
+// the sub router and the given ones wrap the middlewares of each route. Routes added to the sub router after it was
+// mounted aren't served by this router.
+func (rt *Router) Mount(prefix string, sub *Router, middlewares ...Middleware) {
+	group := rt.Group(prefix, middlewares...)
+	group.Use(sub.middlewares...)
+
+	for _, r := range sub.routes {
+		mounted := group.Add(r.pattern, r.method, r.handler, r.middlewares...)
+		mounted.group = append(mounted.group, r.group...)
+		if r.name != "" {
+			mounted.Name(r.name)
+		}
+	}
+}
+
+// Use registers middlewares that run for every request served by the router, including the ones that don't match
+// any route
+func (rt *Router) Use(middlewares ...Middleware) {
+	rt.middlewares = append(rt.middlewares, middlewares...)
+}
+
+// Group returns a group of routes that share the path prefix and the middlewares
+func (rt *Router) Group(prefix string, middlewares ...Middleware) *Group {
+	return &Group{router: rt, prefix: strings.Trim(prefix, "/"), middlewares: middlewares}
+}
+
+// ServeHTTP dispatches the request by it's path
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.Serve(w, r, r.URL.Path)
+}
+
+// URL builds the path of the named route with the values of it's variables, given as name and value pairs. Values
+// are escaped and must match the constraints of their variables.
+func (rt *Router) URL(name string, vars ...string) (string, error) {
+	r, ok := rt.names[name]
+	if !ok {
+		return "", fmt.Errorf("failed to build url: there's no route named %s", name)
+	}
+
+	if len(vars)%2 != 0 {
+		return "", fmt.Errorf("failed to build url of %s: variables must be name and value pairs", name)
+	}
+
+	values := make(map[string]string, len(vars)/2)
+	for i := 0; i < len(vars); i += 2 {
+		values[vars[i]] = vars[i+1]
+	}
+
+	return r.url(values)
+}
+
+// url builds the path of the route with the values of it's variables
+func (r *Route) url(values map[string]string) (string, error) {
+	segments := strings.Split(r.pattern, "/")
+	params := r.params
+
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "{") {
+			continue
+		}
+
+		p := params[0]
+		params = params[1:]
+
+		value, ok := values[p.name]
+		if !ok {
+			return "", fmt.Errorf("failed to build url of %s: missing value of {%s}", r.pattern, p.name)
+		}
+		delete(values, p.name)
+
+		if p.match == nil {
+			escaped := strings.Split(value, "/")
+			for j := range escaped {
+				escaped[j] = url.PathEscape(escaped[j])
+			}
+			segments[i] = strings.Join(escaped, "/")
+			continue
+		}
+
+		if value == "" || !p.match(value) {
+			return "", fmt.Errorf("failed to build url of %s: %q isn't a valid value of {%s}", r.pattern, value,
+				p.name)
+		}
+		segments[i] = url.PathEscape(value)
+	}
+
+	for name := range values {
+		return "", fmt.Errorf("failed to build url of %s: unknown variable {%s}", r.pattern, name)
+	}
+
+	return strings.Join(segments, "/"), nil
+}
+
+// Serve dispatches the request to the handler registered for the path and method, requests that don't match any
+// route are answered by the global middlewares and a not found reply. Paths that exist but don't accept the method
+// are answered with a 405 and the Allow header, OPTIONS requests are answered from the registered methods unless
+// the path has an OPTIONS handler and HEAD requests are served by the GET handler without the body.
+//
+// The 405 and OPTIONS answers run after the middlewares of the groups the routes of the path were added to, like
+// the authentication of a mounted router, so that they aren't disclosed to requests the routes would have rejected.
+// When the routes of a path come from different groups the middlewares of the first one registered are used. The
+// route of these answers is the first one registered for the path too, so that they are labelled with it's pattern.
+func (rt *Router) Serve(w http.ResponseWriter, r *http.Request, path string) {
+	var fn HandlerFunc
+
+	n, vars := rt.match(path)
+	found, err := n.route(r.Method)
+	switch {
+	case err == nil:
+		fn = Chain(found.middlewares...)(found.handler)
+	case err.status == http.StatusMethodNotAllowed && r.Method == http.MethodOptions:
+		found = n.routes[0]
+		fn = Chain(found.group...)(func(w http.ResponseWriter, r UrlRequest) {
+			w.Header().Set("Allow", strings.Join(n.allowed(), ", "))
+			w.WriteHeader(http.StatusNoContent)
+		})
+	case err.status == http.StatusMethodNotAllowed:
+		found = n.routes[0]
+		fn = Chain(found.group...)(func(w http.ResponseWriter, r UrlRequest) {
+			w.Header().Set("Allow", strings.Join(n.allowed(), ", "))
+			FailureReply(err, w, r.R)
+		})
+	default:
+		fn = func(w http.ResponseWriter, r UrlRequest) {
+			FailureReply(err, w, r.R)
+		}
+	}
+
+	// The body is dropped inside the global middlewares, so that the ones recording the reply don't count it
+	if r.Method == http.MethodHead {
+		fn = bodyless(fn)
+	}
+
+	Chain(rt.middlewares...)(fn)(w, UrlRequest{R: r, Vars: vars, router: rt, route: found})
+}
+
+// match returns the node of the tree that matches the path, with the variables extracted from it. The node is nil
+// when no pattern matches the path.
+func (rt *Router) match(path string) (*node, map[string]string) {
+	if rt.root == nil {
+		return nil, nil
+	}
+
+	var vars map[string]string
+	return rt.root.find(cleanPath(path), &vars), vars
+}
+
+// cleanPath returns the path with a leading slash and without the trailing one
+func cleanPath(path string) string {
+	if len(path) > 1 && path[len(path)-1] == '/' {
+		path = path[:len(path)-1]
+	}
+	if path == "" || path[0] != '/' {
+		path = "/" + path
+	}
+	return path
+}
+
+// bodyless wraps the handler so that the body of it's reply is dropped, for the HEAD requests
+func bodyless(fn HandlerFunc) HandlerFunc {
+	return func(w http.ResponseWriter, r UrlRequest) {
+		fn(bodylessWriter{w}, r)
+	}
+}
+
+// bodylessWriter drops the body of the replies to HEAD requests, keeping their status and headers
+type bodylessWriter struct {
+	http.ResponseWriter
+}
+
+func (b bodylessWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// node of the radix tree, it matches the static prefix and holds the routes of the paths that end with it. Static
+// children are indexed by the first byte of their prefix, variables and catch-all variables start right after the
+// prefix, which always ends with a '/' when there are any.
+type node struct {
+	prefix   string
+	indices  []byte
+	children []*node
+	params   []*param
+	catchAll *param
+	routes   []*Route
+}
+
+// param a path variable, the node matches the path that follows the variable segment. Catch-all variables have no
+// match function.
+type param struct {
+	name       string
+	constraint string
+	match      func(segment string) bool
+	node       *node
+}
+
+// insert adds the pattern to the tree and returns the node where it ends, with the variables of the pattern in the
+// order they appear
+func (n *node) insert(pattern string) (*node, []*param) {
+	params := []*param{}
+
+	for pattern != "" {
+		start := strings.IndexByte(pattern, '{')
+		if start < 0 {
+			return n.insertStatic(pattern), params
+		}
+
+		if start > 0 && pattern[start-1] != '/' {
+			panic(fmt.Sprintf("path variables must take a whole segment in %s", pattern))
+		}
+		n = n.insertStatic(pattern[:start])
+
+		end := strings.IndexByte(pattern[start:], '/')
+		if end < 0 {
+			end = len(pattern)
+		} else {
+			end += start
+		}
+
+		segment := pattern[start:end]
+		if segment[len(segment)-1] != '}' {
+			panic(fmt.Sprintf("path variables must take a whole segment in %s", pattern))
+		}
+
+		p := n.insertParam(segment[1:len(segment)-1], end == len(pattern))
+		params = append(params, p)
+		n, pattern = p.node, pattern[end:]
+	}
+
+	return n, params
+}
+
+// insertStatic adds the static path below the node, splitting the children that share only part of their prefix
+// with it, and returns the node where it ends
+func (n *node) insertStatic(path string) *node {
+	if path == "" {
+		return n
+	}
+
+	for i, index := range n.indices {
+		if index != path[0] {
+			continue
+		}
+
+		child := n.children[i]
+		common := commonPrefix(child.prefix, path)
+		if common < len(child.prefix) {
+			split := &node{prefix: child.prefix[:common], indices: []byte{child.prefix[common]},
+				children: []*node{child}}
+			child.prefix = child.prefix[common:]
+			n.children[i] = split
+			child = split
+		}
+
+		return child.insertStatic(path[common:])
+	}
+
+	child := &node{prefix: path}
+	n.indices = append(n.indices, path[0])
+	n.children = append(n.children, child)
+	return child
+}
+
+// insertParam adds the variable defined by the contents of the braces after the node, variables at the same position
+// with the same constraint are shared and must have the same name
+func (n *node) insertParam(definition string, last bool) *param {
+	name, constraint := definition, ""
+	if i := strings.IndexByte(definition, ':'); i >= 0 {
+		name, constraint = definition[:i], definition[i+1:]
+	}
+
+	if strings.HasSuffix(name, "...") && constraint == "" {
+		if !last {
+			panic(fmt.Sprintf("catch-all variable {%s} must be the last segment", definition))
+		}
+		name = strings.TrimSuffix(name, "...")
+		if n.catchAll == nil {
+			n.catchAll = &param{name: name, node: &node{}}
+		} else if n.catchAll.name != name {
+			panic(fmt.Sprintf("catch-all variable {%s...} conflicts with {%s...}", name, n.catchAll.name))
+		}
+		return n.catchAll
+	}
+
+	if name == "" {
+		panic(fmt.Sprintf("path variable {%s} has no name", definition))
+	}
+
+	for _, p := range n.params {
+		if p.constraint != constraint {
+			continue
+		}
+		if p.name != name {
+			panic(fmt.Sprintf("path variable {%s} conflicts with {%s}", definition, p.name))
+		}
+		return p
+	}
+
+	p := &param{name: name, constraint: constraint, match: constraintMatcher(constraint), node: &node{}}
+	n.params = append(n.params, p)
+
+	// Constrained variables are tried before the ones that accept any segment
+	sort.SliceStable(n.params, func(i, j int) bool {
+		return n.params[i].constraint != "" && n.params[j].constraint == ""
+	})
+
+	return p
+}
+
+// constraintMatcher returns the function that checks the segments matched by a variable with the constraint
+func constraintMatcher(constraint string) func(segment string) bool {
+	switch constraint {
+	case "":
+		return func(segment string) bool {
+			return true
+		}
+	case "int":
+		return func(segment string) bool {
+			for i := 0; i < len(segment); i++ {
+				if segment[i] < '0' || segment[i] > '9' {
+					return false
+				}
+			}
+			_, err := strconv.Atoi(segment)
+			return err == nil
+		}
+	default:
+		expression := regexp.MustCompile("^(?:" + constraint + ")$")
+		return expression.MatchString
+	}
+}
+
+// find returns the node that matches the path, the path that follows the prefix of this node, storing the variables
+// in vars. The map is only allocated when the path has variables.
+func (n *node) find(path string, vars *map[string]string) *node {
+	if path == "" && len(n.routes) > 0 {
+		return n
+	}
+
+	if path != "" {
+		for i, index := range n.indices {
+			if index != path[0] {
+				continue
+			}
+
+			child := n.children[i]
+			if strings.HasPrefix(path, child.prefix) {
+				if found := child.find(path[len(child.prefix):], vars); found != nil {
+					return found
+				}
+			}
+			break
+		}
+	}
+
+	if len(n.params) > 0 {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+
+		segment := path[:end]
+		if segment != "" {
+			for _, p := range n.params {
+				if !p.match(segment) {
+					continue
+				}
+				if found := p.node.find(path[end:], vars); found != nil {
+					setVar(vars, p.name, segment)
+					return found
+				}
+			}
+		}
+	}
+
+	if n.catchAll != nil && len(n.catchAll.node.routes) > 0 {
+		setVar(vars, n.catchAll.name, path)
+		return n.catchAll.node
+	}
+
+	return nil
+}
+
+// setVar stores the value of a variable, allocating the map on the first one
+func setVar(vars *map[string]string, name, value string) {
+	if *vars == nil {
+		*vars = map[string]string{}
+	}
+	(*vars)[name] = value
+}
+
+// route returns the route of the node registered for the method, HEAD requests are served by the GET route when there
+// isn't a HEAD one. The error is a 404 when the path didn't match any node and a 405 when the node has no route for
+// the method.
+func (n *node) route(method string) (*Route, *Error) {
+	if n == nil {
+		return nil, routeNotFound
+	}
+
+	var get *Route
+	for _, r := range n.routes {
+		if r.method == method {
+			return r, nil
+		}
+		if r.method == http.MethodGet {
+			get = r
+		}
+	}
+
+	if method == http.MethodHead && get != nil {
+		return get, nil
+	}
+
+	return nil, &Error{msg: MethodNotAllowed, status: http.StatusMethodNotAllowed, code: CodeMethodNotAllowed}
+}
+
+// allowed returns the methods accepted by the node, sorted, including the HEAD requests served by GET routes and the
+// OPTIONS requests answered by the router
+func (n *node) allowed() []string {
+	methods := map[string]bool{http.MethodOptions: true}
+	for _, r := range n.routes {
+		methods[r.method] = true
+		if r.method == http.MethodGet {
+			methods[http.MethodHead] = true
+		}
+	}
+
+	allowed := make([]string, 0, len(methods))
+	for method := range methods {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+
+	return allowed
+}
+
+// commonPrefix returns the length of the prefix shared by both strings
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// Group routes registered under a common path prefix, the middlewares of the group wrap the middlewares of each
+// route added to it
+type Group struct {
+	router      *Router
+	prefix      string
+	middlewares []Middleware
+}
+
+// Use registers middlewares for the routes added to the group afterwards
+func (g *Group) Use(middlewares ...Middleware) {
+	g.middlewares = append(g.middlewares, middlewares...)
+}
+
+// Group returns a nested group, it's prefix and middlewares follow the ones of the parent group
+func (g *Group) Group(prefix string, middlewares ...Middleware) *Group {
+	return &Group{
+		router:      g.router,
+		prefix:      g.path(prefix),
+		middlewares: append(g.copyMiddlewares(), middlewares...),
+	}
+}
+
+// Add registers a route under the prefix of the group
+func (g *Group) Add(path, method string, fn HandlerFunc, middlewares ...Middleware) *Route {
+	r := g.router.Add(g.path(path), method, fn, append(g.copyMiddlewares(), middlewares...)...)
+	r.group = g.copyMiddlewares()
+	return r
+}
+
+// path joins the prefix of the group with the path
+func (g *Group) path(path string) string {
+	path = strings.Trim(path, "/")
+	if g.prefix == "" || path == "" {
+		return g.prefix + path
+	}
+	return g.prefix + "/" + path
+}
+
+// copyMiddlewares returns a copy of the middlewares of the group, so that appending to it doesn't change the ones
+// captured by routes and nested groups
+func (g *Group) copyMiddlewares() []Middleware {
+	middlewares := make([]Middleware, len(g.middlewares))
+	copy(middlewares, g.middlewares)
+	return middlewares
+}
@@ -0,0 +1,265 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+func TestRouter_Match(t *testing.T) {
+
+	router := &Router{}
+	for _, pattern := range []string{
+		"/",
+		"/{id:int}",
+		"/{id:int}/contacts",
+		"/{id:int}/contacts/{contactId:int}",
+		"/new",
+		"/tags/{slug:[a-z-]+}",
+		"/tags/{name}",
+		"/new/{name}/edit",
+		"/files/{path...}",
+	} {
+		router.Add(pattern, http.MethodGet, func(w http.ResponseWriter, r UrlRequest) {})
+	}
+
+	cases := []struct {
+		path    string
+		pattern string
+		vars    map[string]string
+	}{
+		{"", "/", nil},
+		{"/", "/", nil},
+		{"12", "/{id:int}", map[string]string{"id": "12"}},
+		{"/12/", "/{id:int}", map[string]string{"id": "12"}},
+		{"12/contacts", "/{id:int}/contacts", map[string]string{"id": "12"}},
+		{"12/contacts/3", "/{id:int}/contacts/{contactId:int}", map[string]string{"id": "12", "contactId": "3"}},
+		{"new", "/new", nil},
+		{"new/john/edit", "/new/{name}/edit", map[string]string{"name": "john"}},
+		{"tags/go-lang", "/tags/{slug:[a-z-]+}", map[string]string{"slug": "go-lang"}},
+		{"tags/Go", "/tags/{name}", map[string]string{"name": "Go"}},
+		{"files/a/b.txt", "/files/{path...}", map[string]string{"path": "a/b.txt"}},
+		{"files/", "", nil},
+		{"abc", "", nil},
+		{"-1", "", nil},
+		{"99999999999999999999999", "", nil},
+		{"12/contacts/abc", "", nil},
+		{"12/unknown", "", nil},
+		{"tags", "", nil},
+		{"new/john", "", nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			n, vars := router.match(c.path)
+
+			if c.pattern == "" {
+				assert.Nil(t, n, "path shouldn't match any route")
+				return
+			}
+
+			if assert.NotNil(t, n, "path should match a route") {
+				assert.Equal(t, c.pattern, n.routes[0].pattern)
+				assert.Equal(t, c.vars, vars)
+			}
+		})
+	}
+}
+
+func TestRouter_Add(t *testing.T) {
+
+	handler := func(w http.ResponseWriter, r UrlRequest) {}
+
+	t.Run("patterns are split into nodes that share their prefix", func(t *testing.T) {
+		router := &Router{}
+		router.Add("/contacts", http.MethodGet, handler)
+		router.Add("/contains", http.MethodGet, handler)
+
+		assert.Len(t, router.root.children, 1)
+		assert.Equal(t, "/conta", router.root.children[0].prefix)
+		assert.Equal(t, []byte("ci"), router.root.children[0].indices)
+	})
+
+	t.Run("the same route can't be registered twice", func(t *testing.T) {
+		router := &Router{}
+		router.Add("/{id}", http.MethodGet, handler)
+		router.Add("/{id}", http.MethodPost, handler)
+
+		assert.Panics(t, func() { router.Add("/{id}/", http.MethodGet, handler) })
+	})
+
+	t.Run("invalid patterns are rejected", func(t *testing.T) {
+		for _, pattern := range []string{
+			"/users-{id}",
+			"/{id}-users",
+			"/{path...}/contacts",
+			"/{:int}",
+		} {
+			router := &Router{}
+
+			assert.Panics(t, func() { router.Add(pattern, http.MethodGet, handler) }, pattern)
+		}
+	})
+
+	t.Run("variables at the same position must have the same name", func(t *testing.T) {
+		router := &Router{}
+		router.Add("/{id:int}", http.MethodGet, handler)
+
+		assert.Panics(t, func() { router.Add("/{userId:int}/contacts", http.MethodGet, handler) })
+	})
+}
+
+func TestUrlRequest_Int(t *testing.T) {
+
+	router := &Router{}
+	router.Add("/{id:int}", http.MethodGet, func(w http.ResponseWriter, r UrlRequest) {
+		assert.Equal(t, 42, r.Int("id"))
+		assert.Equal(t, 0, r.Int("contactId"))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/users/42", nil)
+	response := httptest.NewRecorder()
+
+	router.Serve(response, req, "42")
+
+	assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+}
+
+// benchmarkPaths requests matched by the routes of the users handler
+var benchmarkPaths = []string{"", "28", "28/contacts", "28/contacts/1", "28/unknown"}
+
+func BenchmarkRouter_Match(b *testing.B) {
+	store := repos.NewMemoryStore()
+	router := NewUserHandler(store.Users(), store.Contacts()).router
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		path := benchmarkPaths[i%len(benchmarkPaths)]
+		n, _ := router.match(path)
+		_, _ = n.route(http.MethodGet)
+	}
+}
+
+func BenchmarkHandlers_GetByMethodAndType(b *testing.B) {
+	handlers := Handlers{}
+	handler := func(w http.ResponseWriter, r UrlRequest) {}
+
+	for _, path := range []string{"", "/{id}", "/{id}/contacts", "/{id}/contacts/{contactId}"} {
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+			http.MethodDelete} {
+			handlers.Add(path, method, handler)
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = handlers.GetByMethodAndType(benchmarkPaths[i%len(benchmarkPaths)], http.MethodGet)
+	}
+}
+
+func TestRouter_Mount(t *testing.T) {
+
+	calls := []string{}
+
+	sub := &Router{}
+	sub.Use(recording("sub", &calls))
+	sub.Add("/{id:int}/contacts", http.MethodGet, func(w http.ResponseWriter, r UrlRequest) {
+		calls = append(calls, "handler "+r.Vars["id"])
+		w.WriteHeader(http.StatusOK)
+	}, recording("route", &calls)).Name("contacts")
+
+	router := &Router{}
+	v1 := router.Group("/v1")
+	router.Mount("/v1/users", sub, recording("mount", &calls))
+	v1.Add("/health", http.MethodGet, func(w http.ResponseWriter, r UrlRequest) {})
+
+	t.Run("mounted routes are served under the prefix", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/v1/users/3/contacts", nil)
+		response := httptest.NewRecorder()
+
+		router.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, []string{"mount", "sub", "route", "handler 3", "/route", "/sub", "/mount"}, calls)
+	})
+
+	t.Run("mounted routes keep their names", func(t *testing.T) {
+		url, err := router.URL("contacts", "id", "3")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "/v1/users/3/contacts", url)
+	})
+}
+
+func TestRouter_URL(t *testing.T) {
+
+	handler := func(w http.ResponseWriter, r UrlRequest) {}
+
+	router := &Router{}
+	router.Add("/users", http.MethodGet, handler).Name("users")
+	router.Add("/users/{id:int}/contacts/{contactId:int}", http.MethodGet, handler).Name("contact")
+	router.Add("/tags/{name}", http.MethodGet, handler).Name("tag")
+	router.Add("/files/{path...}", http.MethodGet, handler).Name("file")
+
+	t.Run("variables are replaced by their values", func(t *testing.T) {
+		cases := []struct {
+			name string
+			vars []string
+			url  string
+		}{
+			{"users", nil, "/users"},
+			{"contact", []string{"id", "1", "contactId", "2"}, "/users/1/contacts/2"},
+			{"tag", []string{"name", "a b/c"}, "/tags/a%20b%2Fc"},
+			{"file", []string{"path", "docs/a b.txt"}, "/files/docs/a%20b.txt"},
+		}
+
+		for _, c := range cases {
+			url, err := router.URL(c.name, c.vars...)
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.url, url)
+		}
+	})
+
+	t.Run("invalid values are rejected", func(t *testing.T) {
+		invalid := map[string][]string{
+			"unknown route":         {"unknown"},
+			"missing variable":      {"contact", "id", "1"},
+			"unknown variable":      {"users", "id", "1"},
+			"value of another type": {"contact", "id", "1", "contactId", "abc"},
+			"odd number of values":  {"contact", "id"},
+			"empty value":           {"tag", "name", ""},
+		}
+
+		for name, args := range invalid {
+			_, err := router.URL(args[0], args[1:]...)
+
+			assert.Error(t, err, name)
+		}
+	})
+
+	t.Run("names are unique", func(t *testing.T) {
+		assert.Panics(t, func() { router.Add("/tags", http.MethodGet, handler).Name("users") })
+	})
+
+	t.Run("requests build the canonical path of the route that matched them", func(t *testing.T) {
+		var path string
+		router.Add("/users/{id:int}", http.MethodGet, func(w http.ResponseWriter, r UrlRequest) {
+			path = r.Path()
+		})
+
+		req, _ := http.NewRequest(http.MethodGet, "/users/7/", nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, "/users/7", path)
+	})
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/pedrorochaorg/contactsApi/tracing"
+)
+
+// Trace returns a middleware that records a server span for every request, named after the method and the route
+// template of the request. The span continues the trace propagated by the client in the traceparent header and is
+// stored in the request context, so that the spans started by handlers and repositories become it's children.
+//
+// Replies with a server error status mark the span as failed.
+func Trace(tracer *tracing.Tracer) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r UrlRequest) {
+			route := UnmatchedRoute
+			attributes := []tracing.Attribute{
+				{Key: "http.request.method", Value: r.R.Method},
+				{Key: "url.path", Value: r.R.URL.Path},
+				{Key: "client.address", Value: r.R.RemoteAddr},
+			}
+			if r.route != nil {
+				route = r.route.Pattern()
+				attributes = append(attributes, tracing.Attribute{Key: "http.route", Value: route})
+			}
+
+			ctx := tracing.Extract(r.R.Context(), r.R.Header)
+			ctx, span := tracer.Start(ctx, r.R.Method+" "+route, tracing.WithKind(tracing.KindServer),
+				tracing.WithAttributes(attributes...))
+			defer span.End()
+
+			r.R = r.R.WithContext(ctx)
+			recorder := &statusRecorder{ResponseWriter: w}
+			next(recorder, r)
+
+			span.SetAttribute("http.response.status_code", recorder.Status())
+			if recorder.Status() >= http.StatusInternalServerError {
+				span.SetError(errors.New(http.StatusText(recorder.Status())))
+			}
+		}
+	}
+}
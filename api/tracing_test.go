@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/tracing"
+	"github.com/pedrorochaorg/contactsApi/tracing/tracingtest"
+)
+
+func TestTrace(t *testing.T) {
+
+	exporter := &tracingtest.Recorder{}
+	tracer := tracing.NewTracer(exporter)
+
+	router := &Router{}
+	router.Use(Trace(tracer))
+	router.Add("/users/{id:int}", http.MethodGet, func(w http.ResponseWriter, r UrlRequest) {
+		SuccessReply(&Data{status: http.StatusOK, message: ContentReady, data: r.Vars["id"]}, w, r.R)
+	})
+	router.Add("/broken", http.MethodGet, func(w http.ResponseWriter, r UrlRequest) {
+		FailureReply(internalError, w, r.R)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/users/12", nil)
+	req.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	req, _ = http.NewRequest(http.MethodGet, "/broken", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	req, _ = http.NewRequest(http.MethodGet, "/unknown", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.NoError(t, tracer.Shutdown(context.Background()))
+
+	spans := map[string]tracing.SpanData{}
+	for _, span := range exporter.Spans() {
+		spans[span.Name] = span
+	}
+
+	server, ok := spans["GET /users/{id:int}"]
+	if !assert.True(t, ok, "The span is named after the route template") {
+		return
+	}
+	assert.Equal(t, tracing.KindServer, server.Kind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.Context.TraceID.String(),
+		"The span continues the trace of the client")
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.String())
+	assert.Contains(t, server.Attributes, tracing.Attribute{Key: "http.route", Value: "/users/{id:int}"})
+	assert.Contains(t, server.Attributes, tracing.Attribute{Key: "http.response.status_code", Value: http.StatusOK})
+
+	encoded := false
+	for _, span := range exporter.Spans() {
+		encoded = encoded || (span.Name == "encode reply" && span.Parent == server.Context.SpanID)
+	}
+	assert.True(t, encoded, "Encoding the reply has it's own span")
+
+	assert.Equal(t, tracing.StatusError, spans["GET /broken"].Status, "Server errors mark the span as failed")
+	assert.Contains(t, spans, "GET "+UnmatchedRoute)
+}
@@ -1,10 +1,9 @@
 package api
 
 import (
-	"encoding/json"
-	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/pedrorochaorg/contactsApi/obj"
 	"github.com/pedrorochaorg/contactsApi/repos"
@@ -14,63 +13,97 @@ const (
 	UserCreatedSuccessfully  = "User successfully created!"
 	UserUpdatedSuccessfully  = "User successfully updated!"
 	UserDeletedSuccessfully  = "User successfully deleted!"
-	BadIdFormat  = "Bad id format!"
 	UserNotFound  = "User not found!"
+	BadInclude  = "Unsupported include value!"
+
+	IncludeContacts = "contacts"
+)
+
+// Names of the routes of the users and their contacts, used to build their URLs
+const (
+	RouteUsers    = "users"
+	RouteUser     = "user"
+	RouteContacts = "contacts"
+	RouteContact  = "contact"
 )
 
 type UserHandler struct {
-	repo repos.UserRepo
+	repo     repos.UserRepo
+	contacts repos.ContactRepo
 	*http.ServeMux
-	handlers Handlers
+	router Router
 }
 
-func NewUserHandler(db repos.UserRepo) *UserHandler {
+func NewUserHandler(db repos.UserRepo, contacts repos.ContactRepo) *UserHandler {
 	handler := new(UserHandler)
 
 	handler.repo = db
+	handler.contacts = contacts
+
+	users := handler.router.Group("/users")
+	users.Add("", http.MethodGet, handler.listUsers).Name(RouteUsers)
+	users.Add("", http.MethodPost, handler.createUser)
+
+	user := users.Group("/{id:int}")
+	user.Add("", http.MethodGet, handler.getUser).Name(RouteUser)
+	user.Add("", http.MethodPut, handler.updateUser)
+	user.Add("", http.MethodPatch, handler.patchUser)
+	user.Add("", http.MethodDelete, handler.deleteUser)
+
+	contact := user.Group("/contacts")
+	contact.Add("", http.MethodGet, handler.listContacts).Name(RouteContacts)
+	contact.Add("", http.MethodPost, handler.createContact)
+	contact.Add("/{contactId:int}", http.MethodGet, handler.getContact).Name(RouteContact)
+	contact.Add("/{contactId:int}", http.MethodPut, handler.updateContact)
+	contact.Add("/{contactId:int}", http.MethodPatch, handler.patchContact)
+	contact.Add("/{contactId:int}", http.MethodDelete, handler.deleteContact)
 
-	handler.handlers = Handlers{}
+	return handler
+}
 
-	handler.handlers.Add("", http.MethodGet, handler.listUsers)
-	handler.handlers.Add("", http.MethodPost, handler.createUser)
-	handler.handlers.Add("/{id}", http.MethodGet, handler.getUser)
-	handler.handlers.Add("/{id}", http.MethodPut, handler.updateUser)
-	handler.handlers.Add("/{id}", http.MethodDelete, handler.deleteUser)
+// Use registers middlewares that run for every request served by the handler
+func (u *UserHandler) Use(middlewares ...Middleware) {
+	u.router.Use(middlewares...)
+}
 
-	return handler
+// Routes returns the router with the routes of the users and their contacts, to be mounted by another router
+func (u *UserHandler) Routes() *Router {
+	return &u.router
 }
 
 func (u *UserHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.router.ServeHTTP(w, r)
+}
 
-	subPath := r.URL.Path[len("/users/"):]
+func (u *UserHandler) listUsers(w http.ResponseWriter, r UrlRequest) {
 
-	if len(subPath) > 0 && subPath[len(subPath)-1:] == "/" {
-		subPath = subPath[:len(subPath)-1]
+	withContacts, ok := includesContacts(w, r)
+	if !ok {
+		return
 	}
 
-	httpMethod := r.Method
-
-	handler, err := u.handlers.GetByMethodAndType(subPath, httpMethod)
-
-	if err != nil {
-		FailureReply(err, w, r)
+	opts, listErr := listOptions(r)
+	if listErr != nil {
+		FailureReply(listErr, w, r.R)
 		return
 	}
 
-	handler.H.handler(w, UrlRequest{R: r, Vars: handler.Vars})
-
-}
-
-func (u *UserHandler) listUsers(w http.ResponseWriter, r UrlRequest) {
-
-	users, err := u.repo.List(r.R.Context())
+	users, page, err := u.repo.List(r.R.Context(), ownedUsers(r.R, opts))
 	if err != nil {
-		FailureReply(&Error{msg: err.Error(), status: 500}, w, r.R)
+		repositoryFailure(err, routeNotFound, w, r.R)
 		return
 	}
 
+	if withContacts {
+		err = u.embedContacts(r, users)
+		if err != nil {
+			repositoryFailure(err, routeNotFound, w, r.R)
+			return
+		}
+	}
+
 	SuccessReply(
-		&Data{status: 200, message: ContentReady, data: users},
+		&Data{status: 200, message: ContentReady, data: users, pagination: paginationLinks(r, page)},
 		w,
 		r.R,
 	)
@@ -78,21 +111,30 @@ func (u *UserHandler) listUsers(w http.ResponseWriter, r UrlRequest) {
 }
 
 func (u *UserHandler) createUser(w http.ResponseWriter, r UrlRequest) {
+	// Only admins can create users, everyone else is told the route doesn't exist
+	if !isAdmin(r.R) {
+		FailureReply(routeNotFound, w, r.R)
+		return
+	}
+
 	user := obj.User{}
-	err := json.NewDecoder(r.R.Body).Decode(&user)
-	if err != nil {
-		FailureReply(&Error{msg: err.Error(), status: 400}, w, r.R)
+	decodeErr := decodeBody(r.R, &user, userWritableFields)
+	if decodeErr != nil {
+		FailureReply(decodeErr, w, r.R)
 		return
 	}
 
 	finalUser, err := u.repo.Create(r.R.Context(), &user)
 	if err != nil {
-		FailureReply(&Error{msg: err.Error(), status: 500}, w, r.R)
+		repositoryFailure(err, userNotFound, w, r.R)
 		return
 	}
 
+	location := resourceURL(r, RouteUser, "id", strconv.Itoa(finalUser.ID))
+
 	SuccessReply(
-		&Data{status: http.StatusCreated, message: UserCreatedSuccessfully, data: finalUser},
+		&Data{status: http.StatusCreated, message: UserCreatedSuccessfully, data: finalUser,
+			etag: etag(finalUser.Version), location: location, contentLocation: location},
 		w,
 		r.R,
 	)
@@ -101,120 +143,212 @@ func (u *UserHandler) createUser(w http.ResponseWriter, r UrlRequest) {
 
 func (u *UserHandler) getUser(w http.ResponseWriter, r UrlRequest) {
 
-	userId, err := strconv.Atoi(r.Vars["id"])
-	if err != nil {
-			FailureReply(&Error{msg: BadIdFormat, status: 400}, w, r.R)
-			return
+	userId := r.Int("id")
+
+	if !authorizeUser(w, r.R, userId) {
+		return
+	}
+
+	withContacts, ok := includesContacts(w, r)
+	if !ok {
+		return
 	}
 
 	user, err := u.repo.Get(r.R.Context(), userId)
 	if err != nil {
-		FailureReply(&Error{msg: UserNotFound, status: 404}, w, r.R)
+		repositoryFailure(err, userNotFound, w, r.R)
 		return
 	}
 
+	// The version of the user doesn't change with it's contacts, so representations that embed them have no ETag
+	tag := ""
+	if withContacts {
+		users := []obj.User{*user}
+		err = u.embedContacts(r, users)
+		if err != nil {
+			repositoryFailure(err, routeNotFound, w, r.R)
+			return
+		}
+		user = &users[0]
+	} else {
+		tag = etag(user.Version)
+		if notModified(r.R, tag) {
+			NotModifiedReply(tag, w)
+			return
+		}
+	}
+
 	SuccessReply(
-		&Data{status: http.StatusOK, message: ContentReady, data: user},
+		&Data{status: http.StatusOK, message: ContentReady, data: user, etag: tag},
 		w,
 		r.R,
 	)
 }
 
 
+// updateUser replaces every writable field of the user with the values in the request body, fields missing from the
+// body are cleared
 func (u *UserHandler) updateUser(w http.ResponseWriter, r UrlRequest) {
 
-	userId, err := strconv.Atoi(r.Vars["id"])
-	if err != nil {
-		FailureReply(&Error{msg: BadIdFormat, status: 400}, w, r.R)
+	userId := r.Int("id")
+
+	if !authorizeUser(w, r.R, userId) {
 		return
 	}
 
-	user, err := u.repo.Get(r.R.Context(), int(userId))
+	stored, err := u.repo.Get(r.R.Context(), userId)
 	if err != nil {
-		FailureReply(&Error{msg: UserNotFound, status: 404}, w, r.R)
+		repositoryFailure(err, userNotFound, w, r.R)
 		return
 	}
 
-	updatedUser := obj.User{}
-	err = json.NewDecoder(r.R.Body).Decode(&updatedUser)
-	if err != nil {
-		FailureReply(&Error{msg: err.Error(), status: 400}, w, r.R)
+	matchErr := checkIfMatch(r.R, stored.Version)
+	if matchErr != nil {
+		FailureReply(matchErr, w, r.R)
 		return
 	}
 
-	user.FirstName = updatedUser.FirstName
-	user.LastName = updatedUser.LastName
+	user := obj.User{}
+	decodeErr := decodeBody(r.R, &user, userWritableFields)
+	if decodeErr != nil {
+		FailureReply(decodeErr, w, r.R)
+		return
+	}
+	user.ID = userId
+	user.Version = stored.Version
 
-	finalUser, err := u.repo.Update(r.R.Context(), user)
+	finalUser, err := u.repo.Update(r.R.Context(), &user)
 	if err != nil {
-		FailureReply(&Error{msg: err.Error(), status: 500}, w, r.R)
+		repositoryFailure(err, userNotFound, w, r.R)
 		return
 	}
 
 	SuccessReply(
-		&Data{status: http.StatusAccepted, message: UserUpdatedSuccessfully, data: finalUser},
+		&Data{status: http.StatusOK, message: UserUpdatedSuccessfully, data: finalUser,
+			etag: etag(finalUser.Version), contentLocation: r.Path()},
 		w,
 		r.R,
 	)
 
 }
 
+// patchUser applies a merge patch or JSON patch to the user, persisting only the fields it changed
+func (u *UserHandler) patchUser(w http.ResponseWriter, r UrlRequest) {
 
-func (u *UserHandler) deleteUser(w http.ResponseWriter, r UrlRequest) {
+	userId := r.Int("id")
 
-	userId, err := strconv.Atoi(r.Vars["id"])
-	if err != nil {
-		FailureReply(&Error{msg: BadIdFormat, status: 400}, w, r.R)
+	if !authorizeUser(w, r.R, userId) {
 		return
 	}
 
-	_, err = u.repo.Get(r.R.Context(), userId)
+	user, err := u.repo.Get(r.R.Context(), userId)
 	if err != nil {
-		FailureReply(&Error{msg: UserNotFound, status: 404}, w, r.R)
+		repositoryFailure(err, userNotFound, w, r.R)
+		return
+	}
+
+	matchErr := checkIfMatch(r.R, user.Version)
+	if matchErr != nil {
+		FailureReply(matchErr, w, r.R)
 		return
 	}
 
-	_, err = u.repo.Delete(r.R.Context(), userId)
+	patched := obj.User{}
+	changed, patchErr := applyPatch(r.R, user, &patched, userWritableFields)
+	if patchErr != nil {
+		FailureReply(patchErr, w, r.R)
+		return
+	}
+	patched.ID = userId
+	patched.Version = user.Version
+
+	finalUser, err := u.repo.Patch(r.R.Context(), &patched, changed)
 	if err != nil {
-		FailureReply(&Error{msg: err.Error(), status: 400}, w, r.R)
+		repositoryFailure(err, userNotFound, w, r.R)
 		return
 	}
 
 	SuccessReply(
-		&Data{status: http.StatusNoContent, message: UserDeletedSuccessfully, data: nil},
+		&Data{status: http.StatusOK, message: UserUpdatedSuccessfully, data: finalUser,
+			etag: etag(finalUser.Version), contentLocation: r.Path()},
 		w,
 		r.R,
 	)
-
 }
 
 
-func (u *UserHandler) getUserContacts(w http.ResponseWriter, r UrlRequest) {
+func (u *UserHandler) deleteUser(w http.ResponseWriter, r UrlRequest) {
 
-	userId, err := strconv.Atoi(r.Vars["userId"])
-	if err != nil {
-		FailureReply(&Error{msg: BadIdFormat, status: 400}, w, r.R)
+	userId := r.Int("id")
+
+	if !authorizeUser(w, r.R, userId) {
 		return
 	}
 
-	contactId, err := strconv.Atoi(r.Vars["id"])
+	user, err := u.repo.Get(r.R.Context(), userId)
 	if err != nil {
-		FailureReply(&Error{msg: BadIdFormat, status: 400}, w, r.R)
+		repositoryFailure(err, userNotFound, w, r.R)
 		return
 	}
 
-	log.Println("ContactId", contactId)
+	matchErr := checkIfMatch(r.R, user.Version)
+	if matchErr != nil {
+		FailureReply(matchErr, w, r.R)
+		return
+	}
 
-	user, err := u.repo.Get(r.R.Context(), userId)
+	_, err = u.repo.Delete(r.R.Context(), userId, user.Version)
 	if err != nil {
-		FailureReply(&Error{msg: UserNotFound, status: 404}, w, r.R)
+		repositoryFailure(err, userNotFound, w, r.R)
 		return
 	}
 
 	SuccessReply(
-		&Data{status: http.StatusOK, message: ContentReady, data: user},
+		&Data{status: http.StatusNoContent, message: UserDeletedSuccessfully, data: nil},
 		w,
 		r.R,
 	)
+
 }
 
+// includesContacts parses the 'include' query parameter, a comma separated list of relations that should be embedded
+// in the returned users. It replies with a bad request and returns a false ok value when an unknown relation is
+// requested.
+func includesContacts(w http.ResponseWriter, r UrlRequest) (contacts bool, ok bool) {
+	include := r.R.URL.Query().Get("include")
+	if include == "" {
+		return false, true
+	}
+
+	for _, relation := range strings.Split(include, ",") {
+		switch strings.TrimSpace(relation) {
+		case IncludeContacts:
+			contacts = true
+		default:
+			FailureReply(&Error{msg: BadInclude, status: http.StatusBadRequest, code: CodeBadInclude}, w, r.R)
+			return false, false
+		}
+	}
+
+	return contacts, true
+}
+
+// embedContacts fills the Contacts property of every user in the slice, fetching the contacts of all of them with a
+// single call to the contacts repository instead of one call per user.
+func (u *UserHandler) embedContacts(r UrlRequest, users []obj.User) error {
+	ids := make([]int, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+
+	contacts, err := u.contacts.ListByUsers(r.R.Context(), ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range users {
+		users[i].Contacts = contacts[users[i].ID]
+	}
+
+	return nil
+}
@@ -7,13 +7,13 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
-	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/pedrorochaorg/contactsApi/obj"
+	"github.com/pedrorochaorg/contactsApi/repos"
 )
 
 func TestNewUserHandler(t *testing.T) {
@@ -22,14 +22,13 @@ func TestNewUserHandler(t *testing.T) {
 	parsedTime, _ := time.Parse(time.RFC3339, "2019-11-22T10:00:00Z")
 
 	userList := []obj.User{
-		obj.User{1, "John", "Cena", parsedTime, parsedTime, nil},
-		obj.User{2, "João", "Cenas", parsedTime, parsedTime, nil},
-		obj.User{3, "Pedro", "Costas", parsedTime, parsedTime, nil},
+		{ID: 1, FirstName: "John", LastName: "Cena", CreatedAt: parsedTime, UpdatedAt: parsedTime},
+		{ID: 2, FirstName: "João", LastName: "Cenas", CreatedAt: parsedTime, UpdatedAt: parsedTime},
+		{ID: 3, FirstName: "Pedro", LastName: "Costas", CreatedAt: parsedTime, UpdatedAt: parsedTime},
 	}
 
-	userHandler := NewUserHandler(
-		&StubUserRepo{users: userList},
-	)
+	store := newStore(t, userList, nil)
+	userHandler := asAdmin(NewUserHandler(store.Users(), store.Contacts()))
 
 	t.Run("fetch a user by id", func(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d", 2), nil)
@@ -75,25 +74,21 @@ func TestNewUserHandler(t *testing.T) {
 			t.Fatalf("error while unmarshling the response body %s", err)
 		}
 
-		assert.Equal(t, http.StatusBadRequest, response.Code, "Status Code doesn't match")
-		assert.Equal(t, BadIdFormat, message)
+		assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match")
+		assert.Equal(t, ErrNotFound, message)
 	})
 
 
 	t.Run("delete a user by id", func(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%d", 2), nil)
+		req.Header.Set("If-Match", "*")
 		response := httptest.NewRecorder()
 
 		userHandler.ServeHTTP(response, req)
 
-		message, err := getResponseMessage(response.Body)
-
-		if err != nil {
-			t.Fatalf("error while unmarshling the response body %s", err)
-		}
-
 		assert.Equal(t, http.StatusNoContent, response.Code, "Status Code doesn't match")
-		assert.Equal(t, UserDeletedSuccessfully, message)
+		assert.Empty(t, response.Body.String(), "A 204 response shouldn't have a body")
+		assert.Empty(t, response.Header().Get("Content-Type"), "A 204 response shouldn't have a content type")
 
 		// Check that the user was really deleted
 		req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d", 2), nil)
@@ -101,7 +96,7 @@ func TestNewUserHandler(t *testing.T) {
 
 		userHandler.ServeHTTP(response, req)
 
-		message, err = getResponseMessage(response.Body)
+		message, err := getResponseMessage(response.Body)
 
 		if err != nil {
 			t.Fatalf("error while unmarshling the response body %s", err)
@@ -114,6 +109,7 @@ func TestNewUserHandler(t *testing.T) {
 
 	t.Run("delete an unexisting user by id", func(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%d", 4), nil)
+		req.Header.Set("If-Match", "*")
 		response := httptest.NewRecorder()
 
 		userHandler.ServeHTTP(response, req)
@@ -130,6 +126,7 @@ func TestNewUserHandler(t *testing.T) {
 
 	t.Run("delete an user using an invalid id format", func(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodDelete, "/users/invalid", nil)
+		req.Header.Set("If-Match", "*")
 		response := httptest.NewRecorder()
 
 		userHandler.ServeHTTP(response, req)
@@ -140,16 +137,16 @@ func TestNewUserHandler(t *testing.T) {
 			t.Fatalf("error while unmarshling the response body %s", err)
 		}
 
-		assert.Equal(t, http.StatusBadRequest, response.Code, "Status Code doesn't match")
-		assert.Equal(t, BadIdFormat, message)
+		assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match")
+		assert.Equal(t, ErrNotFound, message)
 	})
 
 
 	t.Run("create a new user", func(t *testing.T) {
 
-		bodyData, err := json.Marshal(obj.User{
-			FirstName: "José",
-			LastName:  "Santos",
+		bodyData, err := json.Marshal(map[string]string{
+			"first_name": "José",
+			"last_name":  "Santos",
 		})
 		if err != nil {
 			t.Fatalf("error marshling request body %s", err)
@@ -180,6 +177,8 @@ func TestNewUserHandler(t *testing.T) {
 		assert.Equal(t, "José", user.FirstName)
 		assert.Equal(t, "Santos", user.LastName)
 		assert.Equal(t, nextId, user.ID)
+		assert.Equal(t, fmt.Sprintf("/users/%d", user.ID), response.Header().Get("Location"))
+		assert.Equal(t, fmt.Sprintf("/users/%d", user.ID), response.Header().Get("Content-Location"))
 
 
 
@@ -228,9 +227,9 @@ func TestNewUserHandler(t *testing.T) {
 
 		originalUser := userList[0]
 
-		bodyData, err := json.Marshal(obj.User{
-			FirstName: "Mário",
-			LastName:  "Figueira",
+		bodyData, err := json.Marshal(map[string]string{
+			"first_name": "Mário",
+			"last_name":  "Figueira",
 		})
 		if err != nil {
 			t.Fatalf("error marshling request body %s", err)
@@ -241,6 +240,7 @@ func TestNewUserHandler(t *testing.T) {
 			"/users/1",
 			bytes.NewBuffer(bodyData),
 		)
+		req.Header.Set("If-Match", "*")
 		response := httptest.NewRecorder()
 
 		userHandler.ServeHTTP(response, req)
@@ -251,19 +251,20 @@ func TestNewUserHandler(t *testing.T) {
 			t.Fatalf("error while unmarshling the response body %s", err)
 		}
 
-		assert.Equal(t, http.StatusAccepted, response.Code, "Status Code doesn't match")
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
 		assert.Equal(t, "Mário", user.FirstName)
 		assert.Equal(t, "Figueira", user.LastName)
 		assert.NotEqual(t, originalUser.FirstName, user.FirstName)
 		assert.NotEqual(t, originalUser.LastName, user.LastName)
 		assert.Equal(t, originalUser.ID, user.ID)
+		assert.Equal(t, "/users/1", response.Header().Get("Content-Location"))
 
 	})
 
 	t.Run( "update an unexisting user", func(t *testing.T) {
-		bodyData, err := json.Marshal(obj.User{
-			FirstName: "Mário",
-			LastName:  "Figueira",
+		bodyData, err := json.Marshal(map[string]string{
+			"first_name": "Mário",
+			"last_name":  "Figueira",
 		})
 		if err != nil {
 			t.Fatalf("error marshling request body %s", err)
@@ -274,6 +275,7 @@ func TestNewUserHandler(t *testing.T) {
 			"/users/52",
 			bytes.NewBuffer(bodyData),
 		)
+		req.Header.Set("If-Match", "*")
 		response := httptest.NewRecorder()
 
 		userHandler.ServeHTTP(response, req)
@@ -289,9 +291,9 @@ func TestNewUserHandler(t *testing.T) {
 	})
 
 	t.Run( "update an user using an invalid id format", func(t *testing.T) {
-		bodyData, err := json.Marshal(obj.User{
-			FirstName: "Mário",
-			LastName:  "Figueira",
+		bodyData, err := json.Marshal(map[string]string{
+			"first_name": "Mário",
+			"last_name":  "Figueira",
 		})
 		if err != nil {
 			t.Fatalf("error marshling request body %s", err)
@@ -302,6 +304,7 @@ func TestNewUserHandler(t *testing.T) {
 			"/users/invalid",
 			bytes.NewBuffer(bodyData),
 		)
+		req.Header.Set("If-Match", "*")
 		response := httptest.NewRecorder()
 
 		userHandler.ServeHTTP(response, req)
@@ -312,8 +315,8 @@ func TestNewUserHandler(t *testing.T) {
 			t.Fatalf("error while unmarshling the response body %s", err)
 		}
 
-		assert.Equal(t, http.StatusBadRequest, response.Code, "Status Code doesn't match")
-		assert.Equal(t, BadIdFormat, message)
+		assert.Equal(t, http.StatusNotFound, response.Code, "Status Code doesn't match")
+		assert.Equal(t, ErrNotFound, message)
 	})
 
 	t.Run( "update an user with an invalid request body", func(t *testing.T) {
@@ -323,6 +326,7 @@ func TestNewUserHandler(t *testing.T) {
 			"/users/1",
 			bytes.NewBuffer([]byte("something not usefull")),
 		)
+		req.Header.Set("If-Match", "*")
 		response := httptest.NewRecorder()
 
 		userHandler.ServeHTTP(response, req)
@@ -339,97 +343,172 @@ func TestNewUserHandler(t *testing.T) {
 
 }
 
+func TestUserHandler_IncludeContacts(t *testing.T) {
 
+	parsedTime, _ := time.Parse(time.RFC3339, "2019-11-22T10:00:00Z")
 
+	userList := []obj.User{
+		{ID: 1, FirstName: "John", LastName: "Cena", CreatedAt: parsedTime, UpdatedAt: parsedTime},
+		{ID: 2, FirstName: "João", LastName: "Cenas", CreatedAt: parsedTime, UpdatedAt: parsedTime},
+		{ID: 3, FirstName: "Pedro", LastName: "Costas", CreatedAt: parsedTime, UpdatedAt: parsedTime},
+	}
 
+	contactList := []obj.Contact{
+		{ID: 1, UserID: 1, FirstName: "Ana", CreatedAt: parsedTime, UpdatedAt: parsedTime},
+		{ID: 2, UserID: 1, FirstName: "Rui", CreatedAt: parsedTime, UpdatedAt: parsedTime},
+		{ID: 3, UserID: 2, FirstName: "Eva", CreatedAt: parsedTime, UpdatedAt: parsedTime},
+	}
 
-type StubUserRepo struct {
-	sync.Mutex
-	users []obj.User
-}
+	t.Run("list users embedding their contacts with a single batched call", func(t *testing.T) {
+		store, calls := newStore(t, userList, contactList), map[string]int{}
+		userHandler := asAdmin(NewUserHandler(store.Users(),
+			repos.ObserveContacts(store.Contacts(), countCalls(calls))))
 
-func (s *StubUserRepo) List(ctx context.Context) ([]obj.User, error) {
-	return s.users, nil
-}
+		req, _ := http.NewRequest(http.MethodGet, "/users/?include=contacts", nil)
+		response := httptest.NewRecorder()
 
-func (s *StubUserRepo) Create(ctx context.Context, user *obj.User) (*obj.User, error) {
-	newUser := obj.User{
-		ID:        getNextId(s.users),
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Contacts:  nil,
-	}
+		userHandler.ServeHTTP(response, req)
 
-	s.users = append(s.users, newUser)
+		users, err := getUsersFromResponse(response.Body)
 
-	return &newUser, nil
-}
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
 
-func (s *StubUserRepo) Update(ctx context.Context, user *obj.User) (*obj.User, error) {
-	var updatedUser *obj.User = nil
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, map[string]int{"ListByUsers": 1}, calls, "contacts should be fetched with a single call")
+		assert.Equal(t, contactList[:2], users[0].Contacts)
+		assert.Equal(t, contactList[2:], users[1].Contacts)
+		assert.Empty(t, users[2].Contacts)
+	})
 
-	for _, v := range s.users {
-		if v.ID == user.ID {
-			updatedUser = &v
-			break
-		}
-	}
+	t.Run("fetch a user embedding it's contacts", func(t *testing.T) {
+		store := newStore(t, userList, contactList)
+		userHandler := asAdmin(NewUserHandler(store.Users(), store.Contacts()))
 
-	if updatedUser == nil {
-		return nil, fmt.Errorf("User not found %d", user.ID)
-	}
+		req, _ := http.NewRequest(http.MethodGet, "/users/1?include=contacts", nil)
+		response := httptest.NewRecorder()
 
-	updatedUser.FirstName = user.FirstName
-	updatedUser.LastName = user.LastName
+		userHandler.ServeHTTP(response, req)
 
-	updatedUser.UpdatedAt = time.Now()
+		user, err := getUserFromResponse(response.Body)
 
-	return updatedUser, nil
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
 
-}
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Equal(t, contactList[:2], user.Contacts)
+	})
 
-func (s *StubUserRepo) Get(ctx context.Context, id int) (*obj.User, error) {
-	var fetchedUser *obj.User = nil
+	t.Run("contacts aren't embedded unless requested", func(t *testing.T) {
+		store, calls := newStore(t, userList, contactList), map[string]int{}
+		userHandler := asAdmin(NewUserHandler(store.Users(),
+			repos.ObserveContacts(store.Contacts(), countCalls(calls))))
 
-	for _, v := range s.users {
-		if v.ID == id {
+		req, _ := http.NewRequest(http.MethodGet, "/users/1", nil)
+		response := httptest.NewRecorder()
 
-			fetchedUser = &v
-			break
+		userHandler.ServeHTTP(response, req)
+
+		user, err := getUserFromResponse(response.Body)
+
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
 		}
-	}
 
-	if fetchedUser == nil {
-		return nil, fmt.Errorf("User not found %d", id)
-	}
+		assert.Equal(t, http.StatusOK, response.Code, "Status Code doesn't match")
+		assert.Nil(t, user.Contacts)
+		assert.Empty(t, calls, "contacts shouldn't be fetched")
+	})
 
-	return fetchedUser, nil
-}
+	t.Run("an unsupported include value should return a bad request", func(t *testing.T) {
+		store := newStore(t, userList, contactList)
+		userHandler := asAdmin(NewUserHandler(store.Users(), store.Contacts()))
 
-func (s *StubUserRepo) Delete(ctx context.Context, id int) (bool, error)  {
-	var fetchedUser *obj.User = nil
-	var fetchedUserIndex int = -1
+		req, _ := http.NewRequest(http.MethodGet, "/users/?include=friends", nil)
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
 
-	for i, v := range s.users {
-		if v.ID == id {
-			fetchedUser = &v
-			fetchedUserIndex = i
-			break
+		message, err := getResponseMessage(response.Body)
+
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusBadRequest, response.Code, "Status Code doesn't match")
+		assert.Equal(t, BadInclude, message)
+	})
+}
+
+// newStore returns a MemoryStore holding the users and contacts, created in the order they're given so that they get
+// the same ids. The rows are stamped with the times of the fixtures and written again until they reach their version,
+// the writes made afterwards are stamped with the current time.
+func newStore(t *testing.T, users []obj.User, contacts []obj.Contact) *repos.MemoryStore {
+	var stamp *time.Time
+	store := repos.NewMemoryStore(repos.WithMemoryClock(func() time.Time {
+		if stamp != nil {
+			return *stamp
+		}
+		return time.Now()
+	}))
+	ctx := context.Background()
+
+	for _, user := range users {
+		user := user
+		stamp = &user.CreatedAt
+		stored, err := store.Users().Create(ctx, &obj.User{FirstName: user.FirstName, LastName: user.LastName})
+		if err != nil {
+			t.Fatalf("error storing user %d: %s", user.ID, err)
+		}
+		if stored.ID != user.ID {
+			t.Fatalf("user %d was stored with the id %d, the users must be given in the order of their ids",
+				user.ID, stored.ID)
 		}
-	}
 
-	if fetchedUser == nil {
-		return false, fmt.Errorf("User not found %d", id)
+		stamp = &user.UpdatedAt
+		for stored.Version < user.Version {
+			stored, err = store.Users().Update(ctx, stored)
+			if err != nil {
+				t.Fatalf("error updating user %d: %s", user.ID, err)
+			}
+		}
 	}
 
-	s.users = append(s.users[:fetchedUserIndex], s.users[fetchedUserIndex+1:]...)
+	for _, contact := range contacts {
+		contact := contact
+		stamp = &contact.CreatedAt
+		stored, err := store.Contacts().Create(ctx, int(contact.UserID), &obj.Contact{FirstName: contact.FirstName,
+			LastName: contact.LastName, Email: contact.Email, Phone: contact.Phone})
+		if err != nil {
+			t.Fatalf("error storing contact %d: %s", contact.ID, err)
+		}
+		if stored.ID != contact.ID {
+			t.Fatalf("contact %d was stored with the id %d, the contacts must be given in the order of their ids",
+				contact.ID, stored.ID)
+		}
 
-	return true, nil
+		stamp = &contact.UpdatedAt
+		for stored.Version < contact.Version {
+			stored, err = store.Contacts().Update(ctx, int(contact.UserID), stored)
+			if err != nil {
+				t.Fatalf("error updating contact %d: %s", contact.ID, err)
+			}
+		}
+	}
 
+	stamp = nil
+	return store
 }
 
+// countCalls returns a hook that counts the calls made to each method of a repository in calls
+func countCalls(calls map[string]int) repos.Hook {
+	return func(ctx context.Context, repository, method string) (context.Context, func(err error)) {
+		calls[method]++
+		return ctx, func(err error) {}
+	}
+}
 
 func getNextId(users []obj.User) int {
 	var highestIndex = 0
@@ -466,7 +545,26 @@ func getUserFromResponse(response *bytes.Buffer) (*obj.User, error) {
 	return &user, nil
 }
 
+func getUsersFromResponse(response *bytes.Buffer) ([]obj.User, error) {
+	responseObject := Response{}
 
+	err := json.NewDecoder(response).Decode(&responseObject)
+	if err != nil {
+		return nil, err
+	}
+
+	responseData, err := json.Marshal(responseObject.Result)
+	if err != nil {
+		return nil, err
+	}
+
+	users := []obj.User{}
+	err = json.Unmarshal(responseData, &users)
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
 
 func getResponseMessage(response *bytes.Buffer) (string, error) {
 	responseObject := Response{}
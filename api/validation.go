@@ -0,0 +1,197 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/pedrorochaorg/contactsApi/obj"
+)
+
+const (
+	ValidationFailed = "The request contains invalid fields!"
+
+	CodeValidationFailed = "validation_failed"
+)
+
+// validatable an object decoded from a request body that checks it's own fields
+type validatable interface {
+	Validate() error
+}
+
+// decodeBody decodes the JSON request body into v and validates it. Unknown fields, fields that aren't writable,
+// repeated fields, values of the wrong type and fields that break the validation rules of v are all reported together
+// in a single unprocessable entity reply, bodies that aren't valid JSON are rejected with a bad request.
+func decodeBody(r *http.Request, v validatable, writable []string) *Error {
+	return decodeValid(r.Body, v, writable, nil)
+}
+
+// decodeValid decodes the JSON document read from body into v and validates it, violations found beforehand by the
+// caller are reported along with the ones found while decoding. Every member of the document is decoded on it's own
+// so that all the unknown fields and values of the wrong type are reported, not just the first one.
+//
+// Members that aren't in writable are reported as read-only, like the changes PATCH makes to them. A nil writable
+// accepts every field, for the patched documents whose read-only fields were already compared by changedFields.
+func decodeValid(body io.Reader, v validatable, writable []string, violations []Violation) *Error {
+	members, err := decodeMembers(body)
+	if err != nil {
+		return &Error{msg: err.Error(), status: http.StatusBadRequest, code: CodeMalformedBody}
+	}
+
+	fields := jsonFields(v)
+	seen := map[string]bool{}
+	for _, member := range members {
+		if seen[member.name] {
+			violations = append(violations, Violation{Field: member.name, Reason: "is repeated"})
+			continue
+		}
+		seen[member.name] = true
+
+		field, ok := fields[member.name]
+		if !ok {
+			violations = append(violations, Violation{Field: member.name, Reason: "unknown field"})
+			continue
+		}
+		if writable != nil && !contains(writable, member.name) {
+			violations = append(violations, Violation{Field: member.name, Reason: "is read-only"})
+			continue
+		}
+
+		err = json.Unmarshal(member.value, field.Addr().Interface())
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case err == nil:
+		case errors.As(err, &typeErr):
+			name := member.name
+			if typeErr.Field != "" {
+				name += "." + typeErr.Field
+			}
+			violations = append(violations, Violation{Field: name, Reason: "must be a " + jsonType(typeErr.Type)})
+		default:
+			violations = append(violations, Violation{Field: member.name, Reason: err.Error()})
+		}
+	}
+
+	reported := map[string]bool{}
+	for _, violation := range violations {
+		reported[violation.Field] = true
+	}
+
+	var validationErrors obj.ValidationErrors
+	if errors.As(v.Validate(), &validationErrors) {
+		for _, validationError := range validationErrors {
+			// A field that couldn't be decoded was already reported
+			if reported[validationError.Field] {
+				continue
+			}
+			violations = append(violations, Violation{Field: validationError.Field, Reason: validationError.Reason})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &Error{msg: ValidationFailed, status: http.StatusUnprocessableEntity, code: CodeValidationFailed,
+			violations: violations}
+	}
+
+	return nil
+}
+
+// member a member of a JSON object with it's value left undecoded
+type member struct {
+	name  string
+	value json.RawMessage
+}
+
+// decodeMembers reads the members of the JSON object in body in the order they appear, a null document has no
+// members and any other value that isn't an object is rejected, as well as anything that follows the document
+func decodeMembers(body io.Reader) ([]member, error) {
+	decoder := json.NewDecoder(body)
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, decodeEnd(decoder)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return nil, errors.New("the body must be a JSON object")
+	}
+
+	members := []member{}
+	for decoder.More() {
+		token, err = decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		m := member{name: token.(string)}
+		err = decoder.Decode(&m.value)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+
+	_, err = decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	return members, decodeEnd(decoder)
+}
+
+// decodeEnd checks that nothing but whitespace follows the document read by the decoder
+func decodeEnd(decoder *json.Decoder) error {
+	if decoder.More() {
+		return errors.New("the body must contain a single JSON object")
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("the body must contain a single JSON object")
+	}
+	return nil
+}
+
+// jsonFields returns the fields of the struct v points to, keyed by the name they have in JSON documents
+func jsonFields(v interface{}) map[string]reflect.Value {
+	value := reflect.ValueOf(v).Elem()
+	fields := map[string]reflect.Value{}
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = field.Name
+		}
+		fields[name] = value.Field(i)
+	}
+
+	return fields
+}
+
+// jsonType describes the JSON type expected for values of the go type
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8,
+		reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
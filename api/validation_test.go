@@ -0,0 +1,212 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/obj"
+	"github.com/pedrorochaorg/contactsApi/patch"
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+func TestDecodeBody(t *testing.T) {
+
+	store := repos.NewMemoryStore()
+	userHandler := asAdmin(NewUserHandler(store.Users(), store.Contacts()))
+
+	t.Run("every invalid field is reported at once", func(t *testing.T) {
+		body := `{"first_name": "", "last_name": "` + strings.Repeat("a", 91) + `", "age": 30}`
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/", bytes.NewBufferString(body))
+		req.Header.Set("Accept", ProblemContentType)
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		problem, err := getProblemFromResponse(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusUnprocessableEntity, response.Code, "Status Code doesn't match")
+		assert.Equal(t, CodeValidationFailed, problem.Code)
+		assert.Equal(t, []Violation{
+			{Field: "age", Reason: "unknown field"},
+			{Field: "first_name", Reason: "is required"},
+			{Field: "last_name", Reason: "must be at most 90 characters long"},
+		}, problem.Errors)
+	})
+
+	t.Run("values of the wrong type are reported once", func(t *testing.T) {
+		body := `{"first_name": 12, "last_name": "Cena"}`
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/", bytes.NewBufferString(body))
+		req.Header.Set("Accept", ProblemContentType)
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		problem, err := getProblemFromResponse(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusUnprocessableEntity, response.Code, "Status Code doesn't match")
+		assert.Equal(t, []Violation{{Field: "first_name", Reason: "must be a string"}}, problem.Errors)
+	})
+
+	t.Run("every unknown field and value of the wrong type is reported", func(t *testing.T) {
+		body := `{"foo": 1, "bar": 2, "first_name": 5, "last_name": ["Cena"]}`
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/", bytes.NewBufferString(body))
+		req.Header.Set("Accept", ProblemContentType)
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		problem, err := getProblemFromResponse(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusUnprocessableEntity, response.Code, "Status Code doesn't match")
+		assert.Equal(t, []Violation{
+			{Field: "foo", Reason: "unknown field"},
+			{Field: "bar", Reason: "unknown field"},
+			{Field: "first_name", Reason: "must be a string"},
+			{Field: "last_name", Reason: "must be a string"},
+		}, problem.Errors)
+	})
+
+	t.Run("bodies that aren't objects are a bad request", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/users/", bytes.NewBufferString(`["Pedro"]`))
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusBadRequest, response.Code, "Status Code doesn't match")
+	})
+
+	t.Run("invalid contacts are rejected before reaching the repository", func(t *testing.T) {
+		store := newStore(t, []obj.User{{ID: 1, FirstName: "John", LastName: "Cena"}}, nil)
+		handler := asAdmin(NewUserHandler(store.Users(), store.Contacts()))
+
+		body := `{"first_name": "Ana", "email": "ana", "phone": "call me"}`
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/1/contacts", bytes.NewBufferString(body))
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, response.Code, "Status Code doesn't match")
+		contacts, _, err := store.Contacts().List(context.Background(), 1, repos.ListOptions{})
+		assert.NoError(t, err)
+		assert.Empty(t, contacts, "the contact shouldn't have been created")
+	})
+
+	t.Run("bodies with anything after the object are a bad request", func(t *testing.T) {
+		for _, body := range []string{`{"first_name": "Ana"} {"last_name": "Lima"}`, `{"first_name": "Ana"}garbage`,
+			`null null`} {
+			req, _ := http.NewRequest(http.MethodPost, "/users/", bytes.NewBufferString(body))
+			response := httptest.NewRecorder()
+
+			userHandler.ServeHTTP(response, req)
+
+			assert.Equal(t, http.StatusBadRequest, response.Code, "Status Code doesn't match for %s", body)
+		}
+	})
+
+	t.Run("repeated fields are reported", func(t *testing.T) {
+		body := `{"first_name": "Ana", "last_name": "Lima", "first_name": "Eva"}`
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/", bytes.NewBufferString(body))
+		req.Header.Set("Accept", ProblemContentType)
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		problem, err := getProblemFromResponse(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusUnprocessableEntity, response.Code, "Status Code doesn't match")
+		assert.Equal(t, []Violation{{Field: "first_name", Reason: "is repeated"}}, problem.Errors)
+	})
+
+	t.Run("read-only fields are rejected alike by POST, PUT and PATCH", func(t *testing.T) {
+		store := newStore(t, []obj.User{{ID: 1, FirstName: "John", LastName: "Cena"}},
+			[]obj.Contact{{ID: 1, UserID: 1, FirstName: "Ana"}})
+		handler := asAdmin(NewUserHandler(store.Users(), store.Contacts()))
+
+		userBody := `{"id": 7, "first_name": "Ana", "last_name": "Lima"}`
+		contactBody := `{"user_id": 2, "first_name": "Eva"}`
+
+		cases := []struct {
+			method string
+			path   string
+			body   string
+			field  string
+		}{
+			{http.MethodPost, "/users/", userBody, "id"},
+			{http.MethodPut, "/users/1", userBody, "id"},
+			{http.MethodPatch, "/users/1", userBody, "id"},
+			{http.MethodPost, "/users/1/contacts", contactBody, "user_id"},
+			{http.MethodPut, "/users/1/contacts/1", contactBody, "user_id"},
+			{http.MethodPatch, "/users/1/contacts/1", contactBody, "user_id"},
+		}
+
+		for _, c := range cases {
+			req, _ := http.NewRequest(c.method, c.path, bytes.NewBufferString(c.body))
+			req.Header.Set("Accept", ProblemContentType)
+			req.Header.Set("If-Match", "*")
+			if c.method == http.MethodPatch {
+				req.Header.Set("Content-Type", patch.MergePatchContentType)
+			}
+			response := httptest.NewRecorder()
+
+			handler.ServeHTTP(response, req)
+
+			problem, err := getProblemFromResponse(response.Body)
+			if err != nil {
+				t.Fatalf("error while unmarshling the response body %s", err)
+			}
+
+			assert.Equal(t, http.StatusUnprocessableEntity, response.Code, "Status Code doesn't match for %s %s",
+				c.method, c.path)
+			assert.Equal(t, []Violation{{Field: c.field, Reason: "is read-only"}}, problem.Errors,
+				"Violations don't match for %s %s", c.method, c.path)
+		}
+		users, _, err := store.Users().List(context.Background(), repos.ListOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, []obj.User{{ID: 1, FirstName: "John", LastName: "Cena", Version: 1}}, users,
+			"the user shouldn't have been created or changed")
+
+		contacts, _, err := store.Contacts().List(context.Background(), 1, repos.ListOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, []obj.Contact{{ID: 1, UserID: 1, FirstName: "Ana", Version: 1}}, contacts,
+			"the contact shouldn't have been created or changed")
+	})
+
+	t.Run("malformed JSON is a bad request", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/users/", bytes.NewBufferString(`{"first_name": `))
+		req.Header.Set("Accept", ProblemContentType)
+		response := httptest.NewRecorder()
+
+		userHandler.ServeHTTP(response, req)
+
+		problem, err := getProblemFromResponse(response.Body)
+		if err != nil {
+			t.Fatalf("error while unmarshling the response body %s", err)
+		}
+
+		assert.Equal(t, http.StatusBadRequest, response.Code, "Status Code doesn't match")
+		assert.Equal(t, CodeMalformedBody, problem.Code)
+	})
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+// keyPrefix prefix of every generated API key, it makes leaked keys easy to recognise
+const keyPrefix = "cak_"
+
+// GenerateKey returns a new random API key and the hash under which it must be stored
+func GenerateKey() (key string, hash string, err error) {
+	secret := make([]byte, 32)
+	_, err = rand.Read(secret)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %s", err)
+	}
+
+	key = keyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return key, HashKey(key), nil
+}
+
+// HashKey returns the hex encoded SHA-256 hash of an API key. Keys are random enough for a fast hash to be safe, and
+// unlike a salted hash it allows looking keys up by their hash.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// KeyAuthenticator authenticates API keys against the hashes stored in a repository
+type KeyAuthenticator struct {
+	repo repos.APIKeyRepo
+}
+
+// NewKeyAuthenticator instantiates a key authenticator injecting the api key repository as a dependency
+func NewKeyAuthenticator(repo repos.APIKeyRepo) *KeyAuthenticator {
+	return &KeyAuthenticator{repo: repo}
+}
+
+// Authenticate returns the principal of an API key, unknown and revoked keys are invalid credentials
+func (k *KeyAuthenticator) Authenticate(ctx context.Context, key string) (*Principal, error) {
+	apiKey, err := k.repo.GetByHash(ctx, HashKey(key))
+	if errors.Is(err, repos.ErrNotFound) {
+		return nil, fmt.Errorf("%w: unknown api key", ErrInvalidCredentials)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Principal{Subject: "key:" + apiKey.Name, UserID: apiKey.UserID, Admin: apiKey.Admin}, nil
+}
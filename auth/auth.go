@@ -0,0 +1,106 @@
+// Package auth authenticates the clients of the API, either by an API key sent in the X-API-Key header or by a JWT
+// bearer token sent in the Authorization header, and carries the authenticated principal in the request context.
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	// APIKeyHeader header that carries an API key
+	APIKeyHeader = "X-API-Key"
+	// AdminRole role of the principals that can act on every user
+	AdminRole = "admin"
+)
+
+var (
+	// ErrMissingCredentials the request doesn't carry any credentials
+	ErrMissingCredentials = errors.New("missing credentials")
+	// ErrInvalidCredentials the credentials of the request are unknown, expired or malformed
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal the authenticated client of a request
+type Principal struct {
+	// Subject identifies the client, the name of an API key or the subject of a token
+	Subject string
+	// UserID the user the client acts as, zero when it isn't tied to a user
+	UserID int
+	// Admin whether the client can act on every user
+	Admin bool
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of the context that carries the principal
+func NewContext(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// FromContext returns the principal carried by the context, if any
+func FromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(contextKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// Authenticator authenticates requests with the credentials it was configured to accept
+type Authenticator struct {
+	keys   *KeyAuthenticator
+	tokens *Verifier
+}
+
+// Option configures the credentials accepted by an Authenticator
+type Option func(a *Authenticator)
+
+// WithAPIKeys accepts the API keys known by the key authenticator
+func WithAPIKeys(keys *KeyAuthenticator) Option {
+	return func(a *Authenticator) {
+		a.keys = keys
+	}
+}
+
+// WithTokens accepts the bearer tokens signed by the keys of the verifier
+func WithTokens(tokens *Verifier) Option {
+	return func(a *Authenticator) {
+		a.tokens = tokens
+	}
+}
+
+// New instantiates an authenticator, requests are rejected unless one of the options enables a kind of credentials
+func New(opts ...Option) *Authenticator {
+	a := &Authenticator{}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Authenticate returns the principal identified by the credentials of the request. Requests without credentials
+// result in ErrMissingCredentials and rejected credentials in an error wrapping ErrInvalidCredentials, any other
+// error means the credentials couldn't be checked.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		if a.keys == nil {
+			return nil, ErrInvalidCredentials
+		}
+		return a.keys.Authenticate(r.Context(), key)
+	}
+
+	authorization := r.Header.Get("Authorization")
+	if authorization == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	scheme, token := authorization, ""
+	if i := strings.IndexByte(authorization, ' '); i >= 0 {
+		scheme, token = authorization[:i], strings.TrimSpace(authorization[i+1:])
+	}
+	if !strings.EqualFold(scheme, "Bearer") || token == "" || a.tokens == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return a.tokens.Verify(token)
+}
@@ -0,0 +1,156 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/auth"
+	"github.com/pedrorochaorg/contactsApi/obj"
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+// StubAPIKeyRepo stores api keys by their hash
+type StubAPIKeyRepo struct {
+	keys map[string]obj.APIKey
+	err  error
+}
+
+func (s *StubAPIKeyRepo) Create(ctx context.Context, key *obj.APIKey, hash string) (*obj.APIKey, error) {
+	s.keys[hash] = *key
+	return key, nil
+}
+
+func (s *StubAPIKeyRepo) GetByHash(ctx context.Context, hash string) (*obj.APIKey, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	key, ok := s.keys[hash]
+	if !ok {
+		return nil, fmt.Errorf("api key not found: %w", repos.ErrNotFound)
+	}
+	return &key, nil
+}
+
+func (s *StubAPIKeyRepo) Revoke(ctx context.Context, id int) (bool, error) {
+	return false, fmt.Errorf("not implemented")
+}
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+
+	key, hash, err := auth.GenerateKey()
+	if err != nil {
+		t.Fatalf("error generating an api key: %s", err)
+	}
+
+	repo := &StubAPIKeyRepo{keys: map[string]obj.APIKey{
+		hash: {ID: 1, Name: "mobile", UserID: 3},
+	}}
+
+	keys, err := auth.ParseKeySet(keySetWithSecret(hmacSecret))
+	if err != nil {
+		t.Fatalf("error parsing the key set: %s", err)
+	}
+
+	authenticator := auth.New(
+		auth.WithAPIKeys(auth.NewKeyAuthenticator(repo)),
+		auth.WithTokens(auth.NewVerifier(keys)),
+	)
+
+	request := func(header, value string) *http.Request {
+		req, _ := http.NewRequest(http.MethodGet, "/users/", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		return req
+	}
+
+	t.Run("API keys are looked up by their hash", func(t *testing.T) {
+		assert.Equal(t, auth.HashKey(key), hash)
+
+		principal, err := authenticator.Authenticate(request(auth.APIKeyHeader, key))
+
+		assert.NoError(t, err)
+		assert.Equal(t, &auth.Principal{Subject: "key:mobile", UserID: 3}, principal)
+	})
+
+	t.Run("bearer tokens are verified", func(t *testing.T) {
+		token := sign(t, map[string]interface{}{"alg": "HS256"}, map[string]interface{}{"sub": "john",
+			"exp": time.Now().Add(time.Minute).Unix(), "roles": []string{"admin"}}, hmacSecret)
+
+		principal, err := authenticator.Authenticate(request("Authorization", "bearer "+token))
+
+		assert.NoError(t, err)
+		assert.Equal(t, &auth.Principal{Subject: "john", Admin: true}, principal)
+	})
+
+	t.Run("requests without credentials", func(t *testing.T) {
+		_, err := authenticator.Authenticate(request("", ""))
+
+		assert.True(t, errors.Is(err, auth.ErrMissingCredentials), "unexpected error %v", err)
+	})
+
+	t.Run("invalid credentials", func(t *testing.T) {
+		requests := []*http.Request{
+			request(auth.APIKeyHeader, "cak_unknown"),
+			request("Authorization", "Basic am9objpzZWNyZXQ="),
+			request("Authorization", "Bearer"),
+			request("Authorization", "Bearer not.a.token"),
+		}
+
+		for _, req := range requests {
+			_, err := authenticator.Authenticate(req)
+
+			assert.True(t, errors.Is(err, auth.ErrInvalidCredentials), "unexpected error %v", err)
+		}
+	})
+
+	t.Run("bearer tokens are rejected when no key set is configured", func(t *testing.T) {
+		token := sign(t, map[string]interface{}{"alg": "HS256"}, map[string]interface{}{"sub": "john",
+			"exp": time.Now().Add(time.Minute).Unix()}, hmacSecret)
+
+		_, err := auth.New().Authenticate(request("Authorization", "Bearer "+token))
+
+		assert.True(t, errors.Is(err, auth.ErrInvalidCredentials), "unexpected error %v", err)
+	})
+
+	t.Run("failures to read the keys aren't invalid credentials", func(t *testing.T) {
+		failing := auth.New(auth.WithAPIKeys(auth.NewKeyAuthenticator(&StubAPIKeyRepo{
+			err: fmt.Errorf("refused: %w", repos.ErrUnavailable),
+		})))
+
+		_, err := failing.Authenticate(request(auth.APIKeyHeader, key))
+
+		assert.True(t, errors.Is(err, repos.ErrUnavailable), "unexpected error %v", err)
+		assert.False(t, errors.Is(err, auth.ErrInvalidCredentials))
+	})
+}
+
+func TestFromContext(t *testing.T) {
+
+	t.Run("the principal is carried by the context", func(t *testing.T) {
+		principal := &auth.Principal{Subject: "john", UserID: 7}
+
+		found, ok := auth.FromContext(auth.NewContext(context.Background(), principal))
+
+		assert.True(t, ok)
+		assert.Equal(t, principal, found)
+	})
+
+	t.Run("contexts without a principal", func(t *testing.T) {
+		_, ok := auth.FromContext(context.Background())
+
+		assert.False(t, ok)
+	})
+}
+
+func keySetWithSecret(secret []byte) []byte {
+	return []byte(fmt.Sprintf(`{"keys": [{"kty": "oct", "k": %q}]}`, base64.RawURLEncoding.EncodeToString(secret)))
+}
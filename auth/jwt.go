@@ -0,0 +1,281 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// DefaultLeeway clock skew tolerated when checking the expiration and not before times of a token
+const DefaultLeeway = 30 * time.Second
+
+// Key key of a key set, a shared secret for HS256 tokens or an RSA public key for RS256 tokens
+type Key struct {
+	ID     string
+	secret []byte
+	public *rsa.PublicKey
+}
+
+// KeySet keys trusted to sign tokens
+type KeySet []Key
+
+// jwk JSON Web Key as defined by RFC 7517, only the members of oct and RSA keys are decoded
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// ParseKeySet decodes a JWK Set document holding oct and RSA keys, keys meant for encryption are skipped
+func ParseKeySet(data []byte) (KeySet, error) {
+	document := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	err := json.Unmarshal(data, &document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the key set: %s", err)
+	}
+
+	keys := KeySet{}
+	for i, key := range document.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		switch key.Kty {
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(key.K)
+			if err != nil || len(secret) == 0 {
+				return nil, fmt.Errorf("failed to parse key %d of the key set: invalid secret", i)
+			}
+			keys = append(keys, Key{ID: key.Kid, secret: secret})
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(key.N)
+			e, errE := base64.RawURLEncoding.DecodeString(key.E)
+			if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+				return nil, fmt.Errorf("failed to parse key %d of the key set: invalid modulus or exponent", i)
+			}
+			public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+			keys = append(keys, Key{ID: key.Kid, public: public})
+		default:
+			return nil, fmt.Errorf("failed to parse key %d of the key set: unsupported key type %q", i, key.Kty)
+		}
+	}
+
+	return keys, nil
+}
+
+// LoadKeySet reads a JWK Set document from a file
+func LoadKeySet(path string) (KeySet, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the key set: %s", err)
+	}
+	return ParseKeySet(data)
+}
+
+// Verifier verifies HS256 and RS256 signed JWTs against a key set
+type Verifier struct {
+	keys     KeySet
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+// VerifierOption configures the claims required by a Verifier
+type VerifierOption func(v *Verifier)
+
+// WithIssuer only accepts tokens whose iss claim is the issuer
+func WithIssuer(issuer string) VerifierOption {
+	return func(v *Verifier) {
+		v.issuer = issuer
+	}
+}
+
+// WithAudience only accepts tokens whose aud claim contains the audience
+func WithAudience(audience string) VerifierOption {
+	return func(v *Verifier) {
+		v.audience = audience
+	}
+}
+
+// WithLeeway sets the clock skew tolerated when checking the expiration and not before times
+func WithLeeway(leeway time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.leeway = leeway
+	}
+}
+
+// NewVerifier instantiates a verifier of the tokens signed by the keys of the key set
+func NewVerifier(keys KeySet, opts ...VerifierOption) *Verifier {
+	v := &Verifier{keys: keys, leeway: DefaultLeeway, now: time.Now}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// header JOSE header of a token
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// claims registered claims checked by the verifier and the private claims that describe the principal
+type claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+	UserID    int      `json:"user_id"`
+	Roles     []string `json:"roles"`
+}
+
+// audience aud claim, that can be either a single string or an array of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if json.Unmarshal(data, &single) == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var list []string
+	err := json.Unmarshal(data, &list)
+	if err != nil {
+		return fmt.Errorf("aud must be a string or an array of strings")
+	}
+	*a = list
+	return nil
+}
+
+// Verify checks the signature and claims of a token in the JWS compact serialization, returning the principal it
+// describes. Tokens must be signed with HS256 or RS256 by one of the keys, the kid header picks the key when present,
+// and must carry an expiration time. The user_id claim ties the principal to a user and the admin role in the roles
+// claim makes it an administrator.
+func (v *Verifier) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidCredentials)
+	}
+
+	h := header{}
+	err := decodeSegment(parts[0], &h)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed token header: %s", ErrInvalidCredentials, err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed token signature", ErrInvalidCredentials)
+	}
+
+	if !v.verifySignature(h, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, fmt.Errorf("%w: invalid token signature", ErrInvalidCredentials)
+	}
+
+	c := claims{}
+	err = decodeSegment(parts[1], &c)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed token claims: %s", ErrInvalidCredentials, err)
+	}
+
+	err = v.verifyClaims(c)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCredentials, err)
+	}
+
+	principal := &Principal{Subject: c.Subject, UserID: c.UserID}
+	for _, role := range c.Roles {
+		if role == AdminRole {
+			principal.Admin = true
+		}
+	}
+
+	return principal, nil
+}
+
+// verifySignature checks the signature against every key that can produce it, keys of the wrong type for the
+// algorithm are never used so that a public RSA key can't be used as a HMAC secret
+func (v *Verifier) verifySignature(h header, input, signature []byte) bool {
+	digest := sha256.Sum256(input)
+
+	for _, key := range v.keys {
+		if h.Kid != "" && key.ID != h.Kid {
+			continue
+		}
+
+		switch {
+		case h.Alg == "HS256" && key.secret != nil:
+			mac := hmac.New(sha256.New, key.secret)
+			mac.Write(input)
+			if hmac.Equal(mac.Sum(nil), signature) {
+				return true
+			}
+		case h.Alg == "RS256" && key.public != nil:
+			if rsa.VerifyPKCS1v15(key.public, crypto.SHA256, digest[:], signature) == nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// verifyClaims checks the time, issuer and audience claims
+func (v *Verifier) verifyClaims(c claims) error {
+	now := v.now()
+
+	if c.ExpiresAt == nil {
+		return fmt.Errorf("the token has no expiration time")
+	}
+	if now.Add(-v.leeway).After(unixTime(*c.ExpiresAt)) {
+		return fmt.Errorf("the token expired")
+	}
+	if c.NotBefore != nil && now.Add(v.leeway).Before(unixTime(*c.NotBefore)) {
+		return fmt.Errorf("the token isn't valid yet")
+	}
+
+	if v.issuer != "" && c.Issuer != v.issuer {
+		return fmt.Errorf("the token wasn't issued by %s", v.issuer)
+	}
+
+	if v.audience != "" {
+		for _, aud := range c.Audience {
+			if aud == v.audience {
+				return nil
+			}
+		}
+		return fmt.Errorf("the token isn't meant for %s", v.audience)
+	}
+
+	return nil
+}
+
+// decodeSegment decodes a base64url encoded JSON segment of a token
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// unixTime converts a NumericDate, the seconds since the epoch, to a time ignoring the fractional seconds
+func unixTime(seconds float64) time.Time {
+	return time.Unix(int64(seconds), 0)
+}
@@ -0,0 +1,193 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/auth"
+)
+
+var hmacSecret = []byte("a secret that is long enough for HS256")
+
+// sign builds a token in the JWS compact serialization, key is the HMAC secret of HS256 tokens or the RSA private key
+// of RS256 tokens
+func sign(t *testing.T, header, claims map[string]interface{}, key interface{}) string {
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("error encoding the token: %s", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	input := encode(header) + "." + encode(claims)
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(input))
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("error signing the token: %s", err)
+		}
+	}
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// keySet returns a JWK Set document with the HMAC secret and the public part of the RSA key
+func keySet(secret []byte, public *rsa.PublicKey) []byte {
+	return []byte(fmt.Sprintf(`{"keys": [
+		{"kty": "oct", "kid": "hmac", "k": %q},
+		{"kty": "RSA", "kid": "rsa", "use": "sig", "n": %q, "e": %q},
+		{"kty": "RSA", "kid": "encryption", "use": "enc", "n": "AQAB", "e": "AQAB"}
+	]}`,
+		base64.RawURLEncoding.EncodeToString(secret),
+		base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())))
+}
+
+func TestVerifier_Verify(t *testing.T) {
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating the rsa key: %s", err)
+	}
+
+	keys, err := auth.ParseKeySet(keySet(hmacSecret, &rsaKey.PublicKey))
+	if err != nil {
+		t.Fatalf("error parsing the key set: %s", err)
+	}
+	assert.Len(t, keys, 2, "keys meant for encryption should be skipped")
+
+	verifier := auth.NewVerifier(keys, auth.WithIssuer("https://issuer.example.com"), auth.WithAudience("contacts"))
+
+	exp := time.Now().Add(time.Hour).Unix()
+	validClaims := func() map[string]interface{} {
+		return map[string]interface{}{"sub": "john", "iss": "https://issuer.example.com", "aud": "contacts",
+			"exp": exp, "user_id": 7}
+	}
+
+	t.Run("HS256 tokens signed with the shared secret are accepted", func(t *testing.T) {
+		token := sign(t, map[string]interface{}{"alg": "HS256", "kid": "hmac"}, validClaims(), hmacSecret)
+
+		principal, err := verifier.Verify(token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &auth.Principal{Subject: "john", UserID: 7}, principal)
+	})
+
+	t.Run("RS256 tokens signed with the private key are accepted", func(t *testing.T) {
+		claims := validClaims()
+		claims["aud"] = []string{"other", "contacts"}
+		claims["roles"] = []string{"reader", "admin"}
+		token := sign(t, map[string]interface{}{"alg": "RS256"}, claims, rsaKey)
+
+		principal, err := verifier.Verify(token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &auth.Principal{Subject: "john", UserID: 7, Admin: true}, principal)
+	})
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating the rsa key: %s", err)
+	}
+
+	rejected := []struct {
+		name   string
+		header map[string]interface{}
+		claims func(claims map[string]interface{})
+		key    interface{}
+	}{
+		{"unknown secret", map[string]interface{}{"alg": "HS256"}, nil, []byte("another secret")},
+		{"unknown private key", map[string]interface{}{"alg": "RS256"}, nil, otherKey},
+		{"kid of another key", map[string]interface{}{"alg": "HS256", "kid": "rsa"}, nil, hmacSecret},
+		{"unsigned", map[string]interface{}{"alg": "none"}, nil, nil},
+		{"public key used as a HMAC secret", map[string]interface{}{"alg": "HS256"}, nil,
+			rsaKey.PublicKey.N.Bytes()},
+		{"expired", map[string]interface{}{"alg": "HS256"}, func(c map[string]interface{}) {
+			c["exp"] = time.Now().Add(-time.Hour).Unix()
+		}, hmacSecret},
+		{"without expiration", map[string]interface{}{"alg": "HS256"}, func(c map[string]interface{}) {
+			delete(c, "exp")
+		}, hmacSecret},
+		{"not valid yet", map[string]interface{}{"alg": "HS256"}, func(c map[string]interface{}) {
+			c["nbf"] = time.Now().Add(time.Hour).Unix()
+		}, hmacSecret},
+		{"another issuer", map[string]interface{}{"alg": "HS256"}, func(c map[string]interface{}) {
+			c["iss"] = "https://evil.example.com"
+		}, hmacSecret},
+		{"another audience", map[string]interface{}{"alg": "HS256"}, func(c map[string]interface{}) {
+			c["aud"] = []string{"billing"}
+		}, hmacSecret},
+	}
+
+	for _, c := range rejected {
+		t.Run(c.name+" tokens are rejected", func(t *testing.T) {
+			claims := validClaims()
+			if c.claims != nil {
+				c.claims(claims)
+			}
+
+			_, err := verifier.Verify(sign(t, c.header, claims, c.key))
+
+			assert.True(t, errors.Is(err, auth.ErrInvalidCredentials), "unexpected error %v", err)
+		})
+	}
+
+	t.Run("malformed tokens are rejected", func(t *testing.T) {
+		for _, token := range []string{"", "abc", "a.b", "a.b.c", "e30.e30.!!"} {
+			_, err := verifier.Verify(token)
+
+			assert.True(t, errors.Is(err, auth.ErrInvalidCredentials), "unexpected error %v for %q", err, token)
+		}
+	})
+
+	t.Run("expired tokens are accepted within the leeway", func(t *testing.T) {
+		claims := validClaims()
+		claims["exp"] = time.Now().Add(-10 * time.Second).Unix()
+
+		_, err := verifier.Verify(sign(t, map[string]interface{}{"alg": "HS256"}, claims, hmacSecret))
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestParseKeySet(t *testing.T) {
+
+	invalid := []struct {
+		name     string
+		document string
+	}{
+		{"not json", `keys`},
+		{"unsupported key type", `{"keys": [{"kty": "EC", "crv": "P-256"}]}`},
+		{"empty secret", `{"keys": [{"kty": "oct", "k": ""}]}`},
+		{"missing modulus", `{"keys": [{"kty": "RSA", "e": "AQAB"}]}`},
+	}
+
+	for _, c := range invalid {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := auth.ParseKeySet([]byte(c.document))
+
+			assert.Error(t, err, "should have returned an error")
+		})
+	}
+}
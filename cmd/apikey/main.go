@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	_ "github.com/lib/pq"
+
+	"github.com/pedrorochaorg/contactsApi/auth"
+	"github.com/pedrorochaorg/contactsApi/config"
+	"github.com/pedrorochaorg/contactsApi/db"
+	"github.com/pedrorochaorg/contactsApi/obj"
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+const usage = `usage: apikey [flags] <command>
+
+commands:
+  create NAME USER_ID   create a key that acts as the user
+  create-admin NAME     create a key that can act on every user
+  revoke ID             revoke a key
+
+The created key is printed once, only it's hash is stored.
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage+"\nflags:\n")
+		flag.PrintDefaults()
+	}
+
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:], os.Getenv)
+	if err != nil {
+		log.Fatalf("error loading the configuration: %s", err)
+	}
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	database := db.NewDatabaseConnection(cfg.Database.Options()...)
+	conn, err := sql.Open("postgres", database.ConnectionString())
+	if err != nil {
+		log.Fatalf("error starting database connection: %s", err)
+	}
+	defer conn.Close()
+
+	repository := repos.NewAPIKeyRepository(conn)
+	ctx := context.Background()
+
+	switch {
+	case flag.Arg(0) == "create" && flag.NArg() == 3:
+		userID, convErr := strconv.Atoi(flag.Arg(2))
+		if convErr != nil || userID < 1 {
+			log.Fatalf("invalid user id %q", flag.Arg(2))
+		}
+		err = create(ctx, &repository, &obj.APIKey{Name: flag.Arg(1), UserID: userID})
+	case flag.Arg(0) == "create-admin" && flag.NArg() == 2:
+		err = create(ctx, &repository, &obj.APIKey{Name: flag.Arg(1), Admin: true})
+	case flag.Arg(0) == "revoke" && flag.NArg() == 2:
+		id, convErr := strconv.Atoi(flag.Arg(1))
+		if convErr != nil {
+			log.Fatalf("invalid key id %q", flag.Arg(1))
+		}
+		_, err = repository.Revoke(ctx, id)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("apikey %s failed: %s", flag.Arg(0), err)
+	}
+}
+
+func create(ctx context.Context, repository repos.APIKeyRepo, key *obj.APIKey) error {
+	secret, hash, err := auth.GenerateKey()
+	if err != nil {
+		return err
+	}
+
+	key, err = repository.Create(ctx, key, hash)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("id:  %d\nkey: %s\n", key.ID, secret)
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	_ "github.com/lib/pq"
+
+	"github.com/pedrorochaorg/contactsApi/config"
+	"github.com/pedrorochaorg/contactsApi/db"
+)
+
+const usage = `usage: migrate [flags] <command>
+
+commands:
+  up         apply every pending migration
+  down       revert the last applied migration
+  status     list the migrations and whether they were applied
+  goto N     apply or revert migrations until the schema is at version N
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage+"\nflags:\n")
+		flag.PrintDefaults()
+	}
+
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:], os.Getenv)
+	if err != nil {
+		log.Fatalf("error loading the configuration: %s", err)
+	}
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	database := db.NewDatabaseConnection(cfg.Database.Options()...)
+	conn, err := sql.Open("postgres", database.ConnectionString())
+	if err != nil {
+		log.Fatalf("error starting database connection: %s", err)
+	}
+	defer conn.Close()
+
+	migrator := db.NewMigrator(conn, db.Migrations)
+	ctx := context.Background()
+
+	switch flag.Arg(0) {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "goto":
+		if flag.NArg() != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+
+		version, convErr := strconv.Atoi(flag.Arg(1))
+		if convErr != nil {
+			log.Fatalf("invalid version %q", flag.Arg(1))
+		}
+		err = migrator.Goto(ctx, version)
+	case "status":
+		err = printStatus(ctx, migrator)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("migrate %s failed: %s", flag.Arg(0), err)
+	}
+}
+
+func printStatus(ctx context.Context, migrator *db.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		applied := "pending"
+		if status.Applied {
+			applied = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%4d  %-40s %s\n", status.Version, status.Name, applied)
+	}
+
+	return nil
+}
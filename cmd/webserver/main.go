@@ -1,37 +1,141 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"flag"
+	"fmt"
 	"log"
-	"net/http"
+	"os"
+	"time"
 
 	_ "github.com/lib/pq"
 
 	"github.com/pedrorochaorg/contactsApi/api"
+	"github.com/pedrorochaorg/contactsApi/auth"
+	"github.com/pedrorochaorg/contactsApi/config"
 	"github.com/pedrorochaorg/contactsApi/db"
+	"github.com/pedrorochaorg/contactsApi/logging"
+	"github.com/pedrorochaorg/contactsApi/obj"
+	"github.com/pedrorochaorg/contactsApi/repos"
+	"github.com/pedrorochaorg/contactsApi/server"
+	"github.com/pedrorochaorg/contactsApi/tracing"
 )
 
 func main() {
-	database := db.NewDatabaseConnection(
-		db.WithUsername("contacts"),
-		db.WithSslMode("disable"),
-		db.WithDatabase("contacts"),
-		db.WithHost("localhost"),
-		db.WithPort("5435"),
-		db.WithPassword("TwE5]>*Gm^sk_eq)"),
-	)
-	db, err := sql.Open("postgres", database.ConnectionString())
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:], os.Getenv)
 	if err != nil {
-		log.Fatalf("error starting database connection: %s", err)
+		log.Fatalf("error loading the configuration: %s", err)
+	}
+
+	// The level was already validated with the rest of the configuration
+	level, _ := logging.ParseLevel(cfg.Log.Level)
+	logger := logging.New(os.Stderr, logging.WithLevel(level))
+
+	opts := []api.Option{api.WithLogger(logger)}
+	if cfg.Auth.JWTKeys != "" {
+		keys, err := auth.LoadKeySet(cfg.Auth.JWTKeys)
+		if err != nil {
+			log.Fatalf("error loading the jwt keys: %s", err)
+		}
+		opts = append(opts, api.WithTokenVerifier(auth.NewVerifier(keys, auth.WithIssuer(cfg.Auth.JWTIssuer),
+			auth.WithAudience(cfg.Auth.JWTAudience))))
 	}
 
-	defer db.Close()
+	serverOpts := []server.Option{
+		server.WithReadTimeout(time.Duration(cfg.Server.ReadTimeout)),
+		server.WithReadHeaderTimeout(time.Duration(cfg.Server.ReadHeaderTimeout)),
+		server.WithWriteTimeout(time.Duration(cfg.Server.WriteTimeout)),
+		server.WithIdleTimeout(time.Duration(cfg.Server.IdleTimeout)),
+		server.WithShutdownTimeout(time.Duration(cfg.Server.ShutdownTimeout)),
+	}
 
-	server := api.NewAPI(db)
+	var conn *sql.DB
+	if cfg.Storage == config.StorageMemory {
+		store, key, err := memoryStore()
+		if err != nil {
+			log.Fatalf("error creating the admin api key: %s", err)
+		}
+		log.Printf("Keeping the data in memory, it's lost when the webserver stops. The admin api key to call the " +
+			"API is printed to stdout")
+		// The key is kept out of the logs, that are usually collected and stored
+		fmt.Printf("admin key: %s\n", key)
+		opts = append(opts, api.WithRepositories(store.Users(), store.Contacts(), store.APIKeys()))
+	} else {
+		conn = openDatabase(cfg.Database)
+		// The database is closed by the server once the in-flight requests finished
+		serverOpts = append(serverOpts, server.WithCloser(conn))
+	}
 
-	log.Println("Starting the webserver in port 3000")
-	if err := http.ListenAndServe(":3000", server); err != nil {
+	exporter, err := traceExporter(cfg.Tracing)
+	if err != nil {
+		log.Fatalf("error starting the trace exporter: %s", err)
+	}
+	if exporter != nil {
+		// Closing the tracer exports the spans of the last requests
+		tracer := tracing.NewTracer(exporter)
+		opts = append(opts, api.WithTracer(tracer))
+		serverOpts = append(serverOpts, server.WithCloser(tracer))
+	}
+
+	webserver := server.New(cfg.Server.Addr, api.NewAPI(conn, opts...), serverOpts...)
+
+	log.Printf("Starting the webserver on %s", cfg.Server.Addr)
+	if err := webserver.Run(context.Background()); err != nil {
 		log.Fatalf("Error while starting the web server: %s", err)
 	}
 
-}
\ No newline at end of file
+}
+
+// openDatabase opens the connection to the configured database, warning when it's schema is outdated
+func openDatabase(cfg config.Database) *sql.DB {
+	database := db.NewDatabaseConnection(cfg.Options()...)
+	conn, err := sql.Open("postgres", database.ConnectionString())
+	if err != nil {
+		log.Fatalf("error starting database connection: %s", err)
+	}
+
+	// The schema is managed by the migrate command, the server only warns when it's running against an outdated one
+	version, err := db.NewMigrator(conn, db.Migrations).Version(context.Background())
+	if err != nil {
+		log.Printf("Unable to verify the schema version: %s", err)
+	} else if version != db.LatestVersion() {
+		log.Printf("Schema is at version %d but version %d is expected, run 'migrate up'", version,
+			db.LatestVersion())
+	}
+
+	return conn
+}
+
+// memoryStore returns an empty in-memory store with an admin api key, as there's no database to create one with the
+// apikey command
+func memoryStore() (*repos.MemoryStore, string, error) {
+	store := repos.NewMemoryStore()
+
+	key, hash, err := auth.GenerateKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	_, err = store.APIKeys().Create(context.Background(), &obj.APIKey{Name: "admin", Admin: true}, hash)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return store, key, nil
+}
+
+// traceExporter returns the exporter of the traces selected by the configuration, it's nil when the requests
+// shouldn't be traced
+func traceExporter(cfg config.Tracing) (tracing.Exporter, error) {
+	switch cfg.Exporter {
+	case config.ExporterStdout:
+		return tracing.NewWriterExporter(os.Stdout), nil
+	case config.ExporterFile:
+		return tracing.NewFileExporter(cfg.File)
+	case config.ExporterOTLP:
+		return tracing.NewOTLPExporter(cfg.OTLPEndpoint), nil
+	default:
+		return nil, nil
+	}
+}
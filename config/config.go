@@ -0,0 +1,441 @@
+// Package config builds the settings of the contactsApi commands. Every setting has a default value that can be
+// overridden, from the lowest to the highest precedence, by:
+//
+//  1. a YAML or JSON file, given with the -config flag or the CONTACTS_CONFIG environment variable;
+//  2. a CONTACTS_* environment variable, or a CONTACTS_*_FILE variable holding the path of a file with the value,
+//     which is meant for secrets mounted as files;
+//  3. a command line flag.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/pedrorochaorg/contactsApi/db"
+	"github.com/pedrorochaorg/contactsApi/logging"
+)
+
+// EnvPrefix prefix of every environment variable read by the config package
+const EnvPrefix = "CONTACTS_"
+
+// Config settings of the contactsApi commands
+type Config struct {
+	Server   Server   `json:"server" yaml:"server"`
+	Storage  string   `json:"storage" yaml:"storage"`
+	Database Database `json:"database" yaml:"database"`
+	Auth     Auth     `json:"auth" yaml:"auth"`
+	Log      Log      `json:"log" yaml:"log"`
+	Tracing  Tracing  `json:"tracing" yaml:"tracing"`
+}
+
+// Storages the webserver keeps the data in, the data kept in memory is lost when it stops
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
+// Server settings of the http server
+type Server struct {
+	Addr              string   `json:"addr" yaml:"addr"`
+	ReadTimeout       Duration `json:"read_timeout" yaml:"read_timeout"`
+	ReadHeaderTimeout Duration `json:"read_header_timeout" yaml:"read_header_timeout"`
+	WriteTimeout      Duration `json:"write_timeout" yaml:"write_timeout"`
+	IdleTimeout       Duration `json:"idle_timeout" yaml:"idle_timeout"`
+	ShutdownTimeout   Duration `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+}
+
+// Database settings used to connect to the postgres database
+type Database struct {
+	Host     string `json:"host" yaml:"host"`
+	Port     string `json:"port" yaml:"port"`
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+	Name     string `json:"name" yaml:"name"`
+	SSLMode  string `json:"sslmode" yaml:"sslmode"`
+}
+
+// Auth settings of the authentication of API clients, API keys are always accepted while bearer tokens are only
+// accepted when a key set is configured
+type Auth struct {
+	JWTKeys     string `json:"jwt_keys" yaml:"jwt_keys"`
+	JWTIssuer   string `json:"jwt_issuer" yaml:"jwt_issuer"`
+	JWTAudience string `json:"jwt_audience" yaml:"jwt_audience"`
+}
+
+// Log settings of the logs written by the commands
+type Log struct {
+	Level string `json:"level" yaml:"level"`
+}
+
+// Tracing settings of the export of the traces of the requests, they're only recorded when an exporter is set
+type Tracing struct {
+	Exporter     string `json:"exporter" yaml:"exporter"`
+	File         string `json:"file" yaml:"file"`
+	OTLPEndpoint string `json:"otlp_endpoint" yaml:"otlp_endpoint"`
+}
+
+// Exporters of the traces
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterOTLP   = "otlp"
+)
+
+// Options returns the functional options that connect to the configured database
+func (d Database) Options() []db.DatabaseOpts {
+	return []db.DatabaseOpts{
+		db.WithHost(d.Host),
+		db.WithPort(d.Port),
+		db.WithUsername(d.Username),
+		db.WithPassword(d.Password),
+		db.WithDatabase(d.Name),
+		db.WithSslMode(d.SSLMode),
+	}
+}
+
+// Default returns the configuration used when no setting is overridden
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Addr:              ":3000",
+			ReadTimeout:       Duration(15 * time.Second),
+			ReadHeaderTimeout: Duration(5 * time.Second),
+			WriteTimeout:      Duration(30 * time.Second),
+			IdleTimeout:       Duration(60 * time.Second),
+			ShutdownTimeout:   Duration(30 * time.Second),
+		},
+		Storage: StoragePostgres,
+		Database: Database{
+			Host:     "localhost",
+			Port:     "5432",
+			Username: "contacts",
+			Name:     "contacts",
+			SSLMode:  "disable",
+		},
+		Log: Log{
+			Level: "info",
+		},
+		Tracing: Tracing{
+			Exporter: ExporterNone,
+		},
+	}
+}
+
+// setting binds a configuration value to the flag and the environment variable that override it
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	value flag.Value
+}
+
+// settings returns every setting that can be overridden by flags and environment variables
+func (c *Config) settings() []setting {
+	return []setting{
+		{"addr", "ADDR", "address the http server listens on", (*stringValue)(&c.Server.Addr)},
+		{"read-timeout", "READ_TIMEOUT", "maximum duration for reading an entire request",
+			&c.Server.ReadTimeout},
+		{"read-header-timeout", "READ_HEADER_TIMEOUT", "maximum duration for reading the request headers",
+			&c.Server.ReadHeaderTimeout},
+		{"write-timeout", "WRITE_TIMEOUT", "maximum duration for writing the response", &c.Server.WriteTimeout},
+		{"idle-timeout", "IDLE_TIMEOUT", "maximum duration a keep-alive connection waits for the next request",
+			&c.Server.IdleTimeout},
+		{"shutdown-timeout", "SHUTDOWN_TIMEOUT", "how long in-flight requests are given to finish on shutdown",
+			&c.Server.ShutdownTimeout},
+		{"storage", "STORAGE", "where the data is kept: postgres or memory", (*stringValue)(&c.Storage)},
+		{"db-host", "DB_HOST", "database host", (*stringValue)(&c.Database.Host)},
+		{"db-port", "DB_PORT", "database port", (*stringValue)(&c.Database.Port)},
+		{"db-user", "DB_USER", "database username", (*stringValue)(&c.Database.Username)},
+		{"db-password", "DB_PASSWORD", "database password", (*stringValue)(&c.Database.Password)},
+		{"db-name", "DB_NAME", "database name", (*stringValue)(&c.Database.Name)},
+		{"db-sslmode", "DB_SSLMODE", "database ssl mode", (*stringValue)(&c.Database.SSLMode)},
+		{"jwt-keys", "JWT_KEYS", "path of a JWK Set file with the keys that sign bearer tokens",
+			(*stringValue)(&c.Auth.JWTKeys)},
+		{"jwt-issuer", "JWT_ISSUER", "issuer required in the iss claim of bearer tokens",
+			(*stringValue)(&c.Auth.JWTIssuer)},
+		{"jwt-audience", "JWT_AUDIENCE", "audience required in the aud claim of bearer tokens",
+			(*stringValue)(&c.Auth.JWTAudience)},
+		{"log-level", "LOG_LEVEL", "least severe level of the logged entries: debug, info, warn or error",
+			(*stringValue)(&c.Log.Level)},
+		{"trace-exporter", "TRACE_EXPORTER", "where the traces are exported: none, stdout, file or otlp",
+			(*stringValue)(&c.Tracing.Exporter)},
+		{"trace-file", "TRACE_FILE", "path of the file the traces are appended to by the file exporter",
+			(*stringValue)(&c.Tracing.File)},
+		{"otlp-endpoint", "OTLP_ENDPOINT", "OTLP/HTTP traces endpoint of the collector, like " +
+			"http://localhost:4318/v1/traces", (*stringValue)(&c.Tracing.OTLPEndpoint)},
+	}
+}
+
+// Load registers the configuration flags in the flag set, parses the arguments and returns the resulting
+// configuration once it's validated. Arguments that aren't flags remain available through the flag set Args method.
+func Load(fs *flag.FlagSet, args []string, getenv func(string) string) (*Config, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	configFile := fs.String("config", "", "path of a YAML or JSON configuration file (env "+EnvPrefix+"CONFIG)")
+
+	flags := map[string]setting{}
+	for _, s := range settings {
+		flags[s.flag] = s
+		fs.String(s.flag, s.value.String(), fmt.Sprintf("%s (env %s%s)", s.usage, EnvPrefix, s.env))
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	path := *configFile
+	if path == "" {
+		path, err = lookupEnv(getenv, "CONFIG")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if path != "" {
+		err = readFile(path, cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		value, err := lookupEnv(getenv, s.env)
+		if err != nil {
+			return nil, err
+		}
+		if value == "" {
+			continue
+		}
+		err = s.value.Set(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s%s: %s", EnvPrefix, s.env, err)
+		}
+	}
+
+	// Only the flags present in the arguments override the previous sources
+	fs.Visit(func(f *flag.Flag) {
+		if s, ok := flags[f.Name]; ok && err == nil {
+			err = s.value.Set(f.Value.String())
+			if err != nil {
+				err = fmt.Errorf("invalid value for -%s: %s", f.Name, err)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// lookupEnv returns the value of the CONTACTS_ prefixed environment variable, or the contents of the file named by
+// the same variable suffixed with _FILE. Setting both variables is an error.
+func lookupEnv(getenv func(string) string, name string) (string, error) {
+	value := getenv(EnvPrefix + name)
+	file := getenv(EnvPrefix + name + "_FILE")
+
+	if file == "" {
+		return value, nil
+	}
+
+	if value != "" {
+		return "", fmt.Errorf("%s%s and %s%s_FILE can't be both set", EnvPrefix, name, EnvPrefix, name)
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s%s_FILE: %s", EnvPrefix, name, err)
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// readFile decodes a configuration file over the values already present in cfg, the format is picked from the file
+// extension
+func readFile(path string, cfg *Config) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read the configuration file: %s", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = decodeJSON(data, cfg)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, cfg)
+	default:
+		return fmt.Errorf("unsupported configuration file format %q, use .yaml, .yml or .json", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse the configuration file %s: %s", path, err)
+	}
+
+	return nil
+}
+
+// decodeJSON decodes a json document rejecting the keys that don't match a setting like yaml.UnmarshalStrict does,
+// so that a misspelled key isn't silently ignored
+func decodeJSON(data []byte, cfg *Config) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(cfg)
+	if err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("unexpected data after the configuration")
+	}
+	return nil
+}
+
+// Validate checks every setting reporting all the invalid ones at once
+func (c *Config) Validate() error {
+	problems := []string{}
+
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		problems = append(problems, fmt.Sprintf("server.addr %q is not a valid address", c.Server.Addr))
+	}
+
+	timeouts := []struct {
+		name  string
+		value Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value < 0 {
+			problems = append(problems, fmt.Sprintf("%s %s can't be negative", timeout.name, time.Duration(timeout.value)))
+		}
+	}
+
+	if c.Storage != StoragePostgres && c.Storage != StorageMemory {
+		problems = append(problems, fmt.Sprintf("storage %q must be one of postgres or memory", c.Storage))
+	}
+
+	// The data kept in memory doesn't need a database, it's settings are ignored
+	if c.Storage != StorageMemory {
+		if c.Database.Host == "" {
+			problems = append(problems, "database.host is required")
+		}
+		if port, err := strconv.Atoi(c.Database.Port); err != nil || port < 1 || port > 65535 {
+			problems = append(problems, fmt.Sprintf("database.port %q must be a number between 1 and 65535",
+				c.Database.Port))
+		}
+		if c.Database.Username == "" {
+			problems = append(problems, "database.username is required")
+		}
+		if c.Database.Name == "" {
+			problems = append(problems, "database.name is required")
+		}
+
+		switch c.Database.SSLMode {
+		case "disable", "require", "verify-ca", "verify-full":
+		default:
+			problems = append(problems, fmt.Sprintf("database.sslmode %q must be one of disable, require, verify-ca "+
+				"or verify-full", c.Database.SSLMode))
+		}
+	}
+
+	if c.Auth.JWTKeys == "" && (c.Auth.JWTIssuer != "" || c.Auth.JWTAudience != "") {
+		problems = append(problems, "auth.jwt_issuer and auth.jwt_audience require auth.jwt_keys")
+	}
+
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		problems = append(problems, fmt.Sprintf("log.level %q must be one of debug, info, warn or error",
+			c.Log.Level))
+	}
+
+	switch c.Tracing.Exporter {
+	case ExporterNone, ExporterStdout:
+	case ExporterFile:
+		if c.Tracing.File == "" {
+			problems = append(problems, "tracing.file is required by the file exporter")
+		}
+	case ExporterOTLP:
+		if c.Tracing.OTLPEndpoint == "" {
+			problems = append(problems, "tracing.otlp_endpoint is required by the otlp exporter")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("tracing.exporter %q must be one of none, stdout, file or otlp",
+			c.Tracing.Exporter))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// stringValue flag.Value that stores the value in a string of the configuration
+type stringValue string
+
+func (s *stringValue) String() string {
+	return string(*s)
+}
+
+func (s *stringValue) Set(value string) error {
+	*s = stringValue(value)
+	return nil
+}
+
+// Duration time.Duration that is written as a string like "30s" in flags, environment variables and files
+type Duration time.Duration
+
+func (d *Duration) String() string {
+	return time.Duration(*d).String()
+}
+
+func (d *Duration) Set(value string) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// UnmarshalJSON decodes a duration string like "30s"
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\": %s", err)
+	}
+	return d.Set(value)
+}
+
+// UnmarshalYAML decodes a duration string like "30s"
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	err := unmarshal(&value)
+	if err != nil {
+		return err
+	}
+	return d.Set(value)
+}
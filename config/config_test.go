@@ -0,0 +1,231 @@
+package config_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/config"
+	"github.com/pedrorochaorg/contactsApi/db"
+)
+
+// env returns a getenv func that reads from a map instead of the process environment
+func env(values map[string]string) func(string) string {
+	return func(name string) string {
+		return values[name]
+	}
+}
+
+func writeFile(t *testing.T, dir, name, contents string) string {
+	path := filepath.Join(dir, name)
+	err := ioutil.WriteFile(path, []byte(contents), 0600)
+	if err != nil {
+		t.Fatalf("error writing %s: %s", path, err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatalf("error creating a temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	yamlFile := writeFile(t, dir, "config.yaml", "server:\n  addr: \":4000\"\ndatabase:\n  host: yaml-host\n"+
+		"  port: \"5435\"\n  name: yaml-db\n")
+
+	t.Run("defaults are used when nothing is overridden", func(t *testing.T) {
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), nil, env(nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, config.Default(), cfg)
+	})
+
+	t.Run("the file overrides the defaults", func(t *testing.T) {
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config", yamlFile},
+			env(nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, ":4000", cfg.Server.Addr)
+		assert.Equal(t, "yaml-host", cfg.Database.Host)
+		assert.Equal(t, "5435", cfg.Database.Port)
+		assert.Equal(t, "contacts", cfg.Database.Username, "values missing from the file should keep the default")
+	})
+
+	t.Run("the environment overrides the file and flags override the environment", func(t *testing.T) {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+
+		cfg, err := config.Load(fs, []string{"-db-host", "flag-host", "up"}, env(map[string]string{
+			"CONTACTS_CONFIG":  yamlFile,
+			"CONTACTS_DB_HOST": "env-host",
+			"CONTACTS_DB_NAME": "env-db",
+		}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "flag-host", cfg.Database.Host)
+		assert.Equal(t, "env-db", cfg.Database.Name)
+		assert.Equal(t, ":4000", cfg.Server.Addr)
+		assert.Equal(t, []string{"up"}, fs.Args(), "arguments that aren't flags should remain available")
+	})
+
+	t.Run("secrets can be read from the file named by the _FILE variable", func(t *testing.T) {
+		secret := writeFile(t, dir, "password", "s3cr3t\n")
+
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), nil, env(map[string]string{
+			"CONTACTS_DB_PASSWORD_FILE": secret,
+		}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "s3cr3t", cfg.Database.Password)
+	})
+
+	t.Run("a variable and it's _FILE variant can't be both set", func(t *testing.T) {
+		_, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), nil, env(map[string]string{
+			"CONTACTS_DB_PASSWORD":      "s3cr3t",
+			"CONTACTS_DB_PASSWORD_FILE": "/run/secrets/password",
+		}))
+
+		assert.Error(t, err, "should have returned an error")
+		assert.Contains(t, err.Error(), "can't be both set", "Error message doesn't match")
+	})
+
+	t.Run("json files are supported", func(t *testing.T) {
+		jsonFile := writeFile(t, dir, "config.json", `{"database": {"username": "json-user"}}`)
+
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config", jsonFile},
+			env(nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "json-user", cfg.Database.Username)
+	})
+
+	t.Run("timeouts are read as durations from every source", func(t *testing.T) {
+		timeoutsFile := writeFile(t, dir, "timeouts.yaml", "server:\n  idle_timeout: 2m\n  write_timeout: 10s\n")
+
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError),
+			[]string{"-config", timeoutsFile, "-shutdown-timeout", "5s"}, env(map[string]string{
+				"CONTACTS_WRITE_TIMEOUT": "20s",
+			}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, config.Duration(2*time.Minute), cfg.Server.IdleTimeout)
+		assert.Equal(t, config.Duration(20*time.Second), cfg.Server.WriteTimeout)
+		assert.Equal(t, config.Duration(5*time.Second), cfg.Server.ShutdownTimeout)
+		assert.Equal(t, config.Duration(5*time.Second), cfg.Server.ReadHeaderTimeout,
+			"values that aren't overridden should keep the default")
+	})
+
+	t.Run("invalid durations are rejected", func(t *testing.T) {
+		_, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), nil, env(map[string]string{
+			"CONTACTS_IDLE_TIMEOUT": "forever",
+		}))
+
+		assert.Error(t, err, "should have returned an error")
+		assert.Contains(t, err.Error(), "CONTACTS_IDLE_TIMEOUT", "Error message doesn't match")
+	})
+
+	t.Run("unknown keys in a yaml file are rejected", func(t *testing.T) {
+		badFile := writeFile(t, dir, "bad.yaml", "database:\n  hots: localhost\n")
+
+		_, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config", badFile},
+			env(nil))
+
+		assert.Error(t, err, "should have returned an error")
+		assert.Contains(t, err.Error(), "hots", "Error message doesn't match")
+	})
+
+	t.Run("unknown keys in a json file are rejected", func(t *testing.T) {
+		badFile := writeFile(t, dir, "bad.json", `{"database": {"hots": "localhost"}}`)
+
+		_, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config", badFile},
+			env(nil))
+
+		assert.Error(t, err, "should have returned an error")
+		assert.Contains(t, err.Error(), "hots", "Error message doesn't match")
+	})
+
+	t.Run("bearer token settings are read from the auth section", func(t *testing.T) {
+		authFile := writeFile(t, dir, "auth.yaml", "auth:\n  jwt_keys: /etc/contacts/jwks.json\n  jwt_issuer: issuer\n")
+
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError),
+			[]string{"-config", authFile, "-jwt-audience", "contacts"}, env(nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, config.Auth{JWTKeys: "/etc/contacts/jwks.json", JWTIssuer: "issuer", JWTAudience: "contacts"},
+			cfg.Auth)
+	})
+
+	t.Run("token claims can't be required without a key set", func(t *testing.T) {
+		_, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-jwt-issuer", "issuer"},
+			env(nil))
+
+		assert.Error(t, err, "should have returned an error")
+		assert.Contains(t, err.Error(), "auth.jwt_keys", "Error message doesn't match")
+	})
+
+	t.Run("the exporters require their destination", func(t *testing.T) {
+		_, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-trace-exporter", "otlp"},
+			env(nil))
+
+		assert.Error(t, err, "should have returned an error")
+		assert.Contains(t, err.Error(), "tracing.otlp_endpoint", "Error message doesn't match")
+
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-trace-exporter", "file"},
+			env(map[string]string{"CONTACTS_TRACE_FILE": "/var/log/contacts/traces.json"}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, config.Tracing{Exporter: config.ExporterFile, File: "/var/log/contacts/traces.json"},
+			cfg.Tracing)
+	})
+
+	t.Run("the data can be kept in memory", func(t *testing.T) {
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"--storage=memory"},
+			env(nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, config.StorageMemory, cfg.Storage)
+	})
+
+	t.Run("the database settings aren't checked when the data is kept in memory", func(t *testing.T) {
+		cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError),
+			[]string{"--storage=memory", "-db-host", "", "-db-port", "none", "-db-sslmode", "maybe"}, env(nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, config.StorageMemory, cfg.Storage)
+	})
+
+	t.Run("every invalid setting is reported", func(t *testing.T) {
+		_, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError),
+			[]string{"-addr", "3000", "-db-port", "none", "-db-host", "", "-db-sslmode", "maybe", "-log-level", "loud",
+				"-storage", "disk"},
+			env(nil))
+
+		assert.Error(t, err, "should have returned an error")
+		assert.Contains(t, err.Error(), "server.addr", "Error message doesn't match")
+		assert.Contains(t, err.Error(), "database.port", "Error message doesn't match")
+		assert.Contains(t, err.Error(), "database.host", "Error message doesn't match")
+		assert.Contains(t, err.Error(), "database.sslmode", "Error message doesn't match")
+		assert.Contains(t, err.Error(), "log.level", "Error message doesn't match")
+		assert.Contains(t, err.Error(), "storage", "Error message doesn't match")
+	})
+}
+
+func TestDatabase_Options(t *testing.T) {
+
+	t.Run("the options build the connection string of the configured database", func(t *testing.T) {
+		cfg := config.Database{Host: "localhost", Port: "5435", Username: "contacts", Password: "secret",
+			Name: "contacts", SSLMode: "disable"}
+
+		database := db.NewDatabaseConnection(cfg.Options()...)
+
+		assert.Equal(t, "host=localhost port=5435 user=contacts password=secret dbname=contacts sslmode=disable",
+			database.ConnectionString())
+	})
+}
@@ -0,0 +1,243 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// migrationsLockKey identifies the advisory lock held while migrations run, replicas starting at the same time wait
+// for each other instead of applying the same migration twice.
+const migrationsLockKey = 736583410
+
+// undefinedTable error code returned by postgres when a query references a table that doesn't exist
+const undefinedTable = "42P01"
+
+// MigrationStatus describes whether a migration has been applied to the database
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies and reverts migrations keeping track of the applied versions in the schema_migrations table
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator instantiates a migrator for a set of migrations ordered by version
+func NewMigrator(db *sql.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// Version returns the version the database schema is at, a database that was never migrated is at version 0
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	var version int
+	err := m.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == undefinedTable {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to obtain the schema version: %s", err)
+	}
+
+	return version, nil
+}
+
+// Status returns every known migration and whether it was already applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied := map[int]time.Time{}
+
+	rows, err := m.db.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if pqErr, ok := err.(*pq.Error); !ok || pqErr.Code != undefinedTable {
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch the applied migrations: %s", err)
+		}
+
+		defer rows.Close()
+		for rows.Next() {
+			var version int
+			var appliedAt time.Time
+			err = rows.Scan(&version, &appliedAt)
+			if err != nil {
+				return nil, fmt.Errorf("failed to map row to migration: %s", err)
+			}
+			applied[version] = appliedAt
+		}
+	}
+
+	statuses := make([]MigrationStatus, len(m.migrations))
+	for i, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses[i] = MigrationStatus{Migration: migration, Applied: ok, AppliedAt: appliedAt}
+	}
+
+	return statuses, nil
+}
+
+// Up applies every pending migration
+func (m *Migrator) Up(ctx context.Context) error {
+	if len(m.migrations) == 0 {
+		return nil
+	}
+	return m.Goto(ctx, m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down reverts the last applied migration
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := m.lockedVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		if current == 0 {
+			return nil
+		}
+
+		previous := 0
+		for _, migration := range m.migrations {
+			if migration.Version < current {
+				previous = migration.Version
+			}
+		}
+
+		return m.migrate(ctx, conn, current, previous)
+	})
+}
+
+// Goto applies or reverts migrations until the schema is at the target version, version 0 reverts every migration
+func (m *Migrator) Goto(ctx context.Context, target int) error {
+	known := target == 0
+	for _, migration := range m.migrations {
+		if migration.Version == target {
+			known = true
+		}
+	}
+	if !known {
+		return fmt.Errorf("unknown migration version %d", target)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := m.lockedVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		return m.migrate(ctx, conn, current, target)
+	})
+}
+
+// withLock runs fn holding the migrations advisory lock. Advisory locks belong to a session, so every statement
+// issued while the lock is held must use the connection handed to fn.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to obtain a database connection: %s", err)
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationsLockKey)
+	if err != nil {
+		return fmt.Errorf("failed to acquire the migrations lock: %s", err)
+	}
+	defer func() {
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationsLockKey)
+		if err != nil {
+			log.Printf("failed to release the migrations lock: %s", err)
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations(
+		version integer NOT NULL,
+		name varchar(255) NOT NULL,
+		applied_at timestamp NOT NULL DEFAULT NOW(),
+		CONSTRAINT pk_schema_migrations_version PRIMARY KEY (version)
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create the schema_migrations table: %s", err)
+	}
+
+	return fn(conn)
+}
+
+// lockedVersion returns the version of the schema using the connection that holds the migrations lock
+func (m *Migrator) lockedVersion(ctx context.Context, conn *sql.Conn) (int, error) {
+	var version int
+	err := conn.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to obtain the schema version: %s", err)
+	}
+	return version, nil
+}
+
+// migrate applies the migrations between the current and target versions, or reverts them in reverse order when the
+// target is lower than the current version. Each migration runs in it's own transaction and the process stops at the
+// first one that fails, leaving the schema at the last version that succeeded.
+func (m *Migrator) migrate(ctx context.Context, conn *sql.Conn, current, target int) error {
+	if target >= current {
+		for _, migration := range m.migrations {
+			if migration.Version <= current || migration.Version > target {
+				continue
+			}
+
+			err := m.run(ctx, conn, migration, migration.Up,
+				"INSERT INTO schema_migrations(version, name) VALUES($1, $2)", migration.Version, migration.Name)
+			if err != nil {
+				return err
+			}
+			log.Printf("Applied migration %d (%s)", migration.Version, migration.Name)
+		}
+		return nil
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if migration.Version > current || migration.Version <= target {
+			continue
+		}
+
+		err := m.run(ctx, conn, migration, migration.Down, "DELETE FROM schema_migrations WHERE version = $1",
+			migration.Version)
+		if err != nil {
+			return err
+		}
+		log.Printf("Reverted migration %d (%s)", migration.Version, migration.Name)
+	}
+	return nil
+}
+
+// run executes the statements of a migration and the statement that records it in a single transaction
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, migration Migration, statements []string,
+	record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start the transaction of migration %d: %s", migration.Version, err)
+	}
+
+	for _, stmt := range statements {
+		_, err = tx.ExecContext(ctx, stmt)
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to execute migration %d (%s): %s", migration.Version, migration.Name, err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, record, args...)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to record migration %d: %s", migration.Version, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit migration %d: %s", migration.Version, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,249 @@
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/db"
+)
+
+var testMigrations = []db.Migration{
+	{Version: 1, Name: "first", Up: []string{"CREATE TABLE first"}, Down: []string{"DROP TABLE first"}},
+	{Version: 2, Name: "second", Up: []string{"CREATE TABLE second"}, Down: []string{"DROP TABLE second"}},
+	{Version: 3, Name: "third", Up: []string{"CREATE TABLE third"}, Down: []string{"DROP TABLE third"}},
+}
+
+// expectLockedVersion sets the expectations of acquiring the migrations lock and reading the current version
+func expectLockedVersion(mock sqlmock.Sqlmock, version int) {
+	mock.ExpectExec("SELECT pg_advisory_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT COALESCE\\(MAX\\(version\\), 0\\) FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(version))
+}
+
+func expectApplied(mock sqlmock.Sqlmock, migration db.Migration) {
+	mock.ExpectBegin()
+	mock.ExpectExec(migration.Up[0]).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(migration.Version, migration.Name).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func expectReverted(mock sqlmock.Sqlmock, migration db.Migration) {
+	mock.ExpectBegin()
+	mock.ExpectExec(migration.Down[0]).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schema_migrations").WithArgs(migration.Version).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func TestMigrator_Up(t *testing.T) {
+
+	t.Run("apply every pending migration in order holding the lock", func(t *testing.T) {
+		conn, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer conn.Close()
+
+		expectLockedVersion(mock, 1)
+		expectApplied(mock, testMigrations[1])
+		expectApplied(mock, testMigrations[2])
+		mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = db.NewMigrator(conn, testMigrations).Up(context.Background())
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failing migration is rolled back and stops the process", func(t *testing.T) {
+		conn, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer conn.Close()
+
+		expectLockedVersion(mock, 0)
+		expectApplied(mock, testMigrations[0])
+		mock.ExpectBegin()
+		mock.ExpectExec("CREATE TABLE second").WillReturnError(fmt.Errorf("syntax error"))
+		mock.ExpectRollback()
+		mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = db.NewMigrator(conn, testMigrations).Up(context.Background())
+
+		assert.Error(t, err, "should have returned an error")
+		assert.Contains(t, err.Error(), "failed to execute migration 2 (second)", "Error message doesn't match")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMigrator_Down(t *testing.T) {
+
+	t.Run("revert only the last applied migration", func(t *testing.T) {
+		conn, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer conn.Close()
+
+		expectLockedVersion(mock, 3)
+		expectReverted(mock, testMigrations[2])
+		mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = db.NewMigrator(conn, testMigrations).Down(context.Background())
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a database that was never migrated is left untouched", func(t *testing.T) {
+		conn, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer conn.Close()
+
+		expectLockedVersion(mock, 0)
+		mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = db.NewMigrator(conn, testMigrations).Down(context.Background())
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMigrator_Goto(t *testing.T) {
+
+	t.Run("revert migrations in reverse order down to the target version", func(t *testing.T) {
+		conn, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer conn.Close()
+
+		expectLockedVersion(mock, 3)
+		expectReverted(mock, testMigrations[2])
+		expectReverted(mock, testMigrations[1])
+		mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = db.NewMigrator(conn, testMigrations).Goto(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("apply migrations up to the target version", func(t *testing.T) {
+		conn, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer conn.Close()
+
+		expectLockedVersion(mock, 0)
+		expectApplied(mock, testMigrations[0])
+		expectApplied(mock, testMigrations[1])
+		mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = db.NewMigrator(conn, testMigrations).Goto(context.Background(), 2)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("an unknown version is rejected without touching the database", func(t *testing.T) {
+		conn, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer conn.Close()
+
+		err = db.NewMigrator(conn, testMigrations).Goto(context.Background(), 7)
+
+		assert.Error(t, err, "should have returned an error")
+		assert.Contains(t, err.Error(), "unknown migration version 7", "Error message doesn't match")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMigrator_Version(t *testing.T) {
+
+	t.Run("a database without the schema_migrations table is at version 0", func(t *testing.T) {
+		conn, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer conn.Close()
+
+		mock.ExpectQuery("SELECT COALESCE").WillReturnError(&pq.Error{Code: "42P01"})
+
+		version, err := db.NewMigrator(conn, testMigrations).Version(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, version)
+	})
+
+	t.Run("other errors are returned", func(t *testing.T) {
+		conn, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer conn.Close()
+
+		mock.ExpectQuery("SELECT COALESCE").WillReturnError(fmt.Errorf("connection refused"))
+
+		_, err = db.NewMigrator(conn, testMigrations).Version(context.Background())
+
+		assert.Error(t, err, "should have returned an error")
+		assert.Contains(t, err.Error(), "connection refused", "Error message doesn't match")
+	})
+}
+
+func TestMigrator_Status(t *testing.T) {
+
+	t.Run("report which migrations were applied", func(t *testing.T) {
+		conn, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer conn.Close()
+
+		appliedAt := time.Now()
+
+		mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+			WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).
+				AddRow(1, appliedAt).
+				AddRow(2, appliedAt))
+
+		statuses, err := db.NewMigrator(conn, testMigrations).Status(context.Background())
+
+		assert.NoError(t, err)
+		assert.Len(t, statuses, 3)
+		assert.True(t, statuses[0].Applied)
+		assert.Equal(t, appliedAt, statuses[1].AppliedAt)
+		assert.False(t, statuses[2].Applied)
+	})
+}
+
+func TestMigrations(t *testing.T) {
+
+	t.Run("migrations are ordered by version and can be reverted", func(t *testing.T) {
+		previous := 0
+		for _, migration := range db.Migrations {
+			assert.True(t, migration.Version > previous, "migration %d is out of order", migration.Version)
+			assert.NotEmpty(t, migration.Up, "migration %d has no up statements", migration.Version)
+			assert.NotEmpty(t, migration.Down, "migration %d has no down statements", migration.Version)
+			previous = migration.Version
+		}
+
+		assert.Equal(t, previous, db.LatestVersion())
+	})
+}
@@ -0,0 +1,180 @@
+package db
+
+// Migration a versioned change to the database schema. Up holds the statements that apply the change and Down the
+// ones that revert it, both are executed inside a single transaction.
+type Migration struct {
+	Version int
+	Name    string
+	Up      []string
+	Down    []string
+}
+
+// Migrations every schema change ordered by version, new changes must be appended with a higher version and
+// migrations that were already released must never be edited.
+var Migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create users and contacts",
+		// Databases created before migrations were introduced already have this schema, every statement is
+		// idempotent so that they can be brought under version control by applying it.
+		Up: []string{
+			"CREATE SCHEMA IF NOT EXISTS \"contactsApi\"",
+			`CREATE TABLE IF NOT EXISTS "contactsApi".users(
+			id SERIAL,
+			"firstName" varchar(90) DEFAULT NULL,
+			"lastName" varchar(90) DEFAULT NULL,
+			updated_at timestamp DEFAULT NOW(),
+			created_at timestamp DEFAULT NOW(),
+			CONSTRAINT pk_users_id PRIMARY KEY (id) 
+		);`,
+			` CREATE UNIQUE INDEX IF NOT EXISTS pk_users_index ON "contactsApi".users
+		USING btree
+		(
+		  id ASC NULLS LAST
+		);`,
+			`CREATE INDEX IF NOT EXISTS pk_users_created_at ON "contactsApi".users
+		USING btree
+		(
+		  created_at ASC NULLS LAST
+		);`,
+			`CREATE INDEX IF NOT EXISTS pk_users_updated_at ON "contactsApi".users
+		USING btree
+		(
+		  updated_at ASC NULLS LAST
+		);`,
+			`CREATE TABLE IF NOT EXISTS "contactsApi".contacts(
+			id SERIAL,
+			user_id bigint NOT NULL,
+			"firstName" varchar(90) DEFAULT NULL,
+			"lastName" varchar(90) DEFAULT NULL,
+			"email" varchar(90) DEFAULT NULL,
+			"phone" varchar(90) DEFAULT NULL,
+			updated_at timestamp DEFAULT NOW(),
+			created_at timestamp DEFAULT NOW(),
+			CONSTRAINT pk_contacts_id PRIMARY KEY (id) 
+		);`,
+			` CREATE UNIQUE INDEX IF NOT EXISTS pk_contacts_index ON "contactsApi".contacts
+		USING btree
+		(
+		  id ASC NULLS LAST
+		);`,
+			`CREATE INDEX IF NOT EXISTS pk_contacts_created_at ON "contactsApi".contacts
+		USING btree
+		(
+		  created_at ASC NULLS LAST
+		);`,
+			`CREATE INDEX IF NOT EXISTS pk_contacts_updated_at ON "contactsApi".contacts
+		USING btree
+		(
+		  updated_at ASC NULLS LAST
+		);`,
+			`create or replace function create_constraint_if_not_exists (
+    s_name text, t_name text, c_name text, constraint_sql text
+) 
+returns void AS
+$$
+begin
+    -- Look for our constraint
+    if not exists (select constraint_name, constraint_schema
+                   from information_schema.constraint_column_usage 
+                   where constraint_name = c_name and constraint_schema = s_name) then
+        execute constraint_sql;
+    end if;
+end;
+$$ language 'plpgsql'`,
+			`SELECT create_constraint_if_not_exists(
+        'contactsApi',
+			'',
+        'fk_users_user_id',
+        'ALTER TABLE "contactsApi".contacts ADD CONSTRAINT fk_users_user_id FOREIGN KEY ("user_id")
+REFERENCES "contactsApi".users (id) MATCH FULL
+ON DELETE CASCADE ON UPDATE NO ACTION;')`,
+			`CREATE OR REPLACE FUNCTION "contactsApi".set_timestamp()
+RETURNS TRIGGER LANGUAGE 'plpgsql' AS $$
+BEGIN
+  NEW.updated_at = NOW();
+  RETURN NEW;
+END;
+$$;`,
+			`DROP TRIGGER IF EXISTS set_contacts_timestamp ON "contactsApi".contacts CASCADE`,
+			`DROP TRIGGER IF EXISTS set_users_timestamp ON "contactsApi".users CASCADE`,
+			`CREATE TRIGGER set_contacts_timestamp
+		BEFORE UPDATE
+		ON "contactsApi".contacts
+		FOR EACH ROW
+		EXECUTE PROCEDURE "contactsApi".set_timestamp();`,
+			`CREATE TRIGGER set_users_timestamp
+		BEFORE UPDATE
+		ON "contactsApi".users
+		FOR EACH ROW
+		EXECUTE PROCEDURE "contactsApi".set_timestamp();`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS "contactsApi".contacts`,
+			`DROP TABLE IF EXISTS "contactsApi".users`,
+			`DROP FUNCTION IF EXISTS "contactsApi".set_timestamp()`,
+			`DROP FUNCTION IF EXISTS create_constraint_if_not_exists(text, text, text, text)`,
+			`DROP SCHEMA IF EXISTS "contactsApi"`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "add row versions",
+		// The version of a row is increased by every update, it's sent to clients as the ETag of the resource and
+		// conditional writes only succeed while it's unchanged.
+		Up: []string{
+			`ALTER TABLE "contactsApi".users ADD COLUMN version bigint NOT NULL DEFAULT 1`,
+			`ALTER TABLE "contactsApi".contacts ADD COLUMN version bigint NOT NULL DEFAULT 1`,
+			`CREATE OR REPLACE FUNCTION "contactsApi".set_timestamp()
+RETURNS TRIGGER LANGUAGE 'plpgsql' AS $$
+BEGIN
+  NEW.updated_at = NOW();
+  NEW.version = OLD.version + 1;
+  RETURN NEW;
+END;
+$$;`,
+		},
+		Down: []string{
+			`CREATE OR REPLACE FUNCTION "contactsApi".set_timestamp()
+RETURNS TRIGGER LANGUAGE 'plpgsql' AS $$
+BEGIN
+  NEW.updated_at = NOW();
+  RETURN NEW;
+END;
+$$;`,
+			`ALTER TABLE "contactsApi".contacts DROP COLUMN version`,
+			`ALTER TABLE "contactsApi".users DROP COLUMN version`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create api keys",
+		// Keys are looked up by the SHA-256 hash of the key, they may belong to a user and are kept once revoked
+		Up: []string{
+			`CREATE TABLE "contactsApi".api_keys(
+			id SERIAL,
+			name varchar(90) NOT NULL,
+			key_hash char(64) NOT NULL,
+			user_id bigint DEFAULT NULL,
+			admin boolean NOT NULL DEFAULT false,
+			created_at timestamp DEFAULT NOW(),
+			revoked_at timestamp DEFAULT NULL,
+			CONSTRAINT pk_api_keys_id PRIMARY KEY (id),
+			CONSTRAINT uq_api_keys_key_hash UNIQUE (key_hash),
+			CONSTRAINT fk_api_keys_user_id FOREIGN KEY (user_id) REFERENCES "contactsApi".users (id) ON DELETE CASCADE
+		);`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS "contactsApi".api_keys`,
+		},
+	},
+}
+
+// LatestVersion returns the version of the last migration, the version the schema must be at for the application to
+// work properly.
+func LatestVersion() int {
+	if len(Migrations) == 0 {
+		return 0
+	}
+	return Migrations[len(Migrations)-1].Version
+}
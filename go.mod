@@ -6,4 +6,5 @@ require (
 	github.com/DATA-DOG/go-sqlmock v1.3.3
 	github.com/lib/pq v1.2.0
 	github.com/stretchr/testify v1.4.0
+	gopkg.in/yaml.v2 v2.2.2
 )
@@ -0,0 +1,196 @@
+// Package logging writes structured logs as JSON lines, one object per entry with the time, level and message
+// followed by the fields of the entry sorted by name.
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level severity of a log entry, entries below the level of the logger are dropped
+type Level int
+
+// Log levels from the least to the most severe
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel returns the level with the name, as written by Level.String
+func ParseLevel(name string) (Level, error) {
+	for i, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return Level(i), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q, use debug, info, warn or error", name)
+}
+
+// Fields values attached to a log entry
+type Fields map[string]interface{}
+
+// Logger writes log entries as JSON lines, it's safe to use by concurrent goroutines
+type Logger struct {
+	mu    sync.Mutex
+	out   io.Writer
+	level Level
+	now   func() time.Time
+}
+
+// Option configures the Logger
+type Option func(l *Logger)
+
+// WithLevel drops the entries less severe than the level, the default level is info
+func WithLevel(level Level) Option {
+	return func(l *Logger) {
+		l.level = level
+	}
+}
+
+// WithClock sets the function that returns the time of the entries
+func WithClock(now func() time.Time) Option {
+	return func(l *Logger) {
+		l.now = now
+	}
+}
+
+// New returns a logger that writes the entries to out
+func New(out io.Writer, opts ...Option) *Logger {
+	logger := &Logger{out: out, level: LevelInfo, now: time.Now}
+	for _, opt := range opts {
+		opt(logger)
+	}
+	return logger
+}
+
+// Enabled reports whether entries of the level are written
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// Log writes an entry with the level, message and fields. The request id stored in the context is added to the
+// fields.
+func (l *Logger) Log(ctx context.Context, level Level, msg string, fields Fields) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(`{"time":`)
+	writeValue(buf, l.now().UTC().Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeValue(buf, level.String())
+	buf.WriteString(`,"msg":`)
+	writeValue(buf, msg)
+
+	if id := RequestID(ctx); id != "" {
+		buf.WriteString(`,"request_id":`)
+		writeValue(buf, id)
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		buf.WriteByte(',')
+		writeValue(buf, name)
+		buf.WriteByte(':')
+		writeValue(buf, fields[name])
+	}
+	buf.WriteString("}\n")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.out.Write(buf.Bytes())
+}
+
+// Debug writes an entry with the debug level
+func (l *Logger) Debug(ctx context.Context, msg string, fields Fields) {
+	l.Log(ctx, LevelDebug, msg, fields)
+}
+
+// Info writes an entry with the info level
+func (l *Logger) Info(ctx context.Context, msg string, fields Fields) {
+	l.Log(ctx, LevelInfo, msg, fields)
+}
+
+// Warn writes an entry with the warn level
+func (l *Logger) Warn(ctx context.Context, msg string, fields Fields) {
+	l.Log(ctx, LevelWarn, msg, fields)
+}
+
+// Error writes an entry with the error level
+func (l *Logger) Error(ctx context.Context, msg string, fields Fields) {
+	l.Log(ctx, LevelError, msg, fields)
+}
+
+// writeValue writes the value as JSON, errors are written as their message and values that can't be encoded as
+// their fmt representation
+func writeValue(buf *bytes.Buffer, value interface{}) {
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprintf("%+v", value))
+	}
+	buf.Write(data)
+}
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestIDKey
+)
+
+// std logger used when the context doesn't carry one
+var std = New(os.Stderr)
+
+// NewContext returns a copy of the context that carries the logger
+func NewContext(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// FromContext returns the logger carried by the context, or a logger that writes to the standard error when there's
+// none
+func FromContext(ctx context.Context) *Logger {
+	if logger, ok := ctx.Value(loggerKey).(*Logger); ok && logger != nil {
+		return logger
+	}
+	return std
+}
+
+// WithRequestID returns a copy of the context that carries the id of the request being served
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the id of the request carried by the context, it's empty when there's none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
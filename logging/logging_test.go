@@ -0,0 +1,89 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/logging"
+)
+
+func TestLogger_Log(t *testing.T) {
+
+	clock := func() time.Time {
+		return time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+	}
+
+	t.Run("write an entry as a json line", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		logger := logging.New(out, logging.WithClock(clock))
+
+		logger.Info(context.Background(), "request served", logging.Fields{"status": 200, "method": "GET"})
+
+		assert.Equal(t,
+			`{"time":"2019-11-22T10:00:00Z","level":"info","msg":"request served","method":"GET","status":200}`+"\n",
+			out.String())
+	})
+
+	t.Run("add the request id of the context", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		logger := logging.New(out, logging.WithClock(clock))
+
+		ctx := logging.WithRequestID(context.Background(), "abc")
+		logger.Error(ctx, "request failed", logging.Fields{"error": errors.New("boom")})
+
+		assert.Equal(t,
+			`{"time":"2019-11-22T10:00:00Z","level":"error","msg":"request failed","request_id":"abc","error":"boom"}`+
+				"\n",
+			out.String())
+	})
+
+	t.Run("drop the entries below the level", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		logger := logging.New(out, logging.WithClock(clock), logging.WithLevel(logging.LevelWarn))
+
+		logger.Debug(context.Background(), "debug", nil)
+		logger.Info(context.Background(), "info", nil)
+		logger.Warn(context.Background(), "warn", nil)
+
+		assert.Equal(t, `{"time":"2019-11-22T10:00:00Z","level":"warn","msg":"warn"}`+"\n", out.String())
+	})
+
+	t.Run("write the values that can't be encoded as text", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		logger := logging.New(out, logging.WithClock(clock))
+
+		logger.Info(context.Background(), "odd", logging.Fields{"ch": make(chan int)})
+
+		assert.Contains(t, out.String(), `"ch":"0x`)
+	})
+}
+
+func TestParseLevel(t *testing.T) {
+
+	for _, name := range []string{"debug", "info", "warn", "error"} {
+		level, err := logging.ParseLevel(name)
+		assert.NoError(t, err)
+		assert.Equal(t, name, level.String())
+	}
+
+	level, err := logging.ParseLevel("WARN")
+	assert.NoError(t, err)
+	assert.Equal(t, logging.LevelWarn, level)
+
+	_, err = logging.ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestFromContext(t *testing.T) {
+
+	logger := logging.New(&bytes.Buffer{})
+
+	assert.Same(t, logger, logging.FromContext(logging.NewContext(context.Background(), logger)))
+	assert.NotNil(t, logging.FromContext(context.Background()), "A logger is returned when there's none")
+	assert.Empty(t, logging.RequestID(context.Background()))
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"bytes"
+	"database/sql"
+	"sync"
+)
+
+// StatsSource anything that reports the statistics of a database connection pool, like *sql.DB
+type StatsSource interface {
+	Stats() sql.DBStats
+}
+
+// DBStats collector of the statistics of a database connection pool, they're read from the pool every time the
+// metrics are collected
+type DBStats struct {
+	mu      sync.Mutex
+	db      StatsSource
+	stats   sql.DBStats
+	metrics []*GaugeFunc
+}
+
+// NewDBStats instantiates the collector of the statistics of the pool
+func NewDBStats(db StatsSource) *DBStats {
+	collector := &DBStats{db: db}
+	stats := &collector.stats
+
+	gauge := func(name, help string, value func() float64) {
+		collector.metrics = append(collector.metrics, NewGaugeFunc(name, help, value))
+	}
+	counter := func(name, help string, value func() float64) {
+		collector.metrics = append(collector.metrics, NewCounterFunc(name, help, value))
+	}
+
+	// The statistics are read once per collection, before the metrics read their values from them
+	gauge("db_pool_max_open_connections", "Maximum number of open connections to the database.",
+		func() float64 { return float64(stats.MaxOpenConnections) })
+	gauge("db_pool_open_connections", "Number of established connections, both in use and idle.",
+		func() float64 { return float64(stats.OpenConnections) })
+	gauge("db_pool_in_use_connections", "Number of connections currently in use.",
+		func() float64 { return float64(stats.InUse) })
+	gauge("db_pool_idle_connections", "Number of idle connections.",
+		func() float64 { return float64(stats.Idle) })
+	counter("db_pool_wait_count_total", "Total number of connections waited for.",
+		func() float64 { return float64(stats.WaitCount) })
+	counter("db_pool_wait_duration_seconds_total", "Total time blocked waiting for a new connection.",
+		func() float64 { return stats.WaitDuration.Seconds() })
+	counter("db_pool_max_idle_closed_total", "Total number of connections closed due to the idle limit.",
+		func() float64 { return float64(stats.MaxIdleClosed) })
+	counter("db_pool_max_lifetime_closed_total", "Total number of connections closed due to their maximum lifetime.",
+		func() float64 { return float64(stats.MaxLifetimeClosed) })
+
+	return collector
+}
+
+func (d *DBStats) Names() []string {
+	names := make([]string, len(d.metrics))
+	for i, metric := range d.metrics {
+		names[i] = metric.name
+	}
+	return names
+}
+
+func (d *DBStats) Collect(buf *bytes.Buffer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.stats = d.db.Stats()
+	for _, metric := range d.metrics {
+		metric.Collect(buf)
+	}
+}
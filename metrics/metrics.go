@@ -0,0 +1,303 @@
+// Package metrics keeps counters, gauges and histograms and exposes them in the Prometheus text exposition format.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType media type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets upper bounds, in seconds, of the histogram buckets used for latencies
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Collector writes the samples of one or more metric families in the text exposition format
+type Collector interface {
+	Names() []string
+	Collect(buf *bytes.Buffer)
+}
+
+// Registry holds the collectors exposed by the metrics endpoint
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+	names      map[string]bool
+}
+
+// NewRegistry instantiates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{names: map[string]bool{}}
+}
+
+// Register adds the collector to the registry and returns it, it panics when one of it's metric names is already
+// registered
+func (r *Registry) Register(collector Collector) Collector {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range collector.Names() {
+		if r.names[name] {
+			panic(fmt.Sprintf("metric %s is already registered", name))
+		}
+	}
+	for _, name := range collector.Names() {
+		r.names[name] = true
+	}
+
+	r.collectors = append(r.collectors, collector)
+	return collector
+}
+
+// ServeHTTP writes the samples of every registered collector
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	buf := &bytes.Buffer{}
+
+	r.mu.Lock()
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	for _, collector := range collectors {
+		collector.Collect(buf)
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	_, _ = buf.WriteTo(w)
+}
+
+// vec series of a metric family, one for every combination of label values
+type vec struct {
+	mu     sync.Mutex
+	name   string
+	help   string
+	kind   string
+	labels []string
+	series map[string]*series
+}
+
+type series struct {
+	values []string
+	value  float64
+
+	// Histograms only
+	counts []uint64
+	sum    float64
+}
+
+func newVec(name, help, kind string, labels []string) vec {
+	return vec{name: name, help: help, kind: kind, labels: labels, series: map[string]*series{}}
+}
+
+// get returns the series with the label values, creating it when it doesn't exist. It must be called with the lock
+// held.
+func (v *vec) get(values []string) *series {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+
+	key := strings.Join(values, "\x00")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{values: append([]string(nil), values...)}
+		v.series[key] = s
+	}
+	return s
+}
+
+// sorted returns the series ordered by their label values, so that the output is stable. It must be called with the
+// lock held.
+func (v *vec) sorted() []*series {
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sorted := make([]*series, len(keys))
+	for i, key := range keys {
+		sorted[i] = v.series[key]
+	}
+	return sorted
+}
+
+func (v *vec) Names() []string {
+	return []string{v.name}
+}
+
+// CounterVec counters that only go up, partitioned by label values
+type CounterVec struct {
+	vec
+}
+
+// NewCounterVec instantiates a counter family with the label names
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{newVec(name, help, "counter", labels)}
+}
+
+// Inc adds one to the counter with the label values
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds the delta, which can't be negative, to the counter with the label values
+func (c *CounterVec) Add(delta float64, values ...string) {
+	if delta < 0 {
+		panic(fmt.Sprintf("counter %s can't decrease", c.name))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.get(values).value += delta
+}
+
+// Value returns the value of the counter with the label values
+func (c *CounterVec) Value(values ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(values).value
+}
+
+func (c *CounterVec) Collect(buf *bytes.Buffer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	writeHeader(buf, c.name, c.help, c.kind)
+	for _, s := range c.sorted() {
+		writeSample(buf, c.name, c.labels, s.values, "", "", s.value)
+	}
+}
+
+// HistogramVec histograms that count observations in buckets, partitioned by label values
+type HistogramVec struct {
+	vec
+	buckets []float64
+}
+
+// NewHistogramVec instantiates a histogram family with the upper bounds of the buckets, sorted in increasing order,
+// and the label names
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if !sort.Float64sAreSorted(buckets) {
+		panic(fmt.Sprintf("buckets of histogram %s must be sorted", name))
+	}
+	return &HistogramVec{vec: newVec(name, help, "histogram", labels), buckets: buckets}
+}
+
+// Observe adds the value to the histogram with the label values
+func (h *HistogramVec) Observe(value float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.get(values)
+	if s.counts == nil {
+		s.counts = make([]uint64, len(h.buckets)+1)
+	}
+
+	// The last count is the +Inf bucket, buckets are made cumulative when they're written
+	s.counts[sort.SearchFloat64s(h.buckets, value)]++
+	s.sum += value
+}
+
+// Count returns the number of observations of the histogram with the label values
+func (h *HistogramVec) Count(values ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var count uint64
+	for _, n := range h.get(values).counts {
+		count += n
+	}
+	return count
+}
+
+func (h *HistogramVec) Collect(buf *bytes.Buffer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	writeHeader(buf, h.name, h.help, h.kind)
+	for _, s := range h.sorted() {
+		var cumulative uint64
+		for i, n := range s.counts {
+			cumulative += n
+			le := math.Inf(1)
+			if i < len(h.buckets) {
+				le = h.buckets[i]
+			}
+			writeSample(buf, h.name+"_bucket", h.labels, s.values, "le", formatFloat(le), float64(cumulative))
+		}
+		writeSample(buf, h.name+"_sum", h.labels, s.values, "", "", s.sum)
+		writeSample(buf, h.name+"_count", h.labels, s.values, "", "", float64(cumulative))
+	}
+}
+
+// GaugeFunc gauge, or counter, whose value is read when the metrics are collected
+type GaugeFunc struct {
+	name  string
+	help  string
+	kind  string
+	value func() float64
+}
+
+// NewGaugeFunc instantiates a gauge that reads it's value from the function
+func NewGaugeFunc(name, help string, value func() float64) *GaugeFunc {
+	return &GaugeFunc{name: name, help: help, kind: "gauge", value: value}
+}
+
+// NewCounterFunc instantiates a counter that reads it's value from the function, which must never decrease
+func NewCounterFunc(name, help string, value func() float64) *GaugeFunc {
+	return &GaugeFunc{name: name, help: help, kind: "counter", value: value}
+}
+
+func (g *GaugeFunc) Names() []string {
+	return []string{g.name}
+}
+
+func (g *GaugeFunc) Collect(buf *bytes.Buffer) {
+	writeHeader(buf, g.name, g.help, g.kind)
+	writeSample(buf, g.name, nil, nil, "", "", g.value())
+}
+
+func writeHeader(buf *bytes.Buffer, name, help, kind string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// writeSample writes a sample line, extra is an additional label like the le label of the histogram buckets
+func writeSample(buf *bytes.Buffer, name string, labels, values []string, extra, extraValue string, value float64) {
+	buf.WriteString(name)
+
+	if len(labels) > 0 || extra != "" {
+		pairs := make([]string, 0, len(labels)+1)
+		for i, label := range labels {
+			pairs = append(pairs, label+`="`+escapeLabel(values[i])+`"`)
+		}
+		if extra != "" {
+			pairs = append(pairs, extra+`="`+escapeLabel(extraValue)+`"`)
+		}
+		buf.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+
+	buf.WriteString(" " + formatFloat(value) + "\n")
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
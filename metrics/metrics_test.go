@@ -0,0 +1,109 @@
+package metrics_test
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/metrics"
+)
+
+// scrape returns the metrics exposed by the registry
+func scrape(t *testing.T, registry *metrics.Registry) string {
+	req, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+	response := httptest.NewRecorder()
+
+	registry.ServeHTTP(response, req)
+
+	assert.Equal(t, metrics.ContentType, response.Header().Get("Content-Type"))
+	return response.Body.String()
+}
+
+func TestCounterVec(t *testing.T) {
+
+	registry := metrics.NewRegistry()
+	counter := metrics.NewCounterVec("requests_total", "Total requests.", "route", "status")
+	registry.Register(counter)
+
+	counter.Inc("/users", "200")
+	counter.Inc("/users", "200")
+	counter.Add(3, "/users/{id:int}", "404")
+	counter.Inc(`a "quoted\ path`+"\n", "500")
+
+	assert.Equal(t, float64(2), counter.Value("/users", "200"))
+	assert.Equal(t, "# HELP requests_total Total requests.\n"+
+		"# TYPE requests_total counter\n"+
+		`requests_total{route="/users",status="200"} 2`+"\n"+
+		`requests_total{route="/users/{id:int}",status="404"} 3`+"\n"+
+		`requests_total{route="a \"quoted\\ path\n",status="500"} 1`+"\n",
+		scrape(t, registry))
+
+	assert.Panics(t, func() { counter.Add(-1, "/users", "200") }, "Counters can't decrease")
+	assert.Panics(t, func() { counter.Inc("/users") }, "Every label needs a value")
+}
+
+func TestHistogramVec(t *testing.T) {
+
+	registry := metrics.NewRegistry()
+	histogram := metrics.NewHistogramVec("duration_seconds", "Durations.", []float64{0.1, 1}, "method")
+	registry.Register(histogram)
+
+	histogram.Observe(0.05, "List")
+	histogram.Observe(0.1, "List")
+	histogram.Observe(0.5, "List")
+	histogram.Observe(2, "List")
+
+	assert.Equal(t, uint64(4), histogram.Count("List"))
+	assert.Equal(t, "# HELP duration_seconds Durations.\n"+
+		"# TYPE duration_seconds histogram\n"+
+		`duration_seconds_bucket{method="List",le="0.1"} 2`+"\n"+
+		`duration_seconds_bucket{method="List",le="1"} 3`+"\n"+
+		`duration_seconds_bucket{method="List",le="+Inf"} 4`+"\n"+
+		`duration_seconds_sum{method="List"} 2.65`+"\n"+
+		`duration_seconds_count{method="List"} 4`+"\n",
+		scrape(t, registry))
+
+	assert.Panics(t, func() { metrics.NewHistogramVec("unsorted", "", []float64{1, 0.1}) })
+}
+
+func TestRegistry_Register(t *testing.T) {
+
+	registry := metrics.NewRegistry()
+	registry.Register(metrics.NewCounterVec("requests_total", ""))
+
+	assert.Panics(t, func() { registry.Register(metrics.NewGaugeFunc("requests_total", "", nil)) },
+		"A name can only be registered once")
+}
+
+// StubStats reports fixed pool statistics
+type StubStats struct {
+	stats sql.DBStats
+}
+
+func (s *StubStats) Stats() sql.DBStats {
+	return s.stats
+}
+
+func TestDBStats(t *testing.T) {
+
+	source := &StubStats{stats: sql.DBStats{MaxOpenConnections: 10, OpenConnections: 4, InUse: 3, Idle: 1,
+		WaitCount: 7, WaitDuration: 1500 * time.Millisecond}}
+
+	registry := metrics.NewRegistry()
+	registry.Register(metrics.NewDBStats(source))
+
+	output := scrape(t, registry)
+	assert.Contains(t, output, "# TYPE db_pool_open_connections gauge\ndb_pool_open_connections 4\n")
+	assert.Contains(t, output, "db_pool_in_use_connections 3\n")
+	assert.Contains(t, output, "db_pool_idle_connections 1\n")
+	assert.Contains(t, output, "# TYPE db_pool_wait_count_total counter\ndb_pool_wait_count_total 7\n")
+	assert.Contains(t, output, "db_pool_wait_duration_seconds_total 1.5\n")
+
+	// The statistics are read on every scrape
+	source.stats.InUse = 1
+	assert.Contains(t, scrape(t, registry), "db_pool_in_use_connections 1\n")
+}
@@ -0,0 +1,22 @@
+package obj
+
+import (
+	"fmt"
+	"time"
+)
+
+// APIKey credential of a client that calls the API with the X-API-Key header. Only the hash of the key is stored,
+// the key itself is shown once when it's created.
+type APIKey struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	UserID    int        `json:"user_id,omitempty"`
+	Admin     bool       `json:"admin"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (k APIKey) String() string {
+	return fmt.Sprintf("ID=%d Name=%s UserID=%d Admin=%t CreatedAt=%s RevokedAt=%v", k.ID, k.Name, k.UserID, k.Admin,
+		k.CreatedAt, k.RevokedAt)
+}
@@ -8,12 +8,13 @@ import (
 type Contact struct {
 	ID        int64     `json:"id"`
 	UserID    int64     `json:"user_id"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Email     string    `json:"email"`
-	Phone     string    `json:"phone"`
+	FirstName string    `json:"first_name" validate:"required,max=90"`
+	LastName  string    `json:"last_name" validate:"max=90"`
+	Email     string    `json:"email" validate:"max=90,email"`
+	Phone     string    `json:"phone" validate:"max=90,phone"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	Version   int64     `json:"-"`
 }
 
 func (c Contact) String() string {
@@ -22,3 +23,8 @@ func (c Contact) String() string {
 		c.FirstName,
 		c.LastName, c.Email, c.Phone, c.CreatedAt, c.UpdatedAt)
 }
+
+// Validate checks the fields sent by clients, reporting every invalid one as ValidationErrors
+func (c Contact) Validate() error {
+	return Validate(c)
+}
@@ -7,10 +7,11 @@ import (
 
 type User struct {
 	ID        int     `json:"id"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
+	FirstName string    `json:"first_name" validate:"required,max=90"`
+	LastName  string    `json:"last_name" validate:"required,max=90"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	Version   int64     `json:"-"`
 	Contacts  []Contact `json:"contacts,omitempty"`
 }
 
@@ -18,3 +19,8 @@ func (c User) String() string {
 	return fmt.Sprintf("ID=%d FirstName=%s LastName=%s CreatedAt=%s UpdatedAt=%s Contacts=%s", c.ID, c.FirstName,
 		c.LastName, c.CreatedAt, c.UpdatedAt, c.Contacts)
 }
+
+// Validate checks the fields sent by clients, reporting every invalid one as ValidationErrors
+func (c User) Validate() error {
+	return Validate(c)
+}
@@ -0,0 +1,117 @@
+package obj
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationError a field whose value breaks one of the rules declared in it's validate tag
+type ValidationError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// ValidationErrors every field of an object that failed validation
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	reasons := make([]string, len(e))
+	for i, validationError := range e {
+		reasons[i] = fmt.Sprintf("%s: %s", validationError.Field, validationError.Reason)
+	}
+	return "invalid fields: " + strings.Join(reasons, ", ")
+}
+
+// rule checks a single string value, param holds the value given to the rule in the tag, like 90 in max=90
+type rule func(value, param string) (reason string, ok bool)
+
+// rules every rule that can be used in a validate tag. Apart from required, rules skip empty values.
+var rules = map[string]rule{
+	"required": func(value, _ string) (string, bool) {
+		return "is required", strings.TrimSpace(value) != ""
+	},
+	"max": func(value, param string) (string, bool) {
+		max, _ := strconv.Atoi(param)
+		return fmt.Sprintf("must be at most %d characters long", max), utf8.RuneCountInString(value) <= max
+	},
+	"email": func(value, _ string) (string, bool) {
+		address, err := mail.ParseAddress(value)
+		return "must be a valid email address", err == nil && address.Address == value &&
+			strings.Contains(value[strings.LastIndex(value, "@"):], ".")
+	},
+	"phone": func(value, _ string) (string, bool) {
+		return "must be a phone number with 6 to 15 digits, optionally prefixed by +", isPhone(value)
+	},
+}
+
+// Validate checks every string field of the struct against the rules declared in it's validate tag, for instance
+// `validate:"required,max=90"`, reporting all the invalid fields at once. Fields are named after their json tag.
+func Validate(v interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	errs := ValidationErrors{}
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		tag := field.Tag.Get("validate")
+		if tag == "" || field.Type.Kind() != reflect.String {
+			continue
+		}
+
+		fieldValue := value.Field(i).String()
+		for _, declared := range strings.Split(tag, ",") {
+			name, param := declared, ""
+			if index := strings.Index(declared, "="); index != -1 {
+				name, param = declared[:index], declared[index+1:]
+			}
+
+			check, ok := rules[name]
+			if !ok {
+				panic(fmt.Sprintf("unknown validation rule %q in field %s", name, field.Name))
+			}
+
+			if name != "required" && fieldValue == "" {
+				continue
+			}
+
+			if reason, ok := check(fieldValue, param); !ok {
+				errs = append(errs, ValidationError{Field: jsonName(field), Reason: reason})
+				break
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// jsonName returns the name of the field in it's json representation
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+// isPhone accepts digits separated by spaces, dots, dashes or parentheses, optionally prefixed by +
+func isPhone(value string) bool {
+	digits := 0
+	for i, char := range value {
+		switch {
+		case char >= '0' && char <= '9':
+			digits++
+		case char == '+' && i == 0:
+		case strings.ContainsRune(" .-()", char):
+		default:
+			return false
+		}
+	}
+	return digits >= 6 && digits <= 15
+}
@@ -0,0 +1,59 @@
+package obj_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/obj"
+)
+
+func TestUser_Validate(t *testing.T) {
+	t.Run("a user with both names is valid", func(t *testing.T) {
+		assert.NoError(t, obj.User{FirstName: "Pedro", LastName: "Cenas"}.Validate())
+	})
+
+	t.Run("every invalid field is reported", func(t *testing.T) {
+		err := obj.User{FirstName: "  ", LastName: strings.Repeat("a", 91)}.Validate()
+
+		assert.Equal(t, obj.ValidationErrors{
+			{Field: "first_name", Reason: "is required"},
+			{Field: "last_name", Reason: "must be at most 90 characters long"},
+		}, err)
+	})
+
+	t.Run("lengths are counted in characters", func(t *testing.T) {
+		assert.NoError(t, obj.User{FirstName: strings.Repeat("ã", 90), LastName: "Cenas"}.Validate())
+	})
+}
+
+func TestContact_Validate(t *testing.T) {
+	t.Run("only the first name is required", func(t *testing.T) {
+		assert.NoError(t, obj.Contact{FirstName: "Pedro"}.Validate())
+	})
+
+	cases := []struct {
+		email string
+		phone string
+		valid bool
+	}{
+		{"example@example.com", "919236587", true},
+		{"example@example.com", "+351 919 236 587", true},
+		{"example@example.com", "(21) 555-0100", true},
+		{"example", "919236587", false},
+		{"Pedro <example@example.com>", "919236587", false},
+		{"example@localhost", "919236587", false},
+		{"example@example.com", "12345", false},
+		{"example@example.com", "91923658a", false},
+		{"example@example.com", "1234567890123456", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.email+" "+c.phone, func(t *testing.T) {
+			err := obj.Contact{FirstName: "Pedro", Email: c.email, Phone: c.phone}.Validate()
+
+			assert.Equal(t, c.valid, err == nil, "unexpected result %v", err)
+		})
+	}
+}
@@ -0,0 +1,346 @@
+// Package patch applies RFC 7396 JSON Merge Patch and RFC 6902 JSON Patch documents to JSON documents.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+var (
+	// ErrInvalid the patch document is malformed, like an unknown operation or a missing path
+	ErrInvalid = errors.New("invalid patch")
+	// ErrConflict the patch is well formed but can't be applied to the document, like a failed test operation or a
+	// path that doesn't exist
+	ErrConflict = errors.New("patch can't be applied")
+)
+
+// Operation single operation of a JSON Patch document
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Merge applies a JSON Merge Patch to the document returning the patched document
+func Merge(document, mergePatch []byte) ([]byte, error) {
+	target, err := decode(document)
+	if err != nil {
+		return nil, err
+	}
+
+	patch, err := decode(mergePatch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalid, err)
+	}
+
+	return json.Marshal(merge(target, patch))
+}
+
+// merge implements the MergePatch function of RFC 7396
+func merge(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = merge(targetObject[name], value)
+	}
+
+	return targetObject
+}
+
+// Apply applies the operations of a JSON Patch document to the document returning the patched document. The
+// operations are applied in order and the document is left untouched when any of them fails.
+func Apply(document, jsonPatch []byte) ([]byte, error) {
+	target, err := decode(document)
+	if err != nil {
+		return nil, err
+	}
+
+	operations := []Operation{}
+	decoder := json.NewDecoder(bytes.NewReader(jsonPatch))
+	decoder.UseNumber()
+	err = decoder.Decode(&operations)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalid, err)
+	}
+
+	for i, operation := range operations {
+		target, err = apply(target, operation)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, operation.Op, operation.Path, err)
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+// apply applies a single JSON Patch operation
+func apply(document interface{}, operation Operation) (interface{}, error) {
+	path, err := parsePointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation.Op {
+	case "add", "replace", "test":
+		if len(operation.Value) == 0 {
+			return nil, fmt.Errorf("%w: missing value", ErrInvalid)
+		}
+		value, err := decode(operation.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalid, err)
+		}
+
+		switch operation.Op {
+		case "add":
+			return add(document, path, value)
+		case "replace":
+			return replace(document, path, value)
+		default:
+			current, err := get(document, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, fmt.Errorf("%w: test failed", ErrConflict)
+			}
+			return document, nil
+		}
+	case "remove":
+		return remove(document, path)
+	case "move", "copy":
+		from, err := parsePointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := get(document, from)
+		if err != nil {
+			return nil, err
+		}
+
+		if operation.Op == "copy" {
+			value, err = deepCopy(value)
+			if err != nil {
+				return nil, err
+			}
+			return add(document, path, value)
+		}
+
+		if strings.HasPrefix(operation.Path+"/", operation.From+"/") && operation.Path != operation.From {
+			return nil, fmt.Errorf("%w: a value can't be moved into one of it's children", ErrInvalid)
+		}
+
+		document, err = remove(document, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(document, path, value)
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalid, operation.Op)
+	}
+}
+
+// parsePointer splits a RFC 6901 JSON Pointer in it's unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: path %q must start with /", ErrInvalid, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+
+	return tokens, nil
+}
+
+func get(document interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		var err error
+		document, err = child(document, token)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return document, nil
+}
+
+func add(document interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return modify(document, path, func(container interface{}, token string) (interface{}, error) {
+		switch node := container.(type) {
+		case map[string]interface{}:
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			index := len(node)
+			if token != "-" {
+				var err error
+				index, err = arrayIndex(token, len(node)+1)
+				if err != nil {
+					return nil, err
+				}
+			}
+			node = append(node, nil)
+			copy(node[index+1:], node[index:])
+			node[index] = value
+			return node, nil
+		default:
+			return nil, fmt.Errorf("%w: %q can't hold children", ErrConflict, token)
+		}
+	})
+}
+
+func remove(document interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: the whole document can't be removed", ErrInvalid)
+	}
+
+	return modify(document, path, func(container interface{}, token string) (interface{}, error) {
+		if _, err := child(container, token); err != nil {
+			return nil, err
+		}
+
+		switch node := container.(type) {
+		case map[string]interface{}:
+			delete(node, token)
+			return node, nil
+		default:
+			array := node.([]interface{})
+			index, _ := arrayIndex(token, len(array))
+			return append(array[:index], array[index+1:]...), nil
+		}
+	})
+}
+
+func replace(document interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return modify(document, path, func(container interface{}, token string) (interface{}, error) {
+		if _, err := child(container, token); err != nil {
+			return nil, err
+		}
+		return setChild(container, token, value), nil
+	})
+}
+
+// modify walks the document down to the container of the last token of the path and replaces it with the result
+// of fn, returning the modified document
+func modify(document interface{}, path []string, fn func(container interface{}, token string) (interface{},
+	error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(document, path[0])
+	}
+
+	node, err := child(document, path[0])
+	if err != nil {
+		return nil, err
+	}
+
+	node, err = modify(node, path[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+
+	return setChild(document, path[0], node), nil
+}
+
+// child returns the member or element of the container identified by the token
+func child(container interface{}, token string) (interface{}, error) {
+	switch node := container.(type) {
+	case map[string]interface{}:
+		value, ok := node[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: member %q doesn't exist", ErrConflict, token)
+		}
+		return value, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(node))
+		if err != nil {
+			return nil, err
+		}
+		return node[index], nil
+	default:
+		return nil, fmt.Errorf("%w: %q can't hold children", ErrConflict, token)
+	}
+}
+
+// setChild replaces a member or element that is known to exist
+func setChild(container interface{}, token string, value interface{}) interface{} {
+	switch node := container.(type) {
+	case map[string]interface{}:
+		node[token] = value
+		return node
+	default:
+		array := node.([]interface{})
+		index, _ := arrayIndex(token, len(array))
+		array[index] = value
+		return array
+	}
+}
+
+// arrayIndex parses an array index token that must be lower than limit
+func arrayIndex(token string, limit int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalid, token)
+	}
+	if index >= limit {
+		return 0, fmt.Errorf("%w: array index %d is out of bounds", ErrConflict, index)
+	}
+	return index, nil
+}
+
+// decode decodes a JSON document keeping numbers as json.Number so that they're written back unchanged
+func decode(data []byte) (interface{}, error) {
+	var document interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&document)
+	if err != nil {
+		return nil, err
+	}
+
+	return document, nil
+}
+
+func deepCopy(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return decode(data)
+}
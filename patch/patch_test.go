@@ -0,0 +1,126 @@
+package patch_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/patch"
+)
+
+func TestMerge(t *testing.T) {
+
+	// Test cases from the appendix A of RFC 7396
+	cases := []struct {
+		document string
+		patch    string
+		expected string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.document+" "+c.patch, func(t *testing.T) {
+			result, err := patch.Merge([]byte(c.document), []byte(c.patch))
+
+			assert.NoError(t, err)
+			assert.JSONEq(t, c.expected, string(result))
+		})
+	}
+
+	t.Run("malformed patches are invalid", func(t *testing.T) {
+		_, err := patch.Merge([]byte(`{}`), []byte(`{"a":`))
+
+		assert.True(t, errors.Is(err, patch.ErrInvalid), "should have returned an invalid patch error")
+	})
+}
+
+func TestApply(t *testing.T) {
+
+	// Test cases based on the appendix A of RFC 6902
+	cases := []struct {
+		name     string
+		document string
+		patch    string
+		expected string
+	}{
+		{"add an object member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`,
+			`{"baz":"qux","foo":"bar"}`},
+		{"add an array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			`{"foo":["bar","qux","baz"]}`},
+		{"append to an array", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+			`{"foo":["bar",["abc","def"]]}`},
+		{"remove an object member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`,
+			`{"foo":"bar"}`},
+		{"remove an array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`,
+			`{"foo":["bar","baz"]}`},
+		{"replace a value", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`,
+			`{"baz":"boo","foo":"bar"}`},
+		{"move a value", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"move an array element", `{"foo":["all","grass","cows","eat"]}`,
+			`[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{"copy a value", `{"foo":{"bar":"baz"}}`, `[{"op":"copy","from":"/foo","path":"/qux"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"bar":"baz"}}`},
+		{"test a value", `{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`},
+		{"add a null value", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":null}]`,
+			`{"baz":null,"foo":"bar"}`},
+		{"escaped keys", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10},{"op":"remove","path":"/~1"}]`,
+			`{"~1":10}`},
+		{"replace the whole document", `{"foo":"bar"}`, `[{"op":"replace","path":"","value":{"baz":"qux"}}]`,
+			`{"baz":"qux"}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result, err := patch.Apply([]byte(c.document), []byte(c.patch))
+
+			assert.NoError(t, err)
+			assert.JSONEq(t, c.expected, string(result))
+		})
+	}
+
+	failures := []struct {
+		name     string
+		document string
+		patch    string
+		expected error
+	}{
+		{"failed test", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, patch.ErrConflict},
+		{"missing member", `{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, patch.ErrConflict},
+		{"missing parent", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, patch.ErrConflict},
+		{"index out of bounds", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/3","value":"qux"}]`,
+			patch.ErrConflict},
+		{"unknown operation", `{}`, `[{"op":"merge","path":"/foo","value":"qux"}]`, patch.ErrInvalid},
+		{"missing value", `{}`, `[{"op":"add","path":"/foo"}]`, patch.ErrInvalid},
+		{"relative path", `{}`, `[{"op":"add","path":"foo","value":1}]`, patch.ErrInvalid},
+		{"move into a child", `{"foo":{}}`, `[{"op":"move","from":"/foo","path":"/foo/bar"}]`, patch.ErrInvalid},
+		{"not a list of operations", `{}`, `{"op":"add"}`, patch.ErrInvalid},
+	}
+
+	for _, c := range failures {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := patch.Apply([]byte(c.document), []byte(c.patch))
+
+			assert.True(t, errors.Is(err, c.expected), "unexpected error %v", err)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pedrorochaorg/contactsApi/obj"
+)
+
+type APIKeyRepo interface {
+	Create(ctx context.Context, key *obj.APIKey, hash string) (*obj.APIKey, error)
+	GetByHash(ctx context.Context, hash string) (*obj.APIKey, error)
+	Revoke(ctx context.Context, id int) (bool, error)
+}
+
+type APIKeyRepository struct {
+	db conn
+}
+
+// NewAPIKeyRepository instantiates a new api key repository injecting the database connection interface as a
+// dependency
+func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return APIKeyRepository{conn{db}}
+}
+
+// scanAPIKey maps the current row of an api keys query into an api key struct, keys without a user have a zero
+// UserID
+func scanAPIKey(rows *sql.Rows, key *obj.APIKey) error {
+	var userID sql.NullInt64
+	err := rows.Scan(
+		&key.ID,
+		&key.Name,
+		&userID,
+		&key.Admin,
+		&key.CreatedAt,
+		&key.RevokedAt)
+	key.UserID = int(userID.Int64)
+	return err
+}
+
+// Create stores a new api key identified by the hash of the key
+func (a *APIKeyRepository) Create(ctx context.Context, key *obj.APIKey, hash string) (*obj.APIKey, error) {
+	userID := sql.NullInt64{Int64: int64(key.UserID), Valid: key.UserID != 0}
+
+	rows, err := a.db.QueryContext(ctx, "INSERT INTO \"contactsApi\".\"api_keys\"(name, key_hash, user_id, admin) "+
+		"VALUES($1, $2, $3, $4) RETURNING id, name, user_id, admin, created_at, revoked_at",
+		key.Name, hash, userID, key.Admin)
+	if err != nil {
+		return nil, wrapError("failed to insert api key in database", err)
+	}
+
+	defer rows.Close()
+	err = nextRow(rows, "failed to insert api key in database")
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanAPIKey(rows, key)
+	if err != nil {
+		return nil, wrapError("failed to map row to api key", err)
+	}
+
+	traceRows(ctx, 1)
+	return key, nil
+}
+
+// GetByHash fetches the api key with the given hash, revoked keys aren't returned
+func (a *APIKeyRepository) GetByHash(ctx context.Context, hash string) (*obj.APIKey, error) {
+	rows, err := a.db.QueryContext(ctx, "SELECT id, name, user_id, admin, created_at, revoked_at FROM "+
+		"\"contactsApi\".\"api_keys\" WHERE key_hash = $1 AND revoked_at IS NULL", hash)
+	if err != nil {
+		return nil, wrapError("failed to fetch api key from database", err)
+	}
+
+	key := &obj.APIKey{}
+
+	defer rows.Close()
+	err = nextRow(rows, "failed to fetch api key from database")
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanAPIKey(rows, key)
+	if err != nil {
+		return nil, wrapError("failed to map row to api key", err)
+	}
+
+	traceRows(ctx, 1)
+	return key, nil
+}
+
+// Revoke revokes an api key, keys that were already revoked are reported as not found
+func (a *APIKeyRepository) Revoke(ctx context.Context, id int) (bool, error) {
+	result, err := a.db.ExecContext(ctx, "UPDATE \"contactsApi\".\"api_keys\" SET revoked_at = NOW() WHERE id = $1 "+
+		"AND revoked_at IS NULL", id)
+	if err != nil {
+		return false, wrapError("failed to revoke api key in database", err)
+	}
+
+	err = affectedRow(result, "failed to revoke api key in database")
+	if err != nil {
+		return false, err
+	}
+
+	traceRows(ctx, 1)
+	return true, nil
+}
@@ -0,0 +1,149 @@
+package repos_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/obj"
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+var apiKeyColumns = []string{"id", "name", "user_id", "admin", "created_at", "revoked_at"}
+
+func TestAPIKeyRepository_GetByHash(t *testing.T) {
+
+	t.Run("test that we are able to obtain a key by it's hash", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		createdAt := time.Now()
+		rows := sqlmock.NewRows(apiKeyColumns).AddRow(1, "mobile", 3, false, createdAt, nil)
+		mock.ExpectQuery("SELECT (.+) WHERE key_hash = \\$1 AND revoked_at IS NULL").WithArgs("hash").
+			WillReturnRows(rows)
+
+		keyRepo := repos.NewAPIKeyRepository(db)
+
+		key, err := keyRepo.GetByHash(context.Background(), "hash")
+
+		assert.NoError(t, err)
+		assert.Equal(t, &obj.APIKey{ID: 1, Name: "mobile", UserID: 3, CreatedAt: createdAt}, key)
+	})
+
+	t.Run("test that admin keys have no user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		createdAt := time.Now()
+		rows := sqlmock.NewRows(apiKeyColumns).AddRow(2, "ops", nil, true, createdAt, nil)
+		mock.ExpectQuery("SELECT").WithArgs("hash").WillReturnRows(rows)
+
+		keyRepo := repos.NewAPIKeyRepository(db)
+
+		key, err := keyRepo.GetByHash(context.Background(), "hash")
+
+		assert.NoError(t, err)
+		assert.Equal(t, &obj.APIKey{ID: 2, Name: "ops", Admin: true, CreatedAt: createdAt}, key)
+	})
+
+	t.Run("test that unknown keys aren't found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT").WithArgs("hash").WillReturnRows(sqlmock.NewRows(apiKeyColumns))
+
+		keyRepo := repos.NewAPIKeyRepository(db)
+
+		_, err = keyRepo.GetByHash(context.Background(), "hash")
+
+		assert.True(t, errors.Is(err, repos.ErrNotFound), "unexpected error %v", err)
+	})
+}
+
+func TestAPIKeyRepository_Create(t *testing.T) {
+
+	t.Run("test that we are able to store a key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		createdAt := time.Now()
+		rows := sqlmock.NewRows(apiKeyColumns).AddRow(4, "mobile", 3, false, createdAt, nil)
+		mock.ExpectQuery("INSERT INTO").WithArgs("mobile", "hash", 3, false).WillReturnRows(rows)
+
+		keyRepo := repos.NewAPIKeyRepository(db)
+
+		key, err := keyRepo.Create(context.Background(), &obj.APIKey{Name: "mobile", UserID: 3}, "hash")
+
+		assert.NoError(t, err)
+		assert.Equal(t, &obj.APIKey{ID: 4, Name: "mobile", UserID: 3, CreatedAt: createdAt}, key)
+	})
+
+	t.Run("test that admin keys are stored without a user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		rows := sqlmock.NewRows(apiKeyColumns).AddRow(5, "ops", nil, true, time.Now(), nil)
+		mock.ExpectQuery("INSERT INTO").WithArgs("ops", "hash", nil, true).WillReturnRows(rows)
+
+		keyRepo := repos.NewAPIKeyRepository(db)
+
+		_, err = keyRepo.Create(context.Background(), &obj.APIKey{Name: "ops", Admin: true}, "hash")
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestAPIKeyRepository_Revoke(t *testing.T) {
+
+	t.Run("test that we are able to revoke a key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectExec("UPDATE (.+) SET revoked_at").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		keyRepo := repos.NewAPIKeyRepository(db)
+
+		ok, err := keyRepo.Revoke(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("test that revoked keys aren't found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectExec("UPDATE").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		keyRepo := repos.NewAPIKeyRepository(db)
+
+		_, err = keyRepo.Revoke(context.Background(), 1)
+
+		assert.True(t, errors.Is(err, repos.ErrNotFound), "unexpected error %v", err)
+	})
+}
@@ -0,0 +1,75 @@
+package repos_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/pedrorochaorg/contactsApi/db"
+	"github.com/pedrorochaorg/contactsApi/repos"
+	"github.com/pedrorochaorg/contactsApi/repos/repostest"
+)
+
+// testDatabaseEnv environment variable with the connection string of a Postgres database the conformance suite also
+// runs against, like "host=localhost port=5435 user=contacts password=secret dbname=contacts_test sslmode=disable".
+// The database is migrated and every table is emptied before each test, so it mustn't hold data worth keeping.
+const testDatabaseEnv = "CONTACTS_TEST_DATABASE"
+
+func TestMemoryStore_Conformance(t *testing.T) {
+	t.Run("users", func(t *testing.T) {
+		repostest.TestUserRepo(t, func(t *testing.T) repos.UserRepo {
+			return repos.NewMemoryStore().Users()
+		})
+	})
+
+	t.Run("contacts", func(t *testing.T) {
+		repostest.TestContactRepo(t, func(t *testing.T) (repos.UserRepo, repos.ContactRepo) {
+			store := repos.NewMemoryStore()
+			return store.Users(), store.Contacts()
+		})
+	})
+}
+
+func TestPostgres_Conformance(t *testing.T) {
+	dsn := os.Getenv(testDatabaseEnv)
+	if dsn == "" {
+		t.Skipf("%s isn't set", testDatabaseEnv)
+	}
+
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("error opening the test database: %s", err)
+	}
+	defer conn.Close()
+
+	err = db.NewMigrator(conn, db.Migrations).Up(context.Background())
+	if err != nil {
+		t.Fatalf("error migrating the test database: %s", err)
+	}
+
+	// empty truncates every table restarting the ids, the api keys are emptied by the cascade from the users
+	empty := func(t *testing.T) {
+		_, err := conn.Exec("TRUNCATE \"contactsApi\".\"users\", \"contactsApi\".\"contacts\" RESTART IDENTITY " +
+			"CASCADE")
+		if err != nil {
+			t.Fatalf("error emptying the test database: %s", err)
+		}
+	}
+
+	t.Run("users", func(t *testing.T) {
+		repostest.TestUserRepo(t, func(t *testing.T) repos.UserRepo {
+			empty(t)
+			users := repos.NewUserRepository(conn)
+			return &users
+		})
+	})
+
+	t.Run("contacts", func(t *testing.T) {
+		repostest.TestContactRepo(t, func(t *testing.T) (repos.UserRepo, repos.ContactRepo) {
+			empty(t)
+			users, contacts := repos.NewUserRepository(conn), repos.NewContactRepository(conn)
+			return &users, &contacts
+		})
+	})
+}
@@ -0,0 +1,53 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pedrorochaorg/contactsApi/tracing"
+)
+
+// Attributes added to the span of a repository call, see Traced
+const (
+	AttributeSystem    = "db.system"
+	AttributeStatement = "db.statement"
+	AttributeRows      = "db.rows"
+)
+
+// SystemPostgres value of the db.system attribute of the calls made to the Postgres repositories
+const SystemPostgres = "postgresql"
+
+// conn database connection of the repositories, the statements it runs are added to the span of the context
+type conn struct {
+	*sql.DB
+}
+
+func (c conn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	traceStatement(ctx, query)
+	return c.DB.QueryContext(ctx, query, args...)
+}
+
+func (c conn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	traceStatement(ctx, query)
+	return c.DB.QueryRowContext(ctx, query, args...)
+}
+
+func (c conn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	traceStatement(ctx, query)
+	return c.DB.ExecContext(ctx, query, args...)
+}
+
+// traceStatement adds the statement to the span of the context, the statements of calls that run more than one are
+// separated by semicolons
+func traceStatement(ctx context.Context, query string) {
+	span := tracing.SpanFromContext(ctx)
+	if previous, ok := span.Attribute(AttributeStatement).(string); ok && previous != "" {
+		query = previous + "; " + query
+	}
+	span.SetAttribute(AttributeStatement, query)
+}
+
+// traceRows adds the number of rows returned or changed by a call to the span of the context
+func traceRows(ctx context.Context, rows int) {
+	tracing.SpanFromContext(ctx).SetAttribute(AttributeRows, rows)
+}
@@ -0,0 +1,267 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/pedrorochaorg/contactsApi/obj"
+)
+
+// ContactRepo every method is scoped to the user that owns the contact, so a contact can only be reached through
+// the id of it's owner.
+type ContactRepo interface {
+	List(ctx context.Context, userID int, opts ListOptions) ([]obj.Contact, *PageInfo, error)
+	ListByUsers(ctx context.Context, userIDs []int) (map[int][]obj.Contact, error)
+	Create(ctx context.Context, userID int, contact *obj.Contact) (*obj.Contact, error)
+	Update(ctx context.Context, userID int, contact *obj.Contact) (*obj.Contact, error)
+	Patch(ctx context.Context, userID int, contact *obj.Contact, fields []string) (*obj.Contact, error)
+	Get(ctx context.Context, userID int, id int) (*obj.Contact, error)
+	Delete(ctx context.Context, userID int, id int, version int64) (bool, error)
+}
+
+type ContactRepository struct {
+	db conn
+}
+
+// NewContactRepository instantiates a new contact repository injecting the database connection interface as a
+// dependency
+func NewContactRepository(db *sql.DB) ContactRepository {
+	return ContactRepository{conn{db}}
+}
+
+// scanContact maps the current row of a contacts query into a contact struct
+func scanContact(rows *sql.Rows, contact *obj.Contact) error {
+	return rows.Scan(
+		&contact.ID,
+		&contact.UserID,
+		&contact.FirstName,
+		&contact.LastName,
+		&contact.Email,
+		&contact.Phone,
+		&contact.UpdatedAt,
+		&contact.CreatedAt,
+		&contact.Version)
+}
+
+// List return a page of the contacts that belong to a user, filtered and sorted according to the options
+func (c *ContactRepository) List(ctx context.Context, userID int, opts ListOptions) ([]obj.Contact, *PageInfo,
+	error) {
+	query := &selectQuery{table: "contacts"}
+	query.where = append(query.where, "user_id = "+query.arg(userID))
+	err := query.apply(contactColumns, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var total int
+	countQuery, countArgs := query.count()
+	err = c.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total)
+	if err != nil {
+		return nil, nil, wrapError("failed to count contacts in database", err)
+	}
+
+	pageQuery, pageArgs := query.page(opts)
+	rows, err := c.db.QueryContext(ctx, pageQuery, pageArgs...)
+	if err != nil {
+		return nil, nil, wrapError("failed to fetch contacts from database", err)
+	}
+
+	contacts := []obj.Contact{}
+
+	defer rows.Close()
+	for rows.Next() {
+		contact := obj.Contact{}
+		err = scanContact(rows, &contact)
+		if err != nil {
+			return nil, nil, wrapError("failed to map row to contact", err)
+		}
+		contacts = append(contacts, contact)
+	}
+
+	traceRows(ctx, len(contacts))
+	info := pageInfo(opts, total, len(contacts))
+	start, end := info.trim(opts, len(contacts))
+	contacts = contacts[start:end]
+
+	if len(contacts) > 0 {
+		first, last := contacts[0], contacts[len(contacts)-1]
+		info.setCursors(opts, Cursor{ID: int(first.ID), CreatedAt: first.CreatedAt},
+			Cursor{ID: int(last.ID), CreatedAt: last.CreatedAt})
+	}
+
+	return contacts, info, nil
+}
+
+// ListByUsers fetches the contacts of several users with a single query, returning them grouped by the id of the
+// user that owns them. Users without contacts won't have an entry in the resulting map.
+func (c *ContactRepository) ListByUsers(ctx context.Context, userIDs []int) (map[int][]obj.Contact, error) {
+	contacts := map[int][]obj.Contact{}
+
+	if len(userIDs) == 0 {
+		return contacts, nil
+	}
+
+	ids := make([]int64, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = int64(id)
+	}
+
+	rows, err := c.db.QueryContext(ctx, "SELECT * FROM \"contactsApi\".\"contacts\" WHERE user_id = ANY($1) "+
+		"ORDER BY id", pq.Array(ids))
+	if err != nil {
+		return nil, wrapError("failed to fetch contacts from database", err)
+	}
+
+	fetched := 0
+
+	defer rows.Close()
+	for rows.Next() {
+		contact := obj.Contact{}
+		err = scanContact(rows, &contact)
+		if err != nil {
+			return nil, wrapError("failed to map row to contact", err)
+		}
+		contacts[int(contact.UserID)] = append(contacts[int(contact.UserID)], contact)
+		fetched++
+	}
+
+	traceRows(ctx, fetched)
+	return contacts, nil
+}
+
+// Create creates a contact in database owned by the user identified by userID
+func (c *ContactRepository) Create(ctx context.Context, userID int, contact *obj.Contact) (*obj.Contact, error) {
+	rows, err := c.db.QueryContext(ctx, "INSERT INTO \"contactsApi\".\"contacts\"(user_id, \"firstName\", "+
+		"\"lastName\", \"email\", \"phone\") VALUES($1, $2, $3, $4, $5) RETURNING *",
+		userID, contact.FirstName, contact.LastName, contact.Email, contact.Phone)
+	if err != nil {
+		return nil, wrapError("failed to insert contact in database", err)
+	}
+
+	defer rows.Close()
+	err = nextRow(rows, "failed to insert contact in database")
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanContact(rows, contact)
+	if err != nil {
+		return nil, wrapError("failed to map row to contact", err)
+	}
+
+	traceRows(ctx, 1)
+	return contact, nil
+}
+
+// Update updates a contact only if it's owned by the user identified by userID, when contact.Version isn't zero the
+// contact is only updated while it's still at that version
+func (c *ContactRepository) Update(ctx context.Context, userID int, contact *obj.Contact) (*obj.Contact, error) {
+	rows, err := c.db.QueryContext(ctx, "UPDATE \"contactsApi\".\"contacts\" SET \"firstName\" = $1, "+
+		"\"lastName\" = $2, \"email\" = $3, \"phone\" = $4 WHERE id = $5 AND user_id = $6 AND "+
+		"($7::bigint = 0 OR version = $7) RETURNING *",
+		contact.FirstName, contact.LastName, contact.Email, contact.Phone, contact.ID, userID, contact.Version)
+	if err != nil {
+		return nil, wrapError("failed to update contact in database", err)
+	}
+
+	defer rows.Close()
+	err = nextRow(rows, "failed to update contact in database")
+	if err != nil {
+		return nil, conditional(err, contact.Version)
+	}
+
+	err = scanContact(rows, contact)
+	if err != nil {
+		return nil, wrapError("failed to map row to contact", err)
+	}
+
+	traceRows(ctx, 1)
+	return contact, nil
+}
+
+// Patch updates only the columns of the given fields, identified by their json names, of a contact owned by the
+// user identified by userID. Like Update it's conditional on contact.Version when it isn't zero.
+func (c *ContactRepository) Patch(ctx context.Context, userID int, contact *obj.Contact, fields []string) (*obj.Contact,
+	error) {
+	if len(fields) == 0 {
+		return c.Get(ctx, userID, int(contact.ID))
+	}
+
+	set, args, err := assignments(contactColumns, fields, map[string]interface{}{
+		"first_name": contact.FirstName,
+		"last_name":  contact.LastName,
+		"email":      contact.Email,
+		"phone":      contact.Phone,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	args = append(args, contact.ID, userID, contact.Version)
+	rows, err := c.db.QueryContext(ctx, fmt.Sprintf("UPDATE \"contactsApi\".\"contacts\" SET %s WHERE id = $%d "+
+		"AND user_id = $%d AND ($%d::bigint = 0 OR version = $%[4]d) RETURNING *", set, len(args)-2, len(args)-1,
+		len(args)), args...)
+	if err != nil {
+		return nil, wrapError("failed to patch contact in database", err)
+	}
+
+	defer rows.Close()
+	err = nextRow(rows, "failed to patch contact in database")
+	if err != nil {
+		return nil, conditional(err, contact.Version)
+	}
+
+	err = scanContact(rows, contact)
+	if err != nil {
+		return nil, wrapError("failed to map row to contact", err)
+	}
+
+	traceRows(ctx, 1)
+	return contact, nil
+}
+
+// Get fetches a contact only if it's owned by the user identified by userID
+func (c *ContactRepository) Get(ctx context.Context, userID int, id int) (*obj.Contact, error) {
+	rows, err := c.db.QueryContext(ctx, "SELECT * FROM \"contactsApi\".\"contacts\" WHERE id = $1 AND user_id = $2",
+		id, userID)
+	if err != nil {
+		return nil, wrapError("failed to fetch contact from database", err)
+	}
+
+	contact := &obj.Contact{}
+
+	defer rows.Close()
+	err = nextRow(rows, "failed to fetch contact from database")
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanContact(rows, contact)
+	if err != nil {
+		return nil, wrapError("failed to map row to contact", err)
+	}
+
+	traceRows(ctx, 1)
+	return contact, nil
+}
+
+// Delete deletes a contact only if it's owned by the user identified by userID, when version isn't zero the contact
+// is only deleted while it's still at that version
+func (c *ContactRepository) Delete(ctx context.Context, userID int, id int, version int64) (bool, error) {
+	rows, err := c.db.ExecContext(ctx, "DELETE FROM \"contactsApi\".\"contacts\" WHERE id = $1 AND user_id = $2 "+
+		"AND ($3::bigint = 0 OR version = $3)", id, userID, version)
+	if err != nil {
+		return false, wrapError("failed to delete contact from database", err)
+	}
+
+	err = affectedRow(rows, "failed to delete contact from database")
+	if err != nil {
+		return false, conditional(err, version)
+	}
+
+	traceRows(ctx, 1)
+	return true, nil
+}
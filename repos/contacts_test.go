@@ -0,0 +1,357 @@
+package repos_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/obj"
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+var contactColumns = []string{"id", "user_id", "firstName", "lastName", "email", "phone", "updated_at",
+	"created_at", "version"}
+
+func contactRow(rows *sqlmock.Rows, c obj.Contact) *sqlmock.Rows {
+	return rows.AddRow(c.ID, c.UserID, c.FirstName, c.LastName, c.Email, c.Phone, c.UpdatedAt, c.CreatedAt,
+		c.Version)
+}
+
+func TestContactRepository_List(t *testing.T) {
+
+	storedContacts := []obj.Contact{
+		{ID: 1, UserID: 1, FirstName: "Ana", LastName: "Silva", Email: "ana@example.com", Phone: "919236587",
+			CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: 2, UserID: 1, FirstName: "Rui", LastName: "Sousa", Email: "rui@example.com", Phone: "919236588",
+			CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	}
+
+	t.Run("test that we are able to obtain the list of contacts of a user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		rows := sqlmock.NewRows(contactColumns)
+		contactRow(rows, storedContacts[0])
+		contactRow(rows, storedContacts[1])
+
+		mock.ExpectQuery("SELECT COUNT(.+) WHERE user_id = \\$1").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery("SELECT (.+) WHERE user_id = \\$1").WithArgs(1, repos.DefaultLimit+1, 0).
+			WillReturnRows(rows)
+
+		contactRepo := repos.NewContactRepository(db)
+
+		contacts, page, err := contactRepo.List(context.Background(), 1, repos.ListOptions{})
+
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, contacts, storedContacts, "List's don't match")
+		assert.Equal(t, 2, page.Total, "Total doesn't match")
+	})
+
+	t.Run("test that we are able to handle errors returned by the method", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT COUNT").WillReturnError(fmt.Errorf("error"))
+
+		contactRepo := repos.NewContactRepository(db)
+
+		_, _, err = contactRepo.List(context.Background(), 1, repos.ListOptions{})
+
+		assert.Error(t, err, "should have returned an error")
+		assert.Contains(t, err.Error(), "error", "Error message doesn't match")
+	})
+
+	t.Run("test that we are able to handle errors returned by the method scan call", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		rows := sqlmock.NewRows(contactColumns).AddRow(nil, 1, "Ana", "Silva", "", "", time.Now(), time.Now(), 1)
+
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery("SELECT").WillReturnRows(rows)
+
+		contactRepo := repos.NewContactRepository(db)
+
+		_, _, err = contactRepo.List(context.Background(), 1, repos.ListOptions{})
+
+		assert.Error(t, err, "should have returned an error")
+		assert.Contains(t, err.Error(), "failed to map row to contact", "Error message doesn't match")
+	})
+}
+
+func TestContactRepository_ListByUsers(t *testing.T) {
+
+	t.Run("test that the contacts of several users are fetched with a single query", func(t *testing.T) {
+		storedContacts := []obj.Contact{
+			{ID: 1, UserID: 1, FirstName: "Ana", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+			{ID: 2, UserID: 3, FirstName: "Rui", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+			{ID: 3, UserID: 1, FirstName: "Eva", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		}
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		rows := sqlmock.NewRows(contactColumns)
+		for _, c := range storedContacts {
+			contactRow(rows, c)
+		}
+
+		mock.ExpectQuery("SELECT (.+) WHERE user_id = ANY\\(\\$1\\)").WillReturnRows(rows)
+
+		contactRepo := repos.NewContactRepository(db)
+
+		contacts, err := contactRepo.ListByUsers(context.Background(), []int{1, 2, 3})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, []obj.Contact{storedContacts[0], storedContacts[2]}, contacts[1])
+		assert.Equal(t, []obj.Contact{storedContacts[1]}, contacts[3])
+		assert.Empty(t, contacts[2])
+	})
+
+	t.Run("test that no query is executed when no users are given", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		contactRepo := repos.NewContactRepository(db)
+
+		contacts, err := contactRepo.ListByUsers(context.Background(), nil)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Empty(t, contacts)
+	})
+}
+
+func TestContactRepository_Create(t *testing.T) {
+
+	t.Run("test that we are able to call the method Create", func(t *testing.T) {
+		storedContact := obj.Contact{ID: 1, UserID: 2, FirstName: "Ana", LastName: "Silva",
+			Email: "ana@example.com", Phone: "919236587", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("INSERT").
+			WithArgs(2, "Ana", "Silva", "ana@example.com", "919236587").
+			WillReturnRows(contactRow(sqlmock.NewRows(contactColumns), storedContact))
+
+		contactRepo := repos.NewContactRepository(db)
+
+		contact, err := contactRepo.Create(context.Background(), 2, &obj.Contact{FirstName: "Ana",
+			LastName: "Silva", Email: "ana@example.com", Phone: "919236587"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, storedContact, *contact, "Results's don't match")
+	})
+
+	t.Run("test that we are able to handle a errors returned by the query execution", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("INSERT").WillReturnError(fmt.Errorf("error"))
+
+		contactRepo := repos.NewContactRepository(db)
+
+		_, err = contactRepo.Create(context.Background(), 2, &obj.Contact{FirstName: "Ana"})
+
+		assert.Error(t, err, "should have returned an error")
+		assert.Contains(t, err.Error(), "error", "Error message doesn't match")
+	})
+}
+
+func TestContactRepository_Update(t *testing.T) {
+
+	t.Run("test that the update is scoped to the owner of the contact", func(t *testing.T) {
+		storedContact := obj.Contact{ID: 3, UserID: 2, FirstName: "Ana", LastName: "Silva",
+			Email: "ana@example.com", Phone: "919236587", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("UPDATE (.+) WHERE id = \\$5 AND user_id = \\$6").
+			WithArgs("Ana", "Silva", "ana@example.com", "919236587", 3, 2, 0).
+			WillReturnRows(contactRow(sqlmock.NewRows(contactColumns), storedContact))
+
+		contactRepo := repos.NewContactRepository(db)
+
+		contact, err := contactRepo.Update(context.Background(), 2, &obj.Contact{ID: 3, FirstName: "Ana",
+			LastName: "Silva", Email: "ana@example.com", Phone: "919236587"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, storedContact, *contact, "Results's don't match")
+	})
+
+	t.Run("test that updating a contact owned by another user is reported as not found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("UPDATE").WillReturnRows(sqlmock.NewRows(contactColumns))
+
+		contactRepo := repos.NewContactRepository(db)
+
+		_, err = contactRepo.Update(context.Background(), 1, &obj.Contact{ID: 3})
+
+		assert.Error(t, err, "should have returned an error")
+		assert.True(t, errors.Is(err, repos.ErrNotFound), "should have returned a not found error")
+	})
+}
+
+func TestContactRepository_Patch(t *testing.T) {
+
+	t.Run("only the columns of the patched fields are updated", func(t *testing.T) {
+		storedContact := obj.Contact{ID: 3, UserID: 2, FirstName: "Ana", LastName: "Silva",
+			Email: "ana@example.com", Phone: "919236587", CreatedAt: time.Now(), UpdatedAt: time.Now(), Version: 5}
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("UPDATE (.+) SET \"email\" = \\$1, \"phone\" = \\$2 WHERE id = \\$3 AND user_id = \\$4 "+
+			"AND \\(\\$5::bigint = 0 OR version = \\$5\\)").
+			WithArgs("ana@example.com", "919236587", 3, 2, 4).
+			WillReturnRows(contactRow(sqlmock.NewRows(contactColumns), storedContact))
+
+		contactRepo := repos.NewContactRepository(db)
+
+		contact, err := contactRepo.Patch(context.Background(), 2, &obj.Contact{ID: 3, Email: "ana@example.com",
+			Phone: "919236587", Version: 4}, []string{"email", "phone"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, storedContact, *contact, "Results's don't match")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestContactRepository_Get(t *testing.T) {
+
+	t.Run("test that the lookup is scoped to the owner of the contact", func(t *testing.T) {
+		storedContact := obj.Contact{ID: 3, UserID: 2, FirstName: "Ana", LastName: "Silva",
+			Email: "ana@example.com", Phone: "919236587", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT (.+) WHERE id = \\$1 AND user_id = \\$2").
+			WithArgs(3, 2).
+			WillReturnRows(contactRow(sqlmock.NewRows(contactColumns), storedContact))
+
+		contactRepo := repos.NewContactRepository(db)
+
+		contact, err := contactRepo.Get(context.Background(), 2, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, storedContact, *contact, "Results's don't match")
+	})
+
+	t.Run("test that a contact owned by another user isn't returned", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT").WithArgs(3, 1).WillReturnRows(sqlmock.NewRows(contactColumns))
+
+		contactRepo := repos.NewContactRepository(db)
+
+		_, err = contactRepo.Get(context.Background(), 1, 3)
+
+		assert.Error(t, err, "should have returned an error")
+	})
+}
+
+func TestContactRepository_Delete(t *testing.T) {
+
+	t.Run("call the method Delete", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectExec("DELETE (.+) WHERE id = \\$1 AND user_id = \\$2").WithArgs(3, 2, 0).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		contactRepo := repos.NewContactRepository(db)
+
+		deleted, err := contactRepo.Delete(context.Background(), 2, 3, 0)
+
+		assert.NoError(t, err)
+		assert.True(t, deleted, "Should have returned a value of true")
+	})
+
+	t.Run("deleting a contact that changed since the expected version fails the precondition", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectExec("DELETE (.+) AND \\(\\$3::bigint = 0 OR version = \\$3\\)").WithArgs(3, 2, 4).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		contactRepo := repos.NewContactRepository(db)
+
+		deleted, err := contactRepo.Delete(context.Background(), 2, 3, 4)
+
+		assert.False(t, deleted)
+		assert.True(t, errors.Is(err, repos.ErrPreconditionFailed), "should have returned a precondition error")
+		assert.False(t, errors.Is(err, repos.ErrNotFound), "a changed contact isn't reported as missing")
+	})
+
+	t.Run("handle a error while executing the delete query", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectExec("DELETE").WithArgs(3, 2, 0).WillReturnError(fmt.Errorf("error"))
+
+		contactRepo := repos.NewContactRepository(db)
+
+		_, err = contactRepo.Delete(context.Background(), 2, 3, 0)
+
+		assert.Error(t, err, "should have returned an error")
+		assert.Contains(t, err.Error(), "error", "Error message doesn't match")
+	})
+}
@@ -0,0 +1,135 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/lib/pq"
+)
+
+// Kinds of failure reported by the repositories, use errors.Is to find out which one an error returned by a
+// repository belongs to. Errors that don't match any of them are unexpected failures.
+var (
+	// ErrNotFound the requested row doesn't exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict the operation conflicts with existing data, like a duplicated unique value
+	ErrConflict = errors.New("conflict")
+	// ErrValidation the values sent to the database, or the list options, are invalid
+	ErrValidation = errors.New("validation failed")
+	// ErrUnavailable the database couldn't be reached or didn't answer in time
+	ErrUnavailable = errors.New("database unavailable")
+	// ErrPreconditionFailed a conditional write didn't match the version of the row, it was changed or deleted since
+	// that version was read
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// Error failure of a repository operation, it wraps the underlying cause and reports the kind of failure through
+// errors.Is
+type Error struct {
+	Kind    error
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Message, e.Err)
+}
+
+// Unwrap returns the underlying cause
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error is of the target kind
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && e.Kind == target
+}
+
+// wrapError wraps a database error describing the operation that failed, the kind of failure is inferred from the
+// cause
+func wrapError(message string, err error) error {
+	return &Error{Kind: kindOf(err), Message: message, Err: err}
+}
+
+// kindOf classifies a database error, nil is returned for unexpected failures
+func kindOf(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return ErrUnavailable
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		// integrity constraint violation
+		case "23":
+			switch pqErr.Code.Name() {
+			case "unique_violation", "foreign_key_violation", "exclusion_violation":
+				return ErrConflict
+			}
+			return ErrValidation
+		// data exception, like a value too long for it's column
+		case "22":
+			return ErrValidation
+		// connection exception, insufficient resources and operator intervention, like an administrator shutdown
+		case "08", "53", "57":
+			return ErrUnavailable
+		}
+		return nil
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrUnavailable
+	}
+
+	return nil
+}
+
+// nextRow advances to the single row expected from a query, a query that returned no rows results in an ErrNotFound
+// error
+func nextRow(rows *sql.Rows, message string) error {
+	if rows.Next() {
+		return nil
+	}
+
+	err := rows.Err()
+	if err == nil {
+		err = sql.ErrNoRows
+	}
+
+	return wrapError(message, err)
+}
+
+// affectedRow verifies that a statement changed at least one row, reporting ErrNotFound otherwise
+func affectedRow(result sql.Result, message string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return wrapError("failed to obtain the number of affected rows", err)
+	}
+
+	if affected == 0 {
+		return wrapError(message, sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// conditional reports a conditional write, one whose expected version isn't zero, that matched no row as
+// ErrPreconditionFailed instead of ErrNotFound. Other errors are returned unchanged.
+func conditional(err error, version int64) error {
+	var repoErr *Error
+	if version == 0 || !errors.As(err, &repoErr) || repoErr.Kind != ErrNotFound {
+		return err
+	}
+
+	return &Error{Kind: ErrPreconditionFailed, Message: repoErr.Message,
+		Err: fmt.Errorf("the row isn't at version %d", version)}
+}
@@ -0,0 +1,64 @@
+package repos_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+func TestErrorKinds(t *testing.T) {
+
+	cases := []struct {
+		name  string
+		cause error
+		kind  error
+	}{
+		{"missing rows", sql.ErrNoRows, repos.ErrNotFound},
+		{"unique violation", &pq.Error{Code: "23505"}, repos.ErrConflict},
+		{"foreign key violation", &pq.Error{Code: "23503"}, repos.ErrConflict},
+		{"not null violation", &pq.Error{Code: "23502"}, repos.ErrValidation},
+		{"value too long", &pq.Error{Code: "22001"}, repos.ErrValidation},
+		{"administrator shutdown", &pq.Error{Code: "57P01"}, repos.ErrUnavailable},
+		{"connection failure", &pq.Error{Code: "08006"}, repos.ErrUnavailable},
+		{"deadline exceeded", context.DeadlineExceeded, repos.ErrUnavailable},
+		{"syntax error", &pq.Error{Code: "42601"}, nil},
+		{"unknown error", fmt.Errorf("boom"), nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error while opening a new database connection")
+			}
+			defer db.Close()
+
+			mock.ExpectQuery("SELECT").WillReturnError(c.cause)
+
+			userRepo := repos.NewUserRepository(db)
+
+			_, err = userRepo.Get(context.Background(), 1)
+
+			kinds := []error{repos.ErrNotFound, repos.ErrConflict, repos.ErrValidation, repos.ErrUnavailable}
+			for _, kind := range kinds {
+				assert.Equal(t, kind == c.kind, errors.Is(err, kind), "kind %s", kind)
+			}
+			assert.True(t, errors.Is(err, c.cause), "the cause should be wrapped")
+			assert.Contains(t, err.Error(), "failed to fetch users from database")
+		})
+	}
+
+	t.Run("field errors are validation failures", func(t *testing.T) {
+		var err error = repos.FieldErrors{{Field: "age", Reason: "unknown field"}}
+
+		assert.True(t, errors.Is(err, repos.ErrValidation))
+	})
+}
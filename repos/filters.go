@@ -0,0 +1,179 @@
+package repos
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Filter operators
+const (
+	Equal  = "eq"
+	After  = "after"
+	Before = "before"
+)
+
+// Filter restricts a listing to the rows whose field matches the value according to the operator. Field is the json
+// name of the field, the repositories map it to the matching column and reject fields that can't be filtered on.
+type Filter struct {
+	Field string
+	Op    string
+	Value string
+}
+
+// Sort orders a listing by the field identified by it's json name
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// FieldError describes a list parameter that was rejected by a repository
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// FieldErrors every list parameter that was rejected while building a query
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	reasons := make([]string, len(e))
+	for i, fieldError := range e {
+		reasons[i] = fmt.Sprintf("%s: %s", fieldError.Field, fieldError.Reason)
+	}
+	return "invalid list parameters: " + strings.Join(reasons, ", ")
+}
+
+// Is reports field errors as ErrValidation failures
+func (e FieldErrors) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// column a column that can be used to filter and sort a listing, writable columns can also be patched
+type column struct {
+	name      string
+	timestamp bool
+	writable  bool
+}
+
+// userColumns allow-list of the users fields that can be used in filters, sorts and patches
+var userColumns = map[string]column{
+	"first_name": {name: "\"firstName\"", writable: true},
+	"last_name":  {name: "\"lastName\"", writable: true},
+	"created_at": {name: "created_at", timestamp: true},
+	"updated_at": {name: "updated_at", timestamp: true},
+}
+
+// contactColumns allow-list of the contacts fields that can be used in filters, sorts and patches
+var contactColumns = map[string]column{
+	"first_name": {name: "\"firstName\"", writable: true},
+	"last_name":  {name: "\"lastName\"", writable: true},
+	"email":      {name: "\"email\"", writable: true},
+	"phone":      {name: "\"phone\"", writable: true},
+	"created_at": {name: "created_at", timestamp: true},
+	"updated_at": {name: "updated_at", timestamp: true},
+}
+
+// condition a filter accepted by the columns allow-list, the value of the after and before operators is already
+// parsed into a time.Time
+type condition struct {
+	field  string
+	column column
+	op     string
+	value  interface{}
+}
+
+// check validates the filters and sort of the options against the columns allow-list, returning the conditions the
+// rows must match. All the rejected parameters are reported at once.
+func check(columns map[string]column, opts ListOptions) ([]condition, error) {
+	errs := FieldErrors{}
+	conditions := []condition{}
+
+	for _, filter := range opts.Filters {
+		col, ok := columns[filter.Field]
+		if !ok {
+			errs = append(errs, FieldError{Field: filter.Field, Reason: "filtering by this field isn't supported"})
+			continue
+		}
+
+		if filter.Op == Equal {
+			conditions = append(conditions, condition{field: filter.Field, column: col, op: Equal,
+				value: filter.Value})
+			continue
+		}
+
+		if !col.timestamp || (filter.Op != After && filter.Op != Before) {
+			errs = append(errs, FieldError{Field: filter.Field,
+				Reason: fmt.Sprintf("the '%s' operator isn't supported by this field", filter.Op)})
+			continue
+		}
+
+		value, err := time.Parse(time.RFC3339, filter.Value)
+		if err != nil {
+			errs = append(errs, FieldError{Field: filter.Field, Reason: "the value must be a RFC 3339 date"})
+			continue
+		}
+		conditions = append(conditions, condition{field: filter.Field, column: col, op: filter.Op, value: value})
+	}
+
+	for _, sort := range opts.Sort {
+		if _, ok := columns[sort.Field]; !ok {
+			errs = append(errs, FieldError{Field: sort.Field, Reason: "sorting by this field isn't supported"})
+		}
+	}
+
+	if len(opts.Sort) > 0 && opts.Cursor != nil {
+		errs = append(errs, FieldError{Field: "cursor", Reason: "cursors can't be combined with a custom sort"})
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return conditions, nil
+}
+
+// apply adds the filters and sort of the options to the query, only fields present in the columns allow-list are
+// accepted and every value is sent as a query argument
+func (q *selectQuery) apply(columns map[string]column, opts ListOptions) error {
+	conditions, err := check(columns, opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.IDs != nil {
+		ids := make([]int64, len(opts.IDs))
+		for i, id := range opts.IDs {
+			ids[i] = int64(id)
+		}
+		q.where = append(q.where, "id = ANY("+q.arg(pq.Array(ids))+")")
+	}
+
+	for _, cond := range conditions {
+		switch cond.op {
+		case Equal:
+			q.where = append(q.where, cond.column.name+" = "+q.arg(cond.value))
+		case After:
+			q.where = append(q.where, cond.column.name+" > "+q.arg(cond.value))
+		case Before:
+			q.where = append(q.where, cond.column.name+" < "+q.arg(cond.value))
+		}
+	}
+
+	if len(opts.Sort) > 0 {
+		order := make([]string, len(opts.Sort))
+		for i, sort := range opts.Sort {
+			order[i] = columns[sort.Field].name
+			if sort.Desc {
+				order[i] += " DESC"
+			}
+		}
+
+		// id is always the last sort criteria so that rows with the same values have a stable order across pages
+		q.order = strings.Join(append(order, "id"), ", ")
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package repos_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+func TestUserRepository_ListFilters(t *testing.T) {
+
+	userColumns := []string{"id", "firstName", "lastName", "updated_at", "created_at"}
+
+	t.Run("test that filters are sent as arguments of the mapped columns", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		createdAfter, _ := time.Parse(time.RFC3339, "2019-11-22T10:00:00Z")
+
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM \"contactsApi\".\"users\" WHERE \"firstName\" = \\$1 AND "+
+			"created_at > \\$2").
+			WithArgs("John", createdAfter).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery("WHERE \"firstName\" = \\$1 AND created_at > \\$2 ORDER BY created_at, id").
+			WithArgs("John", createdAfter, repos.DefaultLimit+1, 0).
+			WillReturnRows(sqlmock.NewRows(userColumns))
+
+		userRepo := repos.NewUserRepository(db)
+
+		_, _, err = userRepo.List(context.Background(), repos.ListOptions{Filters: []repos.Filter{
+			{Field: "first_name", Op: repos.Equal, Value: "John"},
+			{Field: "created_at", Op: repos.After, Value: "2019-11-22T10:00:00Z"},
+		}})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("test that sort fields are mapped to columns with id as the last criteria", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery("ORDER BY created_at DESC, \"lastName\", id LIMIT").
+			WillReturnRows(sqlmock.NewRows(userColumns))
+
+		userRepo := repos.NewUserRepository(db)
+
+		_, page, err := userRepo.List(context.Background(), repos.ListOptions{Sort: []repos.Sort{
+			{Field: "created_at", Desc: true},
+			{Field: "last_name"},
+		}})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Nil(t, page.Next, "A custom sort shouldn't return cursors")
+	})
+
+	t.Run("test that every invalid parameter is reported without querying the database", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		userRepo := repos.NewUserRepository(db)
+
+		_, _, err = userRepo.List(context.Background(), repos.ListOptions{
+			Cursor: &repos.Cursor{ID: 1},
+			Filters: []repos.Filter{
+				{Field: "password", Op: repos.Equal, Value: "secret"},
+				{Field: "first_name", Op: repos.After, Value: "John"},
+				{Field: "updated_at", Op: repos.Before, Value: "yesterday"},
+			},
+			Sort: []repos.Sort{{Field: "id; DROP TABLE users"}},
+		})
+
+		fieldErrors, ok := err.(repos.FieldErrors)
+
+		assert.True(t, ok, "should have returned the field errors")
+		assert.Len(t, fieldErrors, 5)
+		assert.Equal(t, "password", fieldErrors[0].Field)
+		assert.Equal(t, "first_name", fieldErrors[1].Field)
+		assert.Equal(t, "updated_at", fieldErrors[2].Field)
+		assert.Equal(t, "id; DROP TABLE users", fieldErrors[3].Field)
+		assert.Equal(t, "cursor", fieldErrors[4].Field)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("test that the ids restrict the listing before the filters", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT COUNT(.+) WHERE id = ANY\\(\\$1\\) AND \"firstName\" = \\$2").
+			WithArgs("{1}", "John").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery("WHERE id = ANY\\(\\$1\\) AND \"firstName\" = \\$2 ORDER BY created_at, id").
+			WithArgs("{1}", "John", repos.DefaultLimit+1, 0).
+			WillReturnRows(sqlmock.NewRows(userColumns))
+
+		userRepo := repos.NewUserRepository(db)
+
+		_, _, err = userRepo.List(context.Background(), repos.ListOptions{
+			Filters: []repos.Filter{{Field: "first_name", Op: repos.Equal, Value: "John"}},
+			IDs:     []int{1},
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("test that clients can't filter by id", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		userRepo := repos.NewUserRepository(db)
+
+		_, _, err = userRepo.List(context.Background(), repos.ListOptions{
+			Filters: []repos.Filter{{Field: "id", Op: repos.Equal, Value: "abc"}},
+		})
+
+		assert.Equal(t, repos.FieldErrors{{Field: "id", Reason: "filtering by this field isn't supported"}}, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestContactRepository_ListFilters(t *testing.T) {
+
+	t.Run("test that filters are added after the owner condition", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT COUNT(.+) WHERE user_id = \\$1 AND \"email\" = \\$2").
+			WithArgs(1, "ana@example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery("WHERE user_id = \\$1 AND \"email\" = \\$2 ORDER BY \"phone\" DESC, id").
+			WithArgs(1, "ana@example.com", repos.DefaultLimit+1, 0).
+			WillReturnRows(sqlmock.NewRows(contactColumns))
+
+		contactRepo := repos.NewContactRepository(db)
+
+		_, _, err = contactRepo.List(context.Background(), 1, repos.ListOptions{
+			Filters: []repos.Filter{{Field: "email", Op: repos.Equal, Value: "ana@example.com"}},
+			Sort:    []repos.Sort{{Field: "phone", Desc: true}},
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
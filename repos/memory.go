@@ -0,0 +1,655 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pedrorochaorg/contactsApi/obj"
+)
+
+// maxLength length of the varchar columns of the schema, longer values are rejected like Postgres does
+const maxLength = 90
+
+// MemoryStore keeps users, contacts and api keys in memory with the same behaviour as the Postgres repositories: ids
+// are assigned in sequence and never reused, every write stamps updated_at and increases the version of the row,
+// deleting a user deletes it's contacts and api keys and missing rows are reported as ErrNotFound. It's safe for
+// concurrent use and meant for tests and demos, everything is lost once the process exits.
+//
+// Text is compared by it's bytes while Postgres uses the collation of the database, which usually ignores case and
+// accents at first and sorts "ana" and "Álvaro" before "Bruno". Listings sorted by names, emails or phones can
+// therefore return another order, and other pages, than Postgres when the values mix letter cases or aren't ASCII.
+// Dates and ids, which the cursors and the range filters use, compare the same.
+type MemoryStore struct {
+	mu  sync.RWMutex
+	now func() time.Time
+
+	users    map[int]obj.User
+	contacts map[int]obj.Contact
+	keys     map[int]memoryKey
+
+	lastUserID    int
+	lastContactID int
+	lastKeyID     int
+}
+
+// memoryKey an api key stored with the hash it's looked up by
+type memoryKey struct {
+	key  obj.APIKey
+	hash string
+}
+
+// MemoryOption configures the MemoryStore
+type MemoryOption func(s *MemoryStore)
+
+// WithMemoryClock stamps the rows with the time returned by now instead of the current time
+func WithMemoryClock(now func() time.Time) MemoryOption {
+	return func(s *MemoryStore) {
+		s.now = now
+	}
+}
+
+// NewMemoryStore instantiates an empty store
+func NewMemoryStore(opts ...MemoryOption) *MemoryStore {
+	store := &MemoryStore{
+		now:      time.Now,
+		users:    map[int]obj.User{},
+		contacts: map[int]obj.Contact{},
+		keys:     map[int]memoryKey{},
+	}
+	for _, opt := range opts {
+		opt(store)
+	}
+	return store
+}
+
+// Users returns the UserRepo backed by the store
+func (s *MemoryStore) Users() UserRepo {
+	return &memoryUsers{s}
+}
+
+// Contacts returns the ContactRepo backed by the store
+func (s *MemoryStore) Contacts() ContactRepo {
+	return &memoryContacts{s}
+}
+
+// APIKeys returns the APIKeyRepo backed by the store
+func (s *MemoryStore) APIKeys() APIKeyRepo {
+	return &memoryAPIKeys{s}
+}
+
+// timestamp returns the time rows are stamped with, with the microsecond precision of the Postgres timestamps
+func (s *MemoryStore) timestamp() time.Time {
+	return s.now().UTC().Truncate(time.Microsecond)
+}
+
+// checkLength rejects values longer than the varchar columns with an ErrValidation error
+func checkLength(message string, values ...string) error {
+	for _, value := range values {
+		if utf8.RuneCountInString(value) > maxLength {
+			return &Error{Kind: ErrValidation, Message: message,
+				Err: fmt.Errorf("value too long for type character varying(%d)", maxLength)}
+		}
+	}
+	return nil
+}
+
+// checkVersion reports a row that isn't at the expected version like a conditional statement that matched no row,
+// a zero version matches every row
+func checkVersion(message string, current, expected int64) error {
+	if expected != 0 && current != expected {
+		return conditional(wrapError(message, sql.ErrNoRows), expected)
+	}
+	return nil
+}
+
+// memoryUsers UserRepo backed by a MemoryStore
+type memoryUsers struct {
+	store *MemoryStore
+}
+
+func (m *memoryUsers) List(ctx context.Context, opts ListOptions) ([]obj.User, *PageInfo, error) {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+
+	rows := make([]memoryRow, 0, len(m.store.users))
+	for _, user := range m.store.users {
+		rows = append(rows, memoryRow{id: user.ID, createdAt: user.CreatedAt, values: map[string]interface{}{
+			"first_name": user.FirstName,
+			"last_name":  user.LastName,
+			"created_at": user.CreatedAt,
+			"updated_at": user.UpdatedAt,
+		}})
+	}
+
+	ids, info, err := listRows(rows, userColumns, opts, "failed to fetch users")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	users := make([]obj.User, len(ids))
+	for i, id := range ids {
+		users[i] = m.store.users[id]
+	}
+	return users, info, nil
+}
+
+func (m *memoryUsers) Create(ctx context.Context, user *obj.User) (*obj.User, error) {
+	err := checkLength("failed to insert user", user.FirstName, user.LastName)
+	if err != nil {
+		return nil, err
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	m.store.lastUserID++
+	now := m.store.timestamp()
+	stored := obj.User{ID: m.store.lastUserID, FirstName: user.FirstName, LastName: user.LastName, CreatedAt: now,
+		UpdatedAt: now, Version: 1}
+	m.store.users[stored.ID] = stored
+
+	return copyUser(user, stored), nil
+}
+
+func (m *memoryUsers) Update(ctx context.Context, user *obj.User) (*obj.User, error) {
+	return m.Patch(ctx, user, []string{"first_name", "last_name"})
+}
+
+func (m *memoryUsers) Patch(ctx context.Context, user *obj.User, fields []string) (*obj.User, error) {
+	if len(fields) == 0 {
+		return m.Get(ctx, user.ID)
+	}
+
+	err := writable(userColumns, fields)
+	if err != nil {
+		return nil, err
+	}
+	err = checkLength("failed to update user", user.FirstName, user.LastName)
+	if err != nil {
+		return nil, err
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	stored, ok := m.store.users[user.ID]
+	if !ok {
+		return nil, conditional(wrapError("failed to update user", sql.ErrNoRows), user.Version)
+	}
+	err = checkVersion("failed to update user", stored.Version, user.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range fields {
+		switch field {
+		case "first_name":
+			stored.FirstName = user.FirstName
+		case "last_name":
+			stored.LastName = user.LastName
+		}
+	}
+	stored.UpdatedAt = m.store.timestamp()
+	stored.Version++
+	m.store.users[stored.ID] = stored
+
+	return copyUser(user, stored), nil
+}
+
+func (m *memoryUsers) Get(ctx context.Context, id int) (*obj.User, error) {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+
+	stored, ok := m.store.users[id]
+	if !ok {
+		return nil, wrapError("failed to fetch user", sql.ErrNoRows)
+	}
+	return &stored, nil
+}
+
+func (m *memoryUsers) Delete(ctx context.Context, id int, version int64) (bool, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	stored, ok := m.store.users[id]
+	if !ok {
+		return false, conditional(wrapError("failed to delete user", sql.ErrNoRows), version)
+	}
+	err := checkVersion("failed to delete user", stored.Version, version)
+	if err != nil {
+		return false, err
+	}
+
+	// The contacts and api keys of the user are deleted with it, like the ON DELETE CASCADE of their foreign keys
+	delete(m.store.users, id)
+	for contactID, contact := range m.store.contacts {
+		if int(contact.UserID) == id {
+			delete(m.store.contacts, contactID)
+		}
+	}
+	for keyID, key := range m.store.keys {
+		if key.key.UserID == id {
+			delete(m.store.keys, keyID)
+		}
+	}
+
+	return true, nil
+}
+
+// copyUser copies the columns of the stored user to the user given by the caller, leaving the contacts untouched
+func copyUser(user *obj.User, stored obj.User) *obj.User {
+	stored.Contacts = user.Contacts
+	*user = stored
+	return user
+}
+
+// memoryContacts ContactRepo backed by a MemoryStore
+type memoryContacts struct {
+	store *MemoryStore
+}
+
+func (m *memoryContacts) List(ctx context.Context, userID int, opts ListOptions) ([]obj.Contact, *PageInfo,
+	error) {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+
+	rows := []memoryRow{}
+	for _, contact := range m.store.contacts {
+		if int(contact.UserID) != userID {
+			continue
+		}
+		rows = append(rows, memoryRow{id: int(contact.ID), createdAt: contact.CreatedAt,
+			values: map[string]interface{}{
+				"first_name": contact.FirstName,
+				"last_name":  contact.LastName,
+				"email":      contact.Email,
+				"phone":      contact.Phone,
+				"created_at": contact.CreatedAt,
+				"updated_at": contact.UpdatedAt,
+			}})
+	}
+
+	ids, info, err := listRows(rows, contactColumns, opts, "failed to fetch contacts")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contacts := make([]obj.Contact, len(ids))
+	for i, id := range ids {
+		contacts[i] = m.store.contacts[id]
+	}
+	return contacts, info, nil
+}
+
+func (m *memoryContacts) ListByUsers(ctx context.Context, userIDs []int) (map[int][]obj.Contact, error) {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+
+	wanted := make(map[int]bool, len(userIDs))
+	for _, id := range userIDs {
+		wanted[id] = true
+	}
+
+	found := []obj.Contact{}
+	for _, contact := range m.store.contacts {
+		if wanted[int(contact.UserID)] {
+			found = append(found, contact)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].ID < found[j].ID
+	})
+
+	contacts := map[int][]obj.Contact{}
+	for _, contact := range found {
+		contacts[int(contact.UserID)] = append(contacts[int(contact.UserID)], contact)
+	}
+	return contacts, nil
+}
+
+func (m *memoryContacts) Create(ctx context.Context, userID int, contact *obj.Contact) (*obj.Contact, error) {
+	err := checkLength("failed to insert contact", contact.FirstName, contact.LastName, contact.Email, contact.Phone)
+	if err != nil {
+		return nil, err
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	if _, ok := m.store.users[userID]; !ok {
+		return nil, &Error{Kind: ErrConflict, Message: "failed to insert contact",
+			Err: fmt.Errorf("user %d doesn't exist", userID)}
+	}
+
+	m.store.lastContactID++
+	now := m.store.timestamp()
+	*contact = obj.Contact{ID: int64(m.store.lastContactID), UserID: int64(userID), FirstName: contact.FirstName,
+		LastName: contact.LastName, Email: contact.Email, Phone: contact.Phone, CreatedAt: now, UpdatedAt: now,
+		Version: 1}
+	m.store.contacts[int(contact.ID)] = *contact
+
+	return contact, nil
+}
+
+func (m *memoryContacts) Update(ctx context.Context, userID int, contact *obj.Contact) (*obj.Contact, error) {
+	return m.Patch(ctx, userID, contact, []string{"first_name", "last_name", "email", "phone"})
+}
+
+func (m *memoryContacts) Patch(ctx context.Context, userID int, contact *obj.Contact, fields []string) (*obj.Contact,
+	error) {
+	if len(fields) == 0 {
+		return m.Get(ctx, userID, int(contact.ID))
+	}
+
+	err := writable(contactColumns, fields)
+	if err != nil {
+		return nil, err
+	}
+	err = checkLength("failed to update contact", contact.FirstName, contact.LastName, contact.Email, contact.Phone)
+	if err != nil {
+		return nil, err
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	stored, ok := m.store.contacts[int(contact.ID)]
+	if !ok || int(stored.UserID) != userID {
+		return nil, conditional(wrapError("failed to update contact", sql.ErrNoRows), contact.Version)
+	}
+	err = checkVersion("failed to update contact", stored.Version, contact.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range fields {
+		switch field {
+		case "first_name":
+			stored.FirstName = contact.FirstName
+		case "last_name":
+			stored.LastName = contact.LastName
+		case "email":
+			stored.Email = contact.Email
+		case "phone":
+			stored.Phone = contact.Phone
+		}
+	}
+	stored.UpdatedAt = m.store.timestamp()
+	stored.Version++
+	m.store.contacts[int(stored.ID)] = stored
+
+	*contact = stored
+	return contact, nil
+}
+
+func (m *memoryContacts) Get(ctx context.Context, userID int, id int) (*obj.Contact, error) {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+
+	stored, ok := m.store.contacts[id]
+	if !ok || int(stored.UserID) != userID {
+		return nil, wrapError("failed to fetch contact", sql.ErrNoRows)
+	}
+	return &stored, nil
+}
+
+func (m *memoryContacts) Delete(ctx context.Context, userID int, id int, version int64) (bool, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	stored, ok := m.store.contacts[id]
+	if !ok || int(stored.UserID) != userID {
+		return false, conditional(wrapError("failed to delete contact", sql.ErrNoRows), version)
+	}
+	err := checkVersion("failed to delete contact", stored.Version, version)
+	if err != nil {
+		return false, err
+	}
+
+	delete(m.store.contacts, id)
+	return true, nil
+}
+
+// memoryAPIKeys APIKeyRepo backed by a MemoryStore
+type memoryAPIKeys struct {
+	store *MemoryStore
+}
+
+func (m *memoryAPIKeys) Create(ctx context.Context, key *obj.APIKey, hash string) (*obj.APIKey, error) {
+	err := checkLength("failed to insert api key", key.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	for _, stored := range m.store.keys {
+		if stored.hash == hash {
+			return nil, &Error{Kind: ErrConflict, Message: "failed to insert api key",
+				Err: fmt.Errorf("an api key with the same hash already exists")}
+		}
+	}
+	if _, ok := m.store.users[key.UserID]; key.UserID != 0 && !ok {
+		return nil, &Error{Kind: ErrConflict, Message: "failed to insert api key",
+			Err: fmt.Errorf("user %d doesn't exist", key.UserID)}
+	}
+
+	m.store.lastKeyID++
+	*key = obj.APIKey{ID: m.store.lastKeyID, Name: key.Name, UserID: key.UserID, Admin: key.Admin,
+		CreatedAt: m.store.timestamp()}
+	m.store.keys[key.ID] = memoryKey{key: *key, hash: hash}
+
+	return key, nil
+}
+
+func (m *memoryAPIKeys) GetByHash(ctx context.Context, hash string) (*obj.APIKey, error) {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+
+	for _, stored := range m.store.keys {
+		if stored.hash == hash && stored.key.RevokedAt == nil {
+			key := stored.key
+			return &key, nil
+		}
+	}
+	return nil, wrapError("failed to fetch api key", sql.ErrNoRows)
+}
+
+func (m *memoryAPIKeys) Revoke(ctx context.Context, id int) (bool, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	stored, ok := m.store.keys[id]
+	if !ok || stored.key.RevokedAt != nil {
+		return false, wrapError("failed to revoke api key", sql.ErrNoRows)
+	}
+
+	now := m.store.timestamp()
+	stored.key.RevokedAt = &now
+	m.store.keys[id] = stored
+	return true, nil
+}
+
+// memoryRow a stored row as seen by a listing, values holds the value of every field of the columns allow-list as
+// an int, a string or a time.Time
+type memoryRow struct {
+	id        int
+	createdAt time.Time
+	values    map[string]interface{}
+}
+
+// listRows returns the ids of the rows of the page described by the options, following the same steps as the
+// statements built by selectQuery so that both return the same pages, cursors included
+func listRows(rows []memoryRow, columns map[string]column, opts ListOptions, message string) ([]int, *PageInfo,
+	error) {
+	conditions, err := check(columns, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var ids map[int]bool
+	if opts.IDs != nil {
+		ids = make(map[int]bool, len(opts.IDs))
+		for _, id := range opts.IDs {
+			ids[id] = true
+		}
+	}
+
+	matching := []memoryRow{}
+	for _, row := range rows {
+		if ids != nil && !ids[row.id] {
+			continue
+		}
+
+		matches, err := row.matches(conditions)
+		if err != nil {
+			return nil, nil, &Error{Kind: ErrValidation, Message: message, Err: err}
+		}
+		if matches {
+			matching = append(matching, row)
+		}
+	}
+	total := len(matching)
+
+	if len(opts.Sort) > 0 {
+		sort.Slice(matching, func(i, j int) bool {
+			for _, s := range opts.Sort {
+				if c := compareValues(matching[i].values[s.Field], matching[j].values[s.Field]); c != 0 {
+					return (c < 0) != s.Desc
+				}
+			}
+			return matching[i].id < matching[j].id
+		})
+	} else {
+		sort.Slice(matching, func(i, j int) bool {
+			return matching[i].before(matching[j].createdAt, matching[j].id)
+		})
+	}
+
+	limit := opts.limit() + 1
+	var fetched []memoryRow
+
+	switch {
+	case opts.Cursor == nil:
+		if opts.Offset < len(matching) {
+			fetched = matching[opts.Offset:]
+		}
+		if len(fetched) > limit {
+			fetched = fetched[:limit]
+		}
+	case opts.Cursor.Before:
+		// The rows closest to the cursor are kept by the limit, like the statement that fetches them in reverse order
+		end := sort.Search(len(matching), func(i int) bool {
+			return !matching[i].before(opts.Cursor.CreatedAt, opts.Cursor.ID)
+		})
+		start := end - limit
+		if start < 0 {
+			start = 0
+		}
+		fetched = matching[start:end]
+	default:
+		start := sort.Search(len(matching), func(i int) bool {
+			return matching[i].after(opts.Cursor.CreatedAt, opts.Cursor.ID)
+		})
+		fetched = matching[start:]
+		if len(fetched) > limit {
+			fetched = fetched[:limit]
+		}
+	}
+
+	info := pageInfo(opts, total, len(fetched))
+	start, end := info.trim(opts, len(fetched))
+	fetched = fetched[start:end]
+
+	page := make([]int, len(fetched))
+	for i, row := range fetched {
+		page[i] = row.id
+	}
+
+	if len(fetched) > 0 {
+		first, last := fetched[0], fetched[len(fetched)-1]
+		info.setCursors(opts, Cursor{ID: first.id, CreatedAt: first.createdAt},
+			Cursor{ID: last.id, CreatedAt: last.createdAt})
+	}
+
+	return page, info, nil
+}
+
+// before reports whether the row comes before the position in the default order, by creation date and id
+func (r memoryRow) before(createdAt time.Time, id int) bool {
+	return r.createdAt.Before(createdAt) || (r.createdAt.Equal(createdAt) && r.id < id)
+}
+
+// after reports whether the row comes after the position in the default order, by creation date and id
+func (r memoryRow) after(createdAt time.Time, id int) bool {
+	return r.createdAt.After(createdAt) || (r.createdAt.Equal(createdAt) && r.id > id)
+}
+
+// matches reports whether the row matches every condition, values of equality filters that can't be converted to
+// the type of their field are reported as errors like Postgres does
+func (r memoryRow) matches(conditions []condition) (bool, error) {
+	for _, cond := range conditions {
+		value := r.values[cond.field]
+
+		expected := cond.value
+		if cond.op == Equal {
+			var err error
+			expected, err = convertValue(value, cond.value.(string))
+			if err != nil {
+				return false, fmt.Errorf("invalid value for the %s filter: %s", cond.field, err)
+			}
+		}
+
+		c := compareValues(value, expected)
+		if (cond.op == Equal && c != 0) || (cond.op == After && c <= 0) || (cond.op == Before && c >= 0) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// convertValue converts the value of a filter to the type of the field it's compared with
+func convertValue(field interface{}, value string) (interface{}, error) {
+	switch field.(type) {
+	case int:
+		return strconv.Atoi(value)
+	case time.Time:
+		return time.Parse(time.RFC3339, value)
+	default:
+		return value, nil
+	}
+}
+
+// compareValues returns a negative number when a comes before b, a positive one when it comes after and zero when
+// they're equal. Both values must have the same type, strings are compared by their bytes and not by a collation
+// like Postgres does, see MemoryStore.
+func compareValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case int:
+		return a - b.(int)
+	case time.Time:
+		switch b := b.(time.Time); {
+		case a.Before(b):
+			return -1
+		case a.After(b):
+			return 1
+		}
+		return 0
+	case string:
+		switch b := b.(string); {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	}
+	return 0
+}
@@ -0,0 +1,320 @@
+package repos_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/obj"
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+// clock returns a clock that starts at the given time and moves a second forward every time it's read
+func clock(start time.Time) func() time.Time {
+	mu := sync.Mutex{}
+	now := start
+	return func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(time.Second)
+		return now
+	}
+}
+
+func TestMemoryStore_Users(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("test that users are created with sequential ids, timestamps and the first version", func(t *testing.T) {
+		users := repos.NewMemoryStore(repos.WithMemoryClock(clock(start))).Users()
+
+		first, err := users.Create(ctx, &obj.User{FirstName: "Pedro", LastName: "Rocha"})
+		assert.NoError(t, err)
+		second, err := users.Create(ctx, &obj.User{FirstName: "Ana", LastName: "Silva"})
+		assert.NoError(t, err)
+
+		created := start.Add(time.Second)
+		assert.Equal(t, &obj.User{ID: 1, FirstName: "Pedro", LastName: "Rocha", CreatedAt: created,
+			UpdatedAt: created, Version: 1}, first)
+		assert.Equal(t, 2, second.ID)
+
+		found, err := users.Get(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, first, found)
+	})
+
+	t.Run("test that updates stamp the user and increase it's version", func(t *testing.T) {
+		users := repos.NewMemoryStore(repos.WithMemoryClock(clock(start))).Users()
+		_, _ = users.Create(ctx, &obj.User{FirstName: "Pedro", LastName: "Rocha"})
+
+		updated, err := users.Update(ctx, &obj.User{ID: 1, FirstName: "Ana", LastName: "Silva", Version: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, &obj.User{ID: 1, FirstName: "Ana", LastName: "Silva", CreatedAt: start.Add(time.Second),
+			UpdatedAt: start.Add(2 * time.Second), Version: 2}, updated)
+
+		patched, err := users.Patch(ctx, &obj.User{ID: 1, FirstName: "Maria", LastName: "ignored"},
+			[]string{"first_name"})
+		assert.NoError(t, err)
+		assert.Equal(t, "Maria", patched.FirstName)
+		assert.Equal(t, "Silva", patched.LastName)
+		assert.Equal(t, int64(3), patched.Version)
+	})
+
+	t.Run("test that writes at an outdated version fail their precondition", func(t *testing.T) {
+		users := repos.NewMemoryStore().Users()
+		_, _ = users.Create(ctx, &obj.User{FirstName: "Pedro", LastName: "Rocha"})
+		_, _ = users.Update(ctx, &obj.User{ID: 1, FirstName: "Ana", LastName: "Silva"})
+
+		_, err := users.Update(ctx, &obj.User{ID: 1, FirstName: "Maria", LastName: "Silva", Version: 1})
+		assert.True(t, errors.Is(err, repos.ErrPreconditionFailed))
+
+		_, err = users.Delete(ctx, 1, 1)
+		assert.True(t, errors.Is(err, repos.ErrPreconditionFailed))
+
+		_, err = users.Delete(ctx, 2, 1)
+		assert.True(t, errors.Is(err, repos.ErrPreconditionFailed))
+	})
+
+	t.Run("test that missing users aren't found", func(t *testing.T) {
+		users := repos.NewMemoryStore().Users()
+
+		_, err := users.Get(ctx, 1)
+		assert.True(t, errors.Is(err, repos.ErrNotFound))
+
+		_, err = users.Update(ctx, &obj.User{ID: 1, FirstName: "Pedro"})
+		assert.True(t, errors.Is(err, repos.ErrNotFound))
+
+		deleted, err := users.Delete(ctx, 1, 0)
+		assert.False(t, deleted)
+		assert.True(t, errors.Is(err, repos.ErrNotFound))
+	})
+
+	t.Run("test that values longer than the columns are rejected", func(t *testing.T) {
+		users := repos.NewMemoryStore().Users()
+
+		_, err := users.Create(ctx, &obj.User{FirstName: strings.Repeat("a", 91)})
+		assert.True(t, errors.Is(err, repos.ErrValidation))
+	})
+
+	t.Run("test that fields that can't be written aren't patched", func(t *testing.T) {
+		users := repos.NewMemoryStore().Users()
+		_, _ = users.Create(ctx, &obj.User{FirstName: "Pedro", LastName: "Rocha"})
+
+		_, err := users.Patch(ctx, &obj.User{ID: 1}, []string{"created_at"})
+		assert.EqualError(t, err, "failed to patch field created_at: the field can't be written")
+		assert.True(t, errors.Is(err, repos.ErrValidation))
+	})
+
+	t.Run("test that deleting a user deletes it's contacts and api keys", func(t *testing.T) {
+		store := repos.NewMemoryStore()
+		users, contacts, keys := store.Users(), store.Contacts(), store.APIKeys()
+		_, _ = users.Create(ctx, &obj.User{FirstName: "Pedro", LastName: "Rocha"})
+		_, _ = users.Create(ctx, &obj.User{FirstName: "Ana", LastName: "Silva"})
+		_, _ = contacts.Create(ctx, 1, &obj.Contact{FirstName: "Maria"})
+		_, _ = contacts.Create(ctx, 2, &obj.Contact{FirstName: "Rui"})
+		_, _ = keys.Create(ctx, &obj.APIKey{Name: "mobile", UserID: 1}, "hash")
+
+		deleted, err := users.Delete(ctx, 1, 1)
+		assert.NoError(t, err)
+		assert.True(t, deleted)
+
+		_, err = contacts.Get(ctx, 1, 1)
+		assert.True(t, errors.Is(err, repos.ErrNotFound))
+		_, err = keys.GetByHash(ctx, "hash")
+		assert.True(t, errors.Is(err, repos.ErrNotFound))
+
+		remaining, err := contacts.ListByUsers(ctx, []int{1, 2})
+		assert.NoError(t, err)
+		assert.Len(t, remaining, 1)
+		assert.Len(t, remaining[2], 1)
+	})
+
+	t.Run("test that users can be created concurrently", func(t *testing.T) {
+		users := repos.NewMemoryStore().Users()
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := users.Create(ctx, &obj.User{FirstName: "Pedro", LastName: "Rocha"})
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		list, page, err := users.List(ctx, repos.ListOptions{Limit: 100})
+		assert.NoError(t, err)
+		assert.Equal(t, 50, page.Total)
+		for i, user := range list {
+			assert.Equal(t, i+1, user.ID)
+		}
+	})
+}
+
+func TestMemoryStore_List(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	users := repos.NewMemoryStore(repos.WithMemoryClock(clock(start))).Users()
+	for _, name := range []string{"Pedro", "Ana", "Maria", "Ana", "Rui"} {
+		_, _ = users.Create(ctx, &obj.User{FirstName: name, LastName: "Rocha"})
+	}
+
+	ids := func(list []obj.User) []int {
+		result := make([]int, len(list))
+		for i, user := range list {
+			result[i] = user.ID
+		}
+		return result
+	}
+
+	t.Run("test that users are listed by creation date", func(t *testing.T) {
+		list, page, err := users.List(ctx, repos.ListOptions{Limit: 2, Offset: 1})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2, 3}, ids(list))
+		assert.Equal(t, &repos.PageInfo{Total: 5, Limit: 2, Offset: 1, HasNext: true, HasPrev: true}, page)
+	})
+
+	t.Run("test that listings can be filtered and sorted", func(t *testing.T) {
+		list, page, err := users.List(ctx, repos.ListOptions{
+			Filters: []repos.Filter{{Field: "created_at", Op: repos.After, Value: "2020-01-02T03:04:06Z"}},
+			Sort:    []repos.Sort{{Field: "first_name"}, {Field: "created_at", Desc: true}},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{4, 2, 3, 5}, ids(list))
+		assert.Equal(t, 4, page.Total)
+
+		list, _, err = users.List(ctx, repos.ListOptions{
+			Filters: []repos.Filter{{Field: "first_name", Op: repos.Equal, Value: "Ana"}},
+			IDs:     []int{1, 4},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{4}, ids(list))
+	})
+
+	t.Run("test that invalid list parameters are rejected", func(t *testing.T) {
+		_, _, err := users.List(ctx, repos.ListOptions{
+			Filters: []repos.Filter{{Field: "first_name", Op: repos.After, Value: "Ana"}},
+			Sort:    []repos.Sort{{Field: "password"}},
+		})
+		assert.Equal(t, repos.FieldErrors{
+			{Field: "first_name", Reason: "the 'after' operator isn't supported by this field"},
+			{Field: "password", Reason: "sorting by this field isn't supported"},
+		}, err)
+
+		_, _, err = users.List(ctx, repos.ListOptions{Filters: []repos.Filter{
+			{Field: "created_at", Op: repos.Equal, Value: "yesterday"},
+		}})
+		assert.True(t, errors.Is(err, repos.ErrValidation))
+
+		_, _, err = users.List(ctx, repos.ListOptions{Filters: []repos.Filter{
+			{Field: "id", Op: repos.Equal, Value: "4"},
+		}})
+		assert.Equal(t, repos.FieldErrors{{Field: "id", Reason: "filtering by this field isn't supported"}}, err)
+	})
+
+	t.Run("test that cursors walk the listing in both directions", func(t *testing.T) {
+		list, page, err := users.List(ctx, repos.ListOptions{Limit: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, ids(list))
+		assert.Nil(t, page.Prev)
+
+		list, page, err = users.List(ctx, repos.ListOptions{Limit: 2, Cursor: page.Next})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{3, 4}, ids(list))
+		assert.True(t, page.HasNext)
+		assert.True(t, page.HasPrev)
+
+		list, page, err = users.List(ctx, repos.ListOptions{Limit: 2, Cursor: page.Next})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{5}, ids(list))
+		assert.False(t, page.HasNext)
+
+		list, page, err = users.List(ctx, repos.ListOptions{Limit: 2, Cursor: page.Prev})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{3, 4}, ids(list))
+		assert.True(t, page.HasPrev)
+		assert.True(t, page.HasNext)
+	})
+}
+
+func TestMemoryStore_Contacts(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("test that contacts can only be reached through their owner", func(t *testing.T) {
+		store := repos.NewMemoryStore()
+		users, contacts := store.Users(), store.Contacts()
+		_, _ = users.Create(ctx, &obj.User{FirstName: "Pedro", LastName: "Rocha"})
+		_, _ = users.Create(ctx, &obj.User{FirstName: "Ana", LastName: "Silva"})
+
+		created, err := contacts.Create(ctx, 1, &obj.Contact{FirstName: "Maria", Email: "maria@example.com"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), created.ID)
+		assert.Equal(t, int64(1), created.UserID)
+		assert.Equal(t, int64(1), created.Version)
+
+		_, err = contacts.Get(ctx, 2, 1)
+		assert.True(t, errors.Is(err, repos.ErrNotFound))
+
+		_, err = contacts.Update(ctx, 2, &obj.Contact{ID: 1, FirstName: "Rui"})
+		assert.True(t, errors.Is(err, repos.ErrNotFound))
+
+		_, err = contacts.Delete(ctx, 2, 1, 0)
+		assert.True(t, errors.Is(err, repos.ErrNotFound))
+
+		list, page, err := contacts.List(ctx, 1, repos.ListOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, []obj.Contact{*created}, list)
+		assert.Equal(t, 1, page.Total)
+	})
+
+	t.Run("test that contacts of missing users conflict", func(t *testing.T) {
+		contacts := repos.NewMemoryStore().Contacts()
+
+		_, err := contacts.Create(ctx, 1, &obj.Contact{FirstName: "Maria"})
+		assert.True(t, errors.Is(err, repos.ErrConflict))
+	})
+}
+
+func TestMemoryStore_APIKeys(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("test that revoked keys aren't found", func(t *testing.T) {
+		keys := repos.NewMemoryStore().APIKeys()
+
+		created, err := keys.Create(ctx, &obj.APIKey{Name: "ops", Admin: true}, "hash")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, created.ID)
+
+		found, err := keys.GetByHash(ctx, "hash")
+		assert.NoError(t, err)
+		assert.Equal(t, created, found)
+
+		revoked, err := keys.Revoke(ctx, 1)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+
+		_, err = keys.GetByHash(ctx, "hash")
+		assert.True(t, errors.Is(err, repos.ErrNotFound))
+
+		_, err = keys.Revoke(ctx, 1)
+		assert.True(t, errors.Is(err, repos.ErrNotFound))
+	})
+
+	t.Run("test that hashes are unique", func(t *testing.T) {
+		keys := repos.NewMemoryStore().APIKeys()
+		_, _ = keys.Create(ctx, &obj.APIKey{Name: "ops", Admin: true}, "hash")
+
+		_, err := keys.Create(ctx, &obj.APIKey{Name: "other", Admin: true}, "hash")
+		assert.True(t, errors.Is(err, repos.ErrConflict))
+	})
+}
@@ -0,0 +1,206 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/pedrorochaorg/contactsApi/obj"
+	"github.com/pedrorochaorg/contactsApi/tracing"
+)
+
+// Hook is called before every call to a repository method with the name of the repository, like UserRepository, and
+// the name of the method. It returns the context the call is made with and the function that is called with the
+// error returned by the call once it finishes.
+type Hook func(ctx context.Context, repository, method string) (context.Context, func(err error))
+
+// Observer is called after every call to a repository method with the name of the repository, like UserRepository,
+// the name of the method, how long the call took and the error it returned
+type Observer func(repository, method string, duration time.Duration, err error)
+
+// Timed returns a hook that reports the duration of every call to the observer
+func Timed(observe Observer) Hook {
+	return func(ctx context.Context, repository, method string) (context.Context, func(err error)) {
+		start := time.Now()
+		return ctx, func(err error) {
+			observe(repository, method, time.Since(start), err)
+		}
+	}
+}
+
+// Traced returns a hook that wraps every call in a span named after the repository and the method, a child of the
+// span of the context. The statements run by the call and the number of rows they returned or changed are added to
+// the span. Calls that fail for other reasons than a missing row mark the span as failed.
+//
+// The system, like SystemPostgres, is added to the span as the db.system attribute. It's left out when empty, for
+// repositories that aren't backed by a database like the ones of a MemoryStore.
+func Traced(system string) Hook {
+	options := []tracing.SpanOption{tracing.WithKind(tracing.KindClient)}
+	if system != "" {
+		options = append(options, tracing.WithAttributes(tracing.Attribute{Key: AttributeSystem, Value: system}))
+	}
+
+	return func(ctx context.Context, repository, method string) (context.Context, func(err error)) {
+		ctx, span := tracing.Start(ctx, repository+"."+method, options...)
+		return ctx, func(err error) {
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				span.SetError(err)
+			}
+			span.End()
+		}
+	}
+}
+
+// hooks the hooks of a repository, called in order before a call and in reverse order after it
+type hooks []Hook
+
+// start calls the hooks before a call, the returned function must be deferred with the address of the error
+// returned by the call
+func (h hooks) start(ctx context.Context, repository, method string) (context.Context, func(err *error)) {
+	finishers := make([]func(err error), len(h))
+	for i, hook := range h {
+		ctx, finishers[i] = hook(ctx, repository, method)
+	}
+
+	return ctx, func(err *error) {
+		for i := len(finishers) - 1; i >= 0; i-- {
+			finishers[i](*err)
+		}
+	}
+}
+
+// observedUsers UserRepo that calls hooks around every call
+type observedUsers struct {
+	repo  UserRepo
+	hooks hooks
+}
+
+// ObserveUsers returns a UserRepo that calls the hooks around every call made to the repository
+func ObserveUsers(repo UserRepo, hooks ...Hook) UserRepo {
+	return &observedUsers{repo: repo, hooks: hooks}
+}
+
+func (o *observedUsers) List(ctx context.Context, opts ListOptions) (users []obj.User, page *PageInfo, err error) {
+	ctx, done := o.hooks.start(ctx, "UserRepository", "List")
+	defer done(&err)
+	return o.repo.List(ctx, opts)
+}
+
+func (o *observedUsers) Create(ctx context.Context, user *obj.User) (created *obj.User, err error) {
+	ctx, done := o.hooks.start(ctx, "UserRepository", "Create")
+	defer done(&err)
+	return o.repo.Create(ctx, user)
+}
+
+func (o *observedUsers) Update(ctx context.Context, user *obj.User) (updated *obj.User, err error) {
+	ctx, done := o.hooks.start(ctx, "UserRepository", "Update")
+	defer done(&err)
+	return o.repo.Update(ctx, user)
+}
+
+func (o *observedUsers) Patch(ctx context.Context, user *obj.User, fields []string) (patched *obj.User, err error) {
+	ctx, done := o.hooks.start(ctx, "UserRepository", "Patch")
+	defer done(&err)
+	return o.repo.Patch(ctx, user, fields)
+}
+
+func (o *observedUsers) Get(ctx context.Context, id int) (user *obj.User, err error) {
+	ctx, done := o.hooks.start(ctx, "UserRepository", "Get")
+	defer done(&err)
+	return o.repo.Get(ctx, id)
+}
+
+func (o *observedUsers) Delete(ctx context.Context, id int, version int64) (deleted bool, err error) {
+	ctx, done := o.hooks.start(ctx, "UserRepository", "Delete")
+	defer done(&err)
+	return o.repo.Delete(ctx, id, version)
+}
+
+// observedContacts ContactRepo that calls hooks around every call
+type observedContacts struct {
+	repo  ContactRepo
+	hooks hooks
+}
+
+// ObserveContacts returns a ContactRepo that calls the hooks around every call made to the repository
+func ObserveContacts(repo ContactRepo, hooks ...Hook) ContactRepo {
+	return &observedContacts{repo: repo, hooks: hooks}
+}
+
+func (o *observedContacts) List(ctx context.Context, userID int, opts ListOptions) (contacts []obj.Contact,
+	page *PageInfo, err error) {
+	ctx, done := o.hooks.start(ctx, "ContactRepository", "List")
+	defer done(&err)
+	return o.repo.List(ctx, userID, opts)
+}
+
+func (o *observedContacts) ListByUsers(ctx context.Context, userIDs []int) (contacts map[int][]obj.Contact,
+	err error) {
+	ctx, done := o.hooks.start(ctx, "ContactRepository", "ListByUsers")
+	defer done(&err)
+	return o.repo.ListByUsers(ctx, userIDs)
+}
+
+func (o *observedContacts) Create(ctx context.Context, userID int, contact *obj.Contact) (created *obj.Contact,
+	err error) {
+	ctx, done := o.hooks.start(ctx, "ContactRepository", "Create")
+	defer done(&err)
+	return o.repo.Create(ctx, userID, contact)
+}
+
+func (o *observedContacts) Update(ctx context.Context, userID int, contact *obj.Contact) (updated *obj.Contact,
+	err error) {
+	ctx, done := o.hooks.start(ctx, "ContactRepository", "Update")
+	defer done(&err)
+	return o.repo.Update(ctx, userID, contact)
+}
+
+func (o *observedContacts) Patch(ctx context.Context, userID int, contact *obj.Contact,
+	fields []string) (patched *obj.Contact, err error) {
+	ctx, done := o.hooks.start(ctx, "ContactRepository", "Patch")
+	defer done(&err)
+	return o.repo.Patch(ctx, userID, contact, fields)
+}
+
+func (o *observedContacts) Get(ctx context.Context, userID int, id int) (contact *obj.Contact, err error) {
+	ctx, done := o.hooks.start(ctx, "ContactRepository", "Get")
+	defer done(&err)
+	return o.repo.Get(ctx, userID, id)
+}
+
+func (o *observedContacts) Delete(ctx context.Context, userID int, id int, version int64) (deleted bool,
+	err error) {
+	ctx, done := o.hooks.start(ctx, "ContactRepository", "Delete")
+	defer done(&err)
+	return o.repo.Delete(ctx, userID, id, version)
+}
+
+// observedAPIKeys APIKeyRepo that calls hooks around every call
+type observedAPIKeys struct {
+	repo  APIKeyRepo
+	hooks hooks
+}
+
+// ObserveAPIKeys returns an APIKeyRepo that calls the hooks around every call made to the repository
+func ObserveAPIKeys(repo APIKeyRepo, hooks ...Hook) APIKeyRepo {
+	return &observedAPIKeys{repo: repo, hooks: hooks}
+}
+
+func (o *observedAPIKeys) Create(ctx context.Context, key *obj.APIKey, hash string) (created *obj.APIKey,
+	err error) {
+	ctx, done := o.hooks.start(ctx, "APIKeyRepository", "Create")
+	defer done(&err)
+	return o.repo.Create(ctx, key, hash)
+}
+
+func (o *observedAPIKeys) GetByHash(ctx context.Context, hash string) (key *obj.APIKey, err error) {
+	ctx, done := o.hooks.start(ctx, "APIKeyRepository", "GetByHash")
+	defer done(&err)
+	return o.repo.GetByHash(ctx, hash)
+}
+
+func (o *observedAPIKeys) Revoke(ctx context.Context, id int) (revoked bool, err error) {
+	ctx, done := o.hooks.start(ctx, "APIKeyRepository", "Revoke")
+	defer done(&err)
+	return o.repo.Revoke(ctx, id)
+}
@@ -0,0 +1,171 @@
+package repos_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/repos"
+	"github.com/pedrorochaorg/contactsApi/tracing"
+	"github.com/pedrorochaorg/contactsApi/tracing/tracingtest"
+)
+
+// observation a call reported to an observer
+type observation struct {
+	repository string
+	method     string
+	err        error
+}
+
+func TestObserveUsers(t *testing.T) {
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error while opening a new database connection")
+	}
+	defer db.Close()
+
+	observed := []observation{}
+	observer := func(repository, method string, duration time.Duration, err error) {
+		assert.True(t, duration >= 0, "The duration can't be negative")
+		observed = append(observed, observation{repository, method, err})
+	}
+
+	userRepo := repos.NewUserRepository(db)
+	users := repos.ObserveUsers(&userRepo, repos.Timed(observer))
+
+	mock.ExpectQuery("SELECT").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at", "version"}).
+			AddRow(1, "John", "Cena", time.Now(), time.Now(), 1))
+	mock.ExpectQuery("SELECT").WillReturnError(fmt.Errorf("connection reset"))
+
+	user, err := users.Get(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, user.ID)
+
+	_, err = users.Get(context.Background(), 2)
+	assert.Error(t, err)
+
+	if assert.Len(t, observed, 2) {
+		assert.Equal(t, observation{"UserRepository", "Get", nil}, observed[0])
+		assert.Equal(t, "Get", observed[1].method)
+		assert.True(t, errors.Is(observed[1].err, err), "The error returned by the call is reported")
+	}
+}
+
+func TestObserveContacts(t *testing.T) {
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error while opening a new database connection")
+	}
+	defer db.Close()
+
+	observed := []observation{}
+	observer := func(repository, method string, duration time.Duration, err error) {
+		observed = append(observed, observation{repository, method, err})
+	}
+
+	contactRepo := repos.NewContactRepository(db)
+	contacts := repos.ObserveContacts(&contactRepo, repos.Timed(observer))
+
+	mock.ExpectExec("DELETE").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	deleted, err := contacts.Delete(context.Background(), 1, 2, 0)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	assert.Equal(t, []observation{{"ContactRepository", "Delete", nil}}, observed)
+}
+
+// attributes returns the attributes of the span by key
+func attributes(span tracing.SpanData) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, attribute := range span.Attributes {
+		values[attribute.Key] = attribute.Value
+	}
+	return values
+}
+
+func TestTraced(t *testing.T) {
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error while opening a new database connection")
+	}
+	defer db.Close()
+
+	exporter := &tracingtest.Recorder{}
+	tracer := tracing.NewTracer(exporter)
+
+	userRepo := repos.NewUserRepository(db)
+	users := repos.ObserveUsers(&userRepo, repos.Traced(repos.SystemPostgres))
+
+	mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery("SELECT").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at", "version"}).
+			AddRow(1, "John", "Cena", time.Now(), time.Now(), 1).
+			AddRow(2, "Cena", "Men", time.Now(), time.Now(), 1))
+	mock.ExpectQuery("SELECT").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at", "version"}))
+	mock.ExpectExec("DELETE").WillReturnError(fmt.Errorf("connection reset"))
+
+	ctx, request := tracer.Start(context.Background(), "GET /users")
+
+	_, _, err = users.List(ctx, repos.ListOptions{})
+	assert.NoError(t, err)
+	_, err = users.Get(ctx, 3)
+	assert.True(t, errors.Is(err, repos.ErrNotFound))
+	_, err = users.Delete(ctx, 1, 0)
+	assert.Error(t, err)
+
+	request.End()
+	assert.NoError(t, tracer.Shutdown(context.Background()))
+
+	spans := exporter.Spans()
+	if !assert.Len(t, spans, 4) {
+		return
+	}
+	list, get, remove := spans[0], spans[1], spans[2]
+
+	assert.Equal(t, "UserRepository.List", list.Name)
+	assert.Equal(t, request.SpanContext().SpanID, list.Parent, "Repository spans are children of the request")
+	assert.Equal(t, tracing.KindClient, list.Kind)
+	assert.Equal(t, "postgresql", attributes(list)[repos.AttributeSystem])
+	assert.Regexp(t, "^SELECT COUNT.*; SELECT ", attributes(list)[repos.AttributeStatement])
+	assert.Equal(t, 2, attributes(list)[repos.AttributeRows])
+
+	assert.Equal(t, "UserRepository.Get", get.Name)
+	assert.Equal(t, tracing.StatusUnset, get.Status, "A missing row isn't a failure")
+
+	assert.Equal(t, "UserRepository.Delete", remove.Name)
+	assert.Regexp(t, "^DELETE FROM", attributes(remove)[repos.AttributeStatement])
+	assert.Equal(t, tracing.StatusError, remove.Status)
+}
+
+func TestTraced_WithoutSystem(t *testing.T) {
+
+	exporter := &tracingtest.Recorder{}
+	tracer := tracing.NewTracer(exporter)
+
+	users := repos.ObserveUsers(repos.NewMemoryStore().Users(), repos.Traced(""))
+
+	ctx, request := tracer.Start(context.Background(), "GET /users")
+	_, _, err := users.List(ctx, repos.ListOptions{})
+	assert.NoError(t, err)
+	request.End()
+	assert.NoError(t, tracer.Shutdown(context.Background()))
+
+	spans := exporter.Spans()
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+	assert.Equal(t, "UserRepository.List", spans[0].Name)
+	assert.NotContains(t, attributes(spans[0]), repos.AttributeSystem,
+		"Repositories that aren't backed by a database don't name a system")
+}
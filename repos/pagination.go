@@ -0,0 +1,203 @@
+package repos
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLimit number of rows returned by a listing when the caller doesn't specify a limit
+	DefaultLimit = 50
+	// MaxLimit highest number of rows that can be requested in a single page
+	MaxLimit = 500
+)
+
+// Cursor identifies a position in a listing ordered by creation date and id. It's handed to clients as an opaque
+// string so that they can resume the listing right after (or right before) the row that it points to without the
+// database having to skip every previous row like it does with an offset.
+type Cursor struct {
+	ID        int       `json:"i"`
+	CreatedAt time.Time `json:"c"`
+	Before    bool      `json:"b,omitempty"`
+}
+
+// Encode returns the opaque string representation of the cursor
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a string previously generated by Cursor.Encode
+func DecodeCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %s", err)
+	}
+
+	cursor := &Cursor{}
+	err = json.Unmarshal(data, cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %s", err)
+	}
+
+	return cursor, nil
+}
+
+// ListOptions controls which rows of a listing are returned. Rows are ordered by creation date unless a Sort is
+// given. When a cursor is present the listing resumes from the row it points to, otherwise Offset rows are skipped.
+// Cursors are only available for the default order.
+//
+// IDs restricts the listing to the rows with those ids when it isn't nil, an empty list matches no row. Unlike the
+// filters it's never built from the parameters sent by clients, it's meant for restrictions applied by the server
+// like the users a principal can act on.
+type ListOptions struct {
+	Limit   int
+	Offset  int
+	Cursor  *Cursor
+	Filters []Filter
+	Sort    []Sort
+	IDs     []int
+}
+
+// limit returns the page size that should be used, falling back to DefaultLimit
+func (o ListOptions) limit() int {
+	if o.Limit <= 0 {
+		return DefaultLimit
+	}
+	if o.Limit > MaxLimit {
+		return MaxLimit
+	}
+	return o.Limit
+}
+
+// PageInfo describes the page returned by a listing. Next and Prev are only set when the listing was done with a
+// cursor, offset listings should rely on the Offset, Limit and Total values to build the links to adjacent pages.
+type PageInfo struct {
+	Total   int
+	Limit   int
+	Offset  int
+	HasNext bool
+	HasPrev bool
+	Next    *Cursor
+	Prev    *Cursor
+}
+
+// selectQuery builds a parameterised SELECT statement over one of the tables of the contactsApi schema
+type selectQuery struct {
+	table string
+	where []string
+	args  []interface{}
+	order string
+}
+
+// arg stores a query argument returning the placeholder that should be used to reference it
+func (q *selectQuery) arg(value interface{}) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func (q *selectQuery) conditions() string {
+	if len(q.where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.where, " AND ")
+}
+
+// count returns the statement that counts every row matching the query conditions
+func (q *selectQuery) count() (string, []interface{}) {
+	return fmt.Sprintf("SELECT COUNT(*) FROM \"contactsApi\".%q%s", q.table, q.conditions()), q.args
+}
+
+// page returns the statement that fetches the page described by the options. One more row than the page size is
+// requested so that the caller can tell whether there are more rows after the page.
+func (q *selectQuery) page(opts ListOptions) (string, []interface{}) {
+	page := &selectQuery{
+		table: q.table,
+		where: append([]string{}, q.where...),
+		args:  append([]interface{}{}, q.args...),
+	}
+	limit := opts.limit() + 1
+
+	if opts.Cursor == nil {
+		order := q.order
+		if order == "" {
+			order = "created_at, id"
+		}
+
+		query := fmt.Sprintf("SELECT * FROM \"contactsApi\".%q%s ORDER BY %s LIMIT %s OFFSET %s",
+			page.table, page.conditions(), order, page.arg(limit), page.arg(opts.Offset))
+		return query, page.args
+	}
+
+	if opts.Cursor.Before {
+		page.where = append(page.where, fmt.Sprintf("(created_at, id) < (%s, %s)", page.arg(opts.Cursor.CreatedAt),
+			page.arg(opts.Cursor.ID)))
+
+		// Rows before the cursor are fetched in reverse order so that the ones closest to it are kept by the limit,
+		// the outer query puts them back in ascending order.
+		query := fmt.Sprintf("SELECT * FROM (SELECT * FROM \"contactsApi\".%q%s ORDER BY created_at DESC, "+
+			"id DESC LIMIT %s) AS page ORDER BY created_at, id", page.table, page.conditions(), page.arg(limit))
+		return query, page.args
+	}
+
+	page.where = append(page.where, fmt.Sprintf("(created_at, id) > (%s, %s)", page.arg(opts.Cursor.CreatedAt),
+		page.arg(opts.Cursor.ID)))
+
+	query := fmt.Sprintf("SELECT * FROM \"contactsApi\".%q%s ORDER BY created_at, id LIMIT %s", page.table,
+		page.conditions(), page.arg(limit))
+	return query, page.args
+}
+
+// pageInfo works out whether there are rows around the page that was fetched. fetched is the number of rows returned
+// by the statement built by selectQuery.page, the caller should keep at most info.Limit of them, discarding the first
+// row when paging backwards and the last one otherwise.
+func pageInfo(opts ListOptions, total, fetched int) *PageInfo {
+	info := &PageInfo{Total: total, Limit: opts.limit(), Offset: opts.Offset}
+	more := fetched > info.Limit
+
+	switch {
+	case opts.Cursor == nil:
+		info.HasNext = more
+		info.HasPrev = opts.Offset > 0
+	case opts.Cursor.Before:
+		// The row the cursor points to comes after this page, an empty page has no row to build a cursor from
+		info.HasNext = fetched > 0
+		info.HasPrev = more
+	default:
+		info.HasNext = more
+		info.HasPrev = fetched > 0
+	}
+
+	return info
+}
+
+// trim returns the bounds of the rows that belong to the page
+func (p *PageInfo) trim(opts ListOptions, fetched int) (int, int) {
+	if fetched <= p.Limit {
+		return 0, fetched
+	}
+	if opts.Cursor != nil && opts.Cursor.Before {
+		return fetched - p.Limit, fetched
+	}
+	return 0, p.Limit
+}
+
+// setCursors stores the cursors pointing to the first and last rows of a page, so that clients can keep walking the
+// listing in both directions. Listings that skipped rows with an offset or that use a custom sort keep using offsets
+// and get no cursors.
+func (p *PageInfo) setCursors(opts ListOptions, first, last Cursor) {
+	if opts.Cursor == nil && (opts.Offset > 0 || len(opts.Sort) > 0) {
+		return
+	}
+
+	if p.HasPrev {
+		first.Before = true
+		p.Prev = &first
+	}
+	if p.HasNext {
+		p.Next = &last
+	}
+}
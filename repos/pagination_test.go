@@ -0,0 +1,33 @@
+package repos_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+func TestCursor_Encode(t *testing.T) {
+
+	t.Run("an encoded cursor should decode to the same values", func(t *testing.T) {
+		parsedTime, _ := time.Parse(time.RFC3339Nano, "2019-11-22T10:00:00.123456Z")
+
+		cursor := repos.Cursor{ID: 28, CreatedAt: parsedTime, Before: true}
+
+		decoded, err := repos.DecodeCursor(cursor.Encode())
+
+		assert.NoError(t, err)
+		assert.True(t, cursor.CreatedAt.Equal(decoded.CreatedAt), "Dates don't match")
+		assert.Equal(t, cursor.ID, decoded.ID)
+		assert.Equal(t, cursor.Before, decoded.Before)
+	})
+
+	t.Run("an invalid cursor should return an error", func(t *testing.T) {
+		_, err := repos.DecodeCursor("not a cursor!")
+
+		assert.Error(t, err, "should have returned an error")
+		assert.Contains(t, err.Error(), "invalid cursor", "Error message doesn't match")
+	})
+}
@@ -0,0 +1,39 @@
+package repos
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// assignments builds the SET clause of an update statement that changes only the given fields, values holds the new
+// value of every writable field. The placeholders start at $1 and the arguments are returned in the same order.
+func assignments(columns map[string]column, fields []string, values map[string]interface{}) (string,
+	[]interface{}, error) {
+	err := writable(columns, fields)
+	if err != nil {
+		return "", nil, err
+	}
+
+	set := make([]string, len(fields))
+	args := make([]interface{}, len(fields))
+
+	for i, field := range fields {
+		set[i] = fmt.Sprintf("%s = $%d", columns[field].name, i+1)
+		args[i] = values[field]
+	}
+
+	return strings.Join(set, ", "), args, nil
+}
+
+// writable verifies that every field can be patched, patches of other fields are ErrValidation failures
+func writable(columns map[string]column, fields []string) error {
+	for _, field := range fields {
+		column, ok := columns[field]
+		if !ok || !column.writable {
+			return &Error{Kind: ErrValidation, Message: "failed to patch field " + field,
+				Err: errors.New("the field can't be written")}
+		}
+	}
+	return nil
+}
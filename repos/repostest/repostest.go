@@ -0,0 +1,625 @@
+// Package repostest is the executable contract of the repositories: a suite of tests that every implementation of
+// repos.UserRepo and repos.ContactRepo must pass, whatever it keeps the data in. It covers the writes, the not found
+// and precondition failures, the timestamps and versions of the rows, the order and pages of the listings and
+// concurrent use.
+//
+// The suite doesn't assume anything about the ids other than them being assigned in increasing order, so it can run
+// against a shared database as long as the factory empties it. The names it sorts by are ASCII and capitalized alike,
+// so that their order doesn't depend on the collation of the database.
+package repostest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/obj"
+	"github.com/pedrorochaorg/contactsApi/repos"
+)
+
+// UserFactory returns an empty UserRepo, it's called by every test of the suite
+type UserFactory func(t *testing.T) repos.UserRepo
+
+// ContactFactory returns an empty ContactRepo together with the UserRepo of the users that own the contacts, it's
+// called by every test of the suite
+type ContactFactory func(t *testing.T) (repos.UserRepo, repos.ContactRepo)
+
+// concurrency number of goroutines of the concurrency tests
+const concurrency = 20
+
+// TestUserRepo runs the suite of the UserRepo against the repositories built by the factory
+func TestUserRepo(t *testing.T, factory UserFactory) {
+	for _, test := range userTests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, factory(t))
+		})
+	}
+}
+
+// TestContactRepo runs the suite of the ContactRepo against the repositories built by the factory
+func TestContactRepo(t *testing.T, factory ContactFactory) {
+	for _, test := range contactTests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			users, contacts := factory(t)
+			test.run(t, users, contacts)
+		})
+	}
+}
+
+var userTests = []struct {
+	name string
+	run  func(t *testing.T, users repos.UserRepo)
+}{
+	{"create assigns an id, the timestamps and the first version", func(t *testing.T, users repos.UserRepo) {
+		first := createUser(t, users, "Pedro", "Rocha")
+		second := createUser(t, users, "Ana", "Silva")
+
+		assert.True(t, first.ID > 0, "the id should be positive")
+		assert.True(t, second.ID > first.ID, "the ids should increase")
+		assert.Equal(t, "Pedro", first.FirstName)
+		assert.Equal(t, "Rocha", first.LastName)
+		assert.False(t, first.CreatedAt.IsZero(), "the creation date should be set")
+		assert.True(t, first.UpdatedAt.Equal(first.CreatedAt), "a new user should be updated when it's created")
+		assert.Equal(t, int64(1), first.Version)
+
+		found, err := users.Get(context.Background(), first.ID)
+		assert.NoError(t, err)
+		assertUser(t, first, found)
+	}},
+	{"get of a missing user isn't found", func(t *testing.T, users repos.UserRepo) {
+		_, err := users.Get(context.Background(), 1)
+		assertKind(t, repos.ErrNotFound, err)
+	}},
+	{"update replaces the user, stamps it and increases it's version", func(t *testing.T, users repos.UserRepo) {
+		created := createUser(t, users, "Pedro", "Rocha")
+
+		updated, err := users.Update(context.Background(), &obj.User{ID: created.ID, FirstName: "Ana",
+			LastName: "Silva", Version: created.Version})
+		assert.NoError(t, err)
+		assert.Equal(t, "Ana", updated.FirstName)
+		assert.Equal(t, "Silva", updated.LastName)
+		assert.True(t, updated.CreatedAt.Equal(created.CreatedAt), "the creation date shouldn't change")
+		assert.False(t, updated.UpdatedAt.Before(created.UpdatedAt), "the update date shouldn't go back")
+		assert.Equal(t, int64(2), updated.Version)
+
+		found, err := users.Get(context.Background(), created.ID)
+		assert.NoError(t, err)
+		assertUser(t, updated, found)
+	}},
+	{"update without a version overwrites the user", func(t *testing.T, users repos.UserRepo) {
+		created := createUser(t, users, "Pedro", "Rocha")
+		_, _ = users.Update(context.Background(), &obj.User{ID: created.ID, FirstName: "Ana", LastName: "Silva"})
+
+		updated, err := users.Update(context.Background(), &obj.User{ID: created.ID, FirstName: "Rui",
+			LastName: "Silva"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), updated.Version)
+	}},
+	{"patch only changes the given fields", func(t *testing.T, users repos.UserRepo) {
+		created := createUser(t, users, "Pedro", "Rocha")
+
+		patched, err := users.Patch(context.Background(), &obj.User{ID: created.ID, FirstName: "Ana",
+			LastName: "ignored", Version: created.Version}, []string{"first_name"})
+		assert.NoError(t, err)
+		assert.Equal(t, "Ana", patched.FirstName)
+		assert.Equal(t, "Rocha", patched.LastName)
+		assert.Equal(t, int64(2), patched.Version)
+	}},
+	{"patch without fields returns the user unchanged", func(t *testing.T, users repos.UserRepo) {
+		created := createUser(t, users, "Pedro", "Rocha")
+
+		patched, err := users.Patch(context.Background(), &obj.User{ID: created.ID, FirstName: "Ana"}, nil)
+		assert.NoError(t, err)
+		assertUser(t, created, patched)
+	}},
+	{"patch rejects fields that can't be written", func(t *testing.T, users repos.UserRepo) {
+		created := createUser(t, users, "Pedro", "Rocha")
+
+		_, err := users.Patch(context.Background(), &obj.User{ID: created.ID}, []string{"created_at"})
+		assertKind(t, repos.ErrValidation, err)
+
+		found, err := users.Get(context.Background(), created.ID)
+		assert.NoError(t, err)
+		assertUser(t, created, found)
+	}},
+	{"writes of a missing user aren't found", func(t *testing.T, users repos.UserRepo) {
+		ctx := context.Background()
+
+		_, err := users.Update(ctx, &obj.User{ID: 1, FirstName: "Pedro", LastName: "Rocha"})
+		assertKind(t, repos.ErrNotFound, err)
+
+		_, err = users.Patch(ctx, &obj.User{ID: 1, FirstName: "Pedro"}, []string{"first_name"})
+		assertKind(t, repos.ErrNotFound, err)
+
+		deleted, err := users.Delete(ctx, 1, 0)
+		assert.False(t, deleted)
+		assertKind(t, repos.ErrNotFound, err)
+	}},
+	{"writes at an outdated version fail their precondition", func(t *testing.T, users repos.UserRepo) {
+		ctx := context.Background()
+		created := createUser(t, users, "Pedro", "Rocha")
+		_, _ = users.Update(ctx, &obj.User{ID: created.ID, FirstName: "Ana", LastName: "Silva"})
+
+		_, err := users.Update(ctx, &obj.User{ID: created.ID, FirstName: "Rui", LastName: "Silva", Version: 1})
+		assertKind(t, repos.ErrPreconditionFailed, err)
+
+		_, err = users.Patch(ctx, &obj.User{ID: created.ID, FirstName: "Rui", Version: 1}, []string{"first_name"})
+		assertKind(t, repos.ErrPreconditionFailed, err)
+
+		deleted, err := users.Delete(ctx, created.ID, 1)
+		assert.False(t, deleted)
+		assertKind(t, repos.ErrPreconditionFailed, err)
+
+		found, err := users.Get(ctx, created.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Ana", found.FirstName, "the user shouldn't change")
+	}},
+	{"conditional writes of a missing user fail their precondition", func(t *testing.T, users repos.UserRepo) {
+		_, err := users.Update(context.Background(), &obj.User{ID: 1, FirstName: "Pedro", Version: 1})
+		assertKind(t, repos.ErrPreconditionFailed, err)
+
+		_, err = users.Delete(context.Background(), 1, 1)
+		assertKind(t, repos.ErrPreconditionFailed, err)
+	}},
+	{"delete removes the user", func(t *testing.T, users repos.UserRepo) {
+		ctx := context.Background()
+		created := createUser(t, users, "Pedro", "Rocha")
+
+		deleted, err := users.Delete(ctx, created.ID, created.Version)
+		assert.NoError(t, err)
+		assert.True(t, deleted)
+
+		_, err = users.Get(ctx, created.ID)
+		assertKind(t, repos.ErrNotFound, err)
+
+		_, err = users.Delete(ctx, created.ID, 0)
+		assertKind(t, repos.ErrNotFound, err)
+	}},
+	{"values longer than 90 characters are rejected", func(t *testing.T, users repos.UserRepo) {
+		_, err := users.Create(context.Background(), &obj.User{FirstName: strings.Repeat("a", 91)})
+		assertKind(t, repos.ErrValidation, err)
+
+		created := createUser(t, users, "Pedro", "Rocha")
+		_, err = users.Update(context.Background(), &obj.User{ID: created.ID, LastName: strings.Repeat("a", 91)})
+		assertKind(t, repos.ErrValidation, err)
+	}},
+	{"list orders the users by creation date", func(t *testing.T, users repos.UserRepo) {
+		created := createUsers(t, users, "Pedro", "Ana", "Maria")
+
+		list, page, err := users.List(context.Background(), repos.ListOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, userIDs(created), userIDs(list))
+		assert.Equal(t, 3, page.Total)
+		assert.False(t, page.HasNext)
+		assert.False(t, page.HasPrev)
+	}},
+	{"list of an empty repository is empty", func(t *testing.T, users repos.UserRepo) {
+		list, page, err := users.List(context.Background(), repos.ListOptions{})
+		assert.NoError(t, err)
+		assert.Empty(t, list)
+		assert.Equal(t, 0, page.Total)
+	}},
+	{"list skips the offset and stops at the limit", func(t *testing.T, users repos.UserRepo) {
+		created := createUsers(t, users, "Pedro", "Ana", "Maria", "Rui")
+
+		list, page, err := users.List(context.Background(), repos.ListOptions{Limit: 2, Offset: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, userIDs(created[1:3]), userIDs(list))
+		assert.Equal(t, &repos.PageInfo{Total: 4, Limit: 2, Offset: 1, HasNext: true, HasPrev: true}, page)
+	}},
+	{"list walks the cursors in both directions", func(t *testing.T, users repos.UserRepo) {
+		ctx := context.Background()
+		created := createUsers(t, users, "Pedro", "Ana", "Maria", "Rui", "Sara")
+
+		list, page, err := users.List(ctx, repos.ListOptions{Limit: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, userIDs(created[:2]), userIDs(list))
+		if !assert.NotNil(t, page.Next) {
+			return
+		}
+		assert.Nil(t, page.Prev)
+
+		list, page, err = users.List(ctx, repos.ListOptions{Limit: 2, Cursor: page.Next})
+		assert.NoError(t, err)
+		assert.Equal(t, userIDs(created[2:4]), userIDs(list))
+		if !assert.NotNil(t, page.Next) || !assert.NotNil(t, page.Prev) {
+			return
+		}
+
+		next, nextPage, err := users.List(ctx, repos.ListOptions{Limit: 2, Cursor: page.Next})
+		assert.NoError(t, err)
+		assert.Equal(t, userIDs(created[4:]), userIDs(next))
+		assert.False(t, nextPage.HasNext)
+		assert.Nil(t, nextPage.Next)
+
+		prev, prevPage, err := users.List(ctx, repos.ListOptions{Limit: 2, Cursor: page.Prev})
+		assert.NoError(t, err)
+		assert.Equal(t, userIDs(created[:2]), userIDs(prev))
+		assert.False(t, prevPage.HasPrev)
+		assert.True(t, prevPage.HasNext)
+	}},
+	{"list filters and sorts the users", func(t *testing.T, users repos.UserRepo) {
+		created := createUsers(t, users, "Pedro", "Ana", "Maria", "Ana")
+
+		list, page, err := users.List(context.Background(), repos.ListOptions{
+			Filters: []repos.Filter{{Field: "first_name", Op: repos.Equal, Value: "Ana"}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{created[1].ID, created[3].ID}, userIDs(list))
+		assert.Equal(t, 2, page.Total)
+
+		list, _, err = users.List(context.Background(), repos.ListOptions{
+			Sort: []repos.Sort{{Field: "first_name", Desc: true}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{created[0].ID, created[2].ID, created[1].ID, created[3].ID}, userIDs(list),
+			"users with the same name should be sorted by id")
+	}},
+	{"list rejects invalid parameters", func(t *testing.T, users repos.UserRepo) {
+		_, _, err := users.List(context.Background(), repos.ListOptions{
+			Filters: []repos.Filter{{Field: "password", Op: repos.Equal, Value: "secret"}},
+			Sort:    []repos.Sort{{Field: "first_name"}},
+			Cursor:  &repos.Cursor{ID: 1},
+		})
+		assertKind(t, repos.ErrValidation, err)
+
+		var fieldErrors repos.FieldErrors
+		if assert.True(t, errors.As(err, &fieldErrors), "the rejected parameters should be reported") {
+			assert.Len(t, fieldErrors, 2)
+		}
+	}},
+	{"concurrent creates get distinct ids", func(t *testing.T, users repos.UserRepo) {
+		ids := make(chan int, concurrency)
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				user, err := users.Create(context.Background(), &obj.User{FirstName: "Pedro", LastName: "Rocha"})
+				if assert.NoError(t, err) {
+					ids <- user.ID
+				}
+			}()
+		}
+		wg.Wait()
+		close(ids)
+
+		seen := map[int]bool{}
+		for id := range ids {
+			assert.False(t, seen[id], "id %d was assigned twice", id)
+			seen[id] = true
+		}
+		assert.Len(t, seen, concurrency)
+
+		_, page, err := users.List(context.Background(), repos.ListOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, concurrency, page.Total)
+	}},
+	{"concurrent conditional updates let a single one through", func(t *testing.T, users repos.UserRepo) {
+		created := createUser(t, users, "Pedro", "Rocha")
+		results := make(chan error, concurrency)
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := users.Update(context.Background(), &obj.User{ID: created.ID, FirstName: "Ana",
+					LastName: "Silva", Version: created.Version})
+				results <- err
+			}()
+		}
+		wg.Wait()
+		close(results)
+
+		succeeded := 0
+		for err := range results {
+			if err == nil {
+				succeeded++
+				continue
+			}
+			assertKind(t, repos.ErrPreconditionFailed, err)
+		}
+		assert.Equal(t, 1, succeeded)
+	}},
+}
+
+var contactTests = []struct {
+	name string
+	run  func(t *testing.T, users repos.UserRepo, contacts repos.ContactRepo)
+}{
+	{"create assigns an id, the owner, the timestamps and the first version", func(t *testing.T,
+		users repos.UserRepo, contacts repos.ContactRepo) {
+		owner := createUser(t, users, "Pedro", "Rocha")
+		first := createContact(t, contacts, owner.ID, "Ana")
+		second := createContact(t, contacts, owner.ID, "Maria")
+
+		assert.True(t, first.ID > 0, "the id should be positive")
+		assert.True(t, second.ID > first.ID, "the ids should increase")
+		assert.Equal(t, int64(owner.ID), first.UserID)
+		assert.Equal(t, "Ana", first.FirstName)
+		assert.Equal(t, "ana@example.com", first.Email)
+		assert.False(t, first.CreatedAt.IsZero(), "the creation date should be set")
+		assert.True(t, first.UpdatedAt.Equal(first.CreatedAt), "a new contact should be updated when it's created")
+		assert.Equal(t, int64(1), first.Version)
+
+		found, err := contacts.Get(context.Background(), owner.ID, int(first.ID))
+		assert.NoError(t, err)
+		assertContact(t, first, found)
+	}},
+	{"create for a missing user conflicts", func(t *testing.T, users repos.UserRepo, contacts repos.ContactRepo) {
+		_, err := contacts.Create(context.Background(), 1, &obj.Contact{FirstName: "Ana"})
+		assertKind(t, repos.ErrConflict, err)
+	}},
+	{"contacts can only be reached through their owner", func(t *testing.T, users repos.UserRepo,
+		contacts repos.ContactRepo) {
+		ctx := context.Background()
+		owner := createUser(t, users, "Pedro", "Rocha")
+		other := createUser(t, users, "Rui", "Silva")
+		contact := createContact(t, contacts, owner.ID, "Ana")
+
+		_, err := contacts.Get(ctx, other.ID, int(contact.ID))
+		assertKind(t, repos.ErrNotFound, err)
+
+		_, err = contacts.Update(ctx, other.ID, &obj.Contact{ID: contact.ID, FirstName: "Maria"})
+		assertKind(t, repos.ErrNotFound, err)
+
+		_, err = contacts.Patch(ctx, other.ID, &obj.Contact{ID: contact.ID, FirstName: "Maria"},
+			[]string{"first_name"})
+		assertKind(t, repos.ErrNotFound, err)
+
+		_, err = contacts.Delete(ctx, other.ID, int(contact.ID), 0)
+		assertKind(t, repos.ErrNotFound, err)
+
+		list, page, err := contacts.List(ctx, other.ID, repos.ListOptions{})
+		assert.NoError(t, err)
+		assert.Empty(t, list)
+		assert.Equal(t, 0, page.Total)
+
+		found, err := contacts.Get(ctx, owner.ID, int(contact.ID))
+		assert.NoError(t, err)
+		assertContact(t, contact, found)
+	}},
+	{"update replaces the contact, stamps it and increases it's version", func(t *testing.T, users repos.UserRepo,
+		contacts repos.ContactRepo) {
+		owner := createUser(t, users, "Pedro", "Rocha")
+		created := createContact(t, contacts, owner.ID, "Ana")
+
+		updated, err := contacts.Update(context.Background(), owner.ID, &obj.Contact{ID: created.ID,
+			FirstName: "Maria", Phone: "+351919236587", Version: created.Version})
+		assert.NoError(t, err)
+		assert.Equal(t, "Maria", updated.FirstName)
+		assert.Equal(t, "", updated.Email, "every field should be replaced")
+		assert.Equal(t, "+351919236587", updated.Phone)
+		assert.Equal(t, int64(owner.ID), updated.UserID)
+		assert.True(t, updated.CreatedAt.Equal(created.CreatedAt), "the creation date shouldn't change")
+		assert.False(t, updated.UpdatedAt.Before(created.UpdatedAt), "the update date shouldn't go back")
+		assert.Equal(t, int64(2), updated.Version)
+	}},
+	{"patch only changes the given fields", func(t *testing.T, users repos.UserRepo, contacts repos.ContactRepo) {
+		owner := createUser(t, users, "Pedro", "Rocha")
+		created := createContact(t, contacts, owner.ID, "Ana")
+
+		patched, err := contacts.Patch(context.Background(), owner.ID, &obj.Contact{ID: created.ID,
+			Phone: "+351919236587"}, []string{"phone"})
+		assert.NoError(t, err)
+		assert.Equal(t, "Ana", patched.FirstName)
+		assert.Equal(t, "ana@example.com", patched.Email)
+		assert.Equal(t, "+351919236587", patched.Phone)
+		assert.Equal(t, int64(2), patched.Version)
+	}},
+	{"patch rejects fields that can't be written", func(t *testing.T, users repos.UserRepo,
+		contacts repos.ContactRepo) {
+		owner := createUser(t, users, "Pedro", "Rocha")
+		created := createContact(t, contacts, owner.ID, "Ana")
+
+		_, err := contacts.Patch(context.Background(), owner.ID, &obj.Contact{ID: created.ID, UserID: 2},
+			[]string{"user_id"})
+		assertKind(t, repos.ErrValidation, err)
+
+		found, err := contacts.Get(context.Background(), owner.ID, int(created.ID))
+		assert.NoError(t, err)
+		assertContact(t, created, found)
+	}},
+	{"writes at an outdated version fail their precondition", func(t *testing.T, users repos.UserRepo,
+		contacts repos.ContactRepo) {
+		ctx := context.Background()
+		owner := createUser(t, users, "Pedro", "Rocha")
+		created := createContact(t, contacts, owner.ID, "Ana")
+		_, _ = contacts.Update(ctx, owner.ID, &obj.Contact{ID: created.ID, FirstName: "Maria"})
+
+		_, err := contacts.Update(ctx, owner.ID, &obj.Contact{ID: created.ID, FirstName: "Rui", Version: 1})
+		assertKind(t, repos.ErrPreconditionFailed, err)
+
+		deleted, err := contacts.Delete(ctx, owner.ID, int(created.ID), 1)
+		assert.False(t, deleted)
+		assertKind(t, repos.ErrPreconditionFailed, err)
+	}},
+	{"delete removes the contact", func(t *testing.T, users repos.UserRepo, contacts repos.ContactRepo) {
+		ctx := context.Background()
+		owner := createUser(t, users, "Pedro", "Rocha")
+		created := createContact(t, contacts, owner.ID, "Ana")
+
+		deleted, err := contacts.Delete(ctx, owner.ID, int(created.ID), created.Version)
+		assert.NoError(t, err)
+		assert.True(t, deleted)
+
+		_, err = contacts.Get(ctx, owner.ID, int(created.ID))
+		assertKind(t, repos.ErrNotFound, err)
+	}},
+	{"deleting the owner deletes it's contacts", func(t *testing.T, users repos.UserRepo,
+		contacts repos.ContactRepo) {
+		ctx := context.Background()
+		owner := createUser(t, users, "Pedro", "Rocha")
+		other := createUser(t, users, "Rui", "Silva")
+		created := createContact(t, contacts, owner.ID, "Ana")
+		kept := createContact(t, contacts, other.ID, "Maria")
+
+		_, err := users.Delete(ctx, owner.ID, 0)
+		assert.NoError(t, err)
+
+		_, err = contacts.Get(ctx, owner.ID, int(created.ID))
+		assertKind(t, repos.ErrNotFound, err)
+
+		found, err := contacts.Get(ctx, other.ID, int(kept.ID))
+		assert.NoError(t, err)
+		assertContact(t, kept, found)
+	}},
+	{"list orders the contacts of the owner by creation date", func(t *testing.T, users repos.UserRepo,
+		contacts repos.ContactRepo) {
+		ctx := context.Background()
+		owner := createUser(t, users, "Pedro", "Rocha")
+		other := createUser(t, users, "Rui", "Silva")
+		first := createContact(t, contacts, owner.ID, "Ana")
+		_ = createContact(t, contacts, other.ID, "Sara")
+		second := createContact(t, contacts, owner.ID, "Maria")
+		third := createContact(t, contacts, owner.ID, "Ana")
+
+		list, page, err := contacts.List(ctx, owner.ID, repos.ListOptions{Limit: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{first.ID, second.ID}, contactIDs(list))
+		assert.Equal(t, 3, page.Total)
+		assert.True(t, page.HasNext)
+
+		list, page, err = contacts.List(ctx, owner.ID, repos.ListOptions{
+			Filters: []repos.Filter{{Field: "first_name", Op: repos.Equal, Value: "Ana"}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{first.ID, third.ID}, contactIDs(list))
+		assert.Equal(t, 2, page.Total)
+	}},
+	{"list by users groups the contacts by owner", func(t *testing.T, users repos.UserRepo,
+		contacts repos.ContactRepo) {
+		ctx := context.Background()
+		owner := createUser(t, users, "Pedro", "Rocha")
+		other := createUser(t, users, "Rui", "Silva")
+		without := createUser(t, users, "Sara", "Costa")
+		first := createContact(t, contacts, owner.ID, "Ana")
+		second := createContact(t, contacts, other.ID, "Maria")
+		third := createContact(t, contacts, owner.ID, "Rita")
+
+		grouped, err := contacts.ListByUsers(ctx, []int{owner.ID, other.ID, without.ID})
+		assert.NoError(t, err)
+		assert.Len(t, grouped, 2, "users without contacts shouldn't have an entry")
+		assert.Equal(t, []int64{first.ID, third.ID}, contactIDs(grouped[owner.ID]))
+		assert.Equal(t, []int64{second.ID}, contactIDs(grouped[other.ID]))
+
+		grouped, err = contacts.ListByUsers(ctx, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, grouped)
+	}},
+	{"concurrent creates get distinct ids", func(t *testing.T, users repos.UserRepo, contacts repos.ContactRepo) {
+		owner := createUser(t, users, "Pedro", "Rocha")
+		ids := make(chan int64, concurrency)
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				contact, err := contacts.Create(context.Background(), owner.ID, &obj.Contact{FirstName: "Ana"})
+				if assert.NoError(t, err) {
+					ids <- contact.ID
+				}
+			}()
+		}
+		wg.Wait()
+		close(ids)
+
+		seen := map[int64]bool{}
+		for id := range ids {
+			assert.False(t, seen[id], "id %d was assigned twice", id)
+			seen[id] = true
+		}
+		assert.Len(t, seen, concurrency)
+	}},
+}
+
+// createUser creates a user failing the test when it can't be created
+func createUser(t *testing.T, users repos.UserRepo, firstName, lastName string) *obj.User {
+	user, err := users.Create(context.Background(), &obj.User{FirstName: firstName, LastName: lastName})
+	if err != nil {
+		t.Fatalf("error creating user %s: %s", firstName, err)
+	}
+	return user
+}
+
+// createUsers creates a user for every first name, one after the other
+func createUsers(t *testing.T, users repos.UserRepo, firstNames ...string) []obj.User {
+	created := make([]obj.User, len(firstNames))
+	for i, name := range firstNames {
+		created[i] = *createUser(t, users, name, "Rocha")
+	}
+	return created
+}
+
+// createContact creates a contact owned by the user failing the test when it can't be created
+func createContact(t *testing.T, contacts repos.ContactRepo, userID int, firstName string) *obj.Contact {
+	contact, err := contacts.Create(context.Background(), userID, &obj.Contact{FirstName: firstName,
+		Email: strings.ToLower(firstName) + "@example.com"})
+	if err != nil {
+		t.Fatalf("error creating contact %s: %s", firstName, err)
+	}
+	return contact
+}
+
+func userIDs(users []obj.User) []int {
+	ids := make([]int, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	return ids
+}
+
+func contactIDs(contacts []obj.Contact) []int64 {
+	ids := make([]int64, len(contacts))
+	for i, contact := range contacts {
+		ids[i] = contact.ID
+	}
+	return ids
+}
+
+// assertKind asserts that the error is a failure of the kind
+func assertKind(t *testing.T, kind error, err error) {
+	t.Helper()
+	assert.True(t, errors.Is(err, kind), "expected a %q error, got %v", kind, err)
+}
+
+// assertUser asserts that both users hold the same values, the timestamps are compared as instants as their location
+// depends on the implementation
+func assertUser(t *testing.T, expected, actual *obj.User) {
+	t.Helper()
+	if !assert.NotNil(t, actual) {
+		return
+	}
+	assert.Equal(t, expected.ID, actual.ID)
+	assert.Equal(t, expected.FirstName, actual.FirstName)
+	assert.Equal(t, expected.LastName, actual.LastName)
+	assert.Equal(t, expected.Version, actual.Version)
+	assert.True(t, expected.CreatedAt.Equal(actual.CreatedAt), "created_at doesn't match")
+	assert.True(t, expected.UpdatedAt.Equal(actual.UpdatedAt), "updated_at doesn't match")
+}
+
+// assertContact asserts that both contacts hold the same values, like assertUser
+func assertContact(t *testing.T, expected, actual *obj.Contact) {
+	t.Helper()
+	if !assert.NotNil(t, actual) {
+		return
+	}
+	assert.Equal(t, expected.ID, actual.ID)
+	assert.Equal(t, expected.UserID, actual.UserID)
+	assert.Equal(t, expected.FirstName, actual.FirstName)
+	assert.Equal(t, expected.LastName, actual.LastName)
+	assert.Equal(t, expected.Email, actual.Email)
+	assert.Equal(t, expected.Phone, actual.Phone)
+	assert.Equal(t, expected.Version, actual.Version)
+	assert.True(t, expected.CreatedAt.Equal(actual.CreatedAt), "created_at doesn't match")
+	assert.True(t, expected.UpdatedAt.Equal(actual.UpdatedAt), "updated_at doesn't match")
+}
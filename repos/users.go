@@ -9,28 +9,53 @@ import (
 )
 
 type UserRepo interface {
-	List(ctx context.Context) ([]obj.User, error)
+	List(ctx context.Context, opts ListOptions) ([]obj.User, *PageInfo, error)
 	Create(ctx context.Context, user *obj.User) (*obj.User, error)
 	Update(ctx context.Context, user *obj.User) (*obj.User, error)
+	Patch(ctx context.Context, user *obj.User, fields []string) (*obj.User, error)
 	Get(ctx context.Context, id int) (*obj.User, error)
-	Delete(ctx context.Context, id int) (bool, error)
+	Delete(ctx context.Context, id int, version int64) (bool, error)
 }
 
 type UserRepository struct {
-	db *sql.DB
+	db conn
 }
 
 // NewUserRepository instantiates a new user repository injecting the database connection interface as a dependency
 func NewUserRepository(db *sql.DB) UserRepository {
-	return UserRepository{db}
+	return UserRepository{conn{db}}
 }
 
-// List return a set of users from database
-func (u *UserRepository) List(ctx context.Context) ([]obj.User, error) {
+// scanUser maps the current row of a users query into a user struct
+func scanUser(rows *sql.Rows, user *obj.User) error {
+	return rows.Scan(
+		&user.ID,
+		&user.FirstName,
+		&user.LastName,
+		&user.UpdatedAt,
+		&user.CreatedAt,
+		&user.Version)
+}
+
+// List return a page of users from database, filtered and sorted according to the options
+func (u *UserRepository) List(ctx context.Context, opts ListOptions) ([]obj.User, *PageInfo, error) {
+	query := &selectQuery{table: "users"}
+	err := query.apply(userColumns, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var total int
+	countQuery, countArgs := query.count()
+	err = u.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total)
+	if err != nil {
+		return nil, nil, wrapError("failed to count users in database", err)
+	}
 
-	rows, err := u.db.QueryContext(ctx, "SELECT * FROM \"contactsApi\".\"users\"")
+	pageQuery, pageArgs := query.page(opts)
+	rows, err := u.db.QueryContext(ctx, pageQuery, pageArgs...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch users from database: %s", err)
+		return nil, nil, wrapError("failed to fetch users from database", err)
 	}
 
 	users := []obj.User{}
@@ -38,18 +63,25 @@ func (u *UserRepository) List(ctx context.Context) ([]obj.User, error) {
 	defer rows.Close()
 	for rows.Next() {
 		user := obj.User{}
-		err = rows.Scan(
-			&user.ID,
-			&user.FirstName,
-			&user.LastName,
-			&user.UpdatedAt,
-			&user.CreatedAt)
+		err = scanUser(rows, &user)
 		if err != nil {
-			return nil, fmt.Errorf("failed to map row to user: %s", err)
+			return nil, nil, wrapError("failed to map row to user", err)
 		}
 		users = append(users, user)
 	}
-	return users, nil
+
+	traceRows(ctx, len(users))
+	info := pageInfo(opts, total, len(users))
+	start, end := info.trim(opts, len(users))
+	users = users[start:end]
+
+	if len(users) > 0 {
+		first, last := users[0], users[len(users)-1]
+		info.setCursors(opts, Cursor{ID: first.ID, CreatedAt: first.CreatedAt},
+			Cursor{ID: last.ID, CreatedAt: last.CreatedAt})
+	}
+
+	return users, info, nil
 }
 
 // Creates a user in database
@@ -59,47 +91,85 @@ func (u *UserRepository) Create(ctx context.Context, user *obj.User) (*obj.User,
 		"$2) RETURNING *",
 		user.FirstName, user.LastName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch users from database: %s", err)
+		return nil, wrapError("failed to fetch users from database", err)
 	}
 
 	defer rows.Close()
-	rows.Next()
-	err = rows.Scan(
-		&user.ID,
-		&user.FirstName,
-		&user.LastName,
-		&user.UpdatedAt,
-		&user.CreatedAt)
+	err = nextRow(rows, "failed to insert user in database")
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanUser(rows, user)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to map row to user: %s", err)
+		return nil, wrapError("failed to map row to user", err)
 	}
 
+	traceRows(ctx, 1)
 	return user, nil
 }
 
-// Update
+// Update replaces the user, when user.Version isn't zero the user is only updated while it's still at that version
 func (u *UserRepository) Update(ctx context.Context, user *obj.User) (*obj.User, error) {
 	rows, err := u.db.QueryContext(ctx, "UPDATE \"contactsApi\".\"users\" SET \"firstName\" = $1, "+
-		"\"lastName\" = $2 WHERE id = $3 RETURNING *",
-		user.FirstName, user.LastName, user.ID)
+		"\"lastName\" = $2 WHERE id = $3 AND ($4::bigint = 0 OR version = $4) RETURNING *",
+		user.FirstName, user.LastName, user.ID, user.Version)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch users from database: %s", err)
+		return nil, wrapError("failed to fetch users from database", err)
 	}
 
 	defer rows.Close()
-	rows.Next()
-	err = rows.Scan(
-		&user.ID,
-		&user.FirstName,
-		&user.LastName,
-		&user.UpdatedAt,
-		&user.CreatedAt)
+	err = nextRow(rows, "failed to update user in database")
+	if err != nil {
+		return nil, conditional(err, user.Version)
+	}
 
+	err = scanUser(rows, user)
+
+	if err != nil {
+		return nil, wrapError("failed to map row to user", err)
+	}
+
+	traceRows(ctx, 1)
+	return user, nil
+}
+
+// Patch updates only the columns of the given fields, identified by their json names, leaving the others untouched.
+// Like Update it's conditional on user.Version when it isn't zero.
+func (u *UserRepository) Patch(ctx context.Context, user *obj.User, fields []string) (*obj.User, error) {
+	if len(fields) == 0 {
+		return u.Get(ctx, user.ID)
+	}
+
+	set, args, err := assignments(userColumns, fields, map[string]interface{}{
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	args = append(args, user.ID, user.Version)
+	rows, err := u.db.QueryContext(ctx, fmt.Sprintf("UPDATE \"contactsApi\".\"users\" SET %s WHERE id = $%d "+
+		"AND ($%d::bigint = 0 OR version = $%[3]d) RETURNING *", set, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, wrapError("failed to patch user in database", err)
+	}
+
+	defer rows.Close()
+	err = nextRow(rows, "failed to patch user in database")
 	if err != nil {
-		return nil, fmt.Errorf("failed to map row to user: %s", err)
+		return nil, conditional(err, user.Version)
 	}
 
+	err = scanUser(rows, user)
+
+	if err != nil {
+		return nil, wrapError("failed to map row to user", err)
+	}
+
+	traceRows(ctx, 1)
 	return user, nil
 }
 
@@ -107,40 +177,41 @@ func (u *UserRepository) Update(ctx context.Context, user *obj.User) (*obj.User,
 func (u *UserRepository) Get(ctx context.Context, id int) (*obj.User, error) {
 	rows, err := u.db.QueryContext(ctx, "SELECT * FROM \"contactsApi\".\"users\" WHERE id = $1", id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch users from database: %s", err)
+		return nil, wrapError("failed to fetch users from database", err)
 	}
 
 	user := &obj.User{}
 
 	defer rows.Close()
 
-	rows.Next()
-	err = rows.Scan(
-		&user.ID,
-		&user.FirstName,
-		&user.LastName,
-		&user.UpdatedAt,
-		&user.CreatedAt)
+	err = nextRow(rows, "failed to fetch user from database")
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanUser(rows, user)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to map row to user: %s", err)
+		return nil, wrapError("failed to map row to user", err)
 	}
 
+	traceRows(ctx, 1)
 	return user, nil
 }
 
-// Delete
-func (u *UserRepository) Delete(ctx context.Context, id int) (bool, error) {
-	rows, err := u.db.ExecContext(ctx, "DELETE FROM \"contactsApi\".\"users\" WHERE id = $1", id)
+// Delete deletes the user, when version isn't zero the user is only deleted while it's still at that version
+func (u *UserRepository) Delete(ctx context.Context, id int, version int64) (bool, error) {
+	rows, err := u.db.ExecContext(ctx, "DELETE FROM \"contactsApi\".\"users\" WHERE id = $1 AND "+
+		"($2::bigint = 0 OR version = $2)", id, version)
 	if err != nil {
-		return false, fmt.Errorf("failed to fetch users from database: %s", err)
+		return false, wrapError("failed to fetch users from database", err)
 	}
 
-	_, err = rows.RowsAffected()
+	err = affectedRow(rows, "failed to delete user from database")
 	if err != nil {
-		return false, fmt.Errorf("failed to obtain the number of affected rows: %s", err)
-
+		return false, conditional(err, version)
 	}
 
+	traceRows(ctx, 1)
 	return true, nil
 }
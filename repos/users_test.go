@@ -2,8 +2,10 @@ package repos_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"testing"
 	"time"
 
@@ -42,19 +44,23 @@ func TestUserRepository_List(t *testing.T) {
 		}
 		defer db.Close()
 
-		rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at"}).
+		rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at", "version"}).
 			AddRow(storedUsers[0].ID, storedUsers[0].FirstName, storedUsers[0].LastName, storedUsers[0].UpdatedAt,
-				storedUsers[0].CreatedAt).
+				storedUsers[0].CreatedAt, storedUsers[0].Version).
 			AddRow(storedUsers[1].ID, storedUsers[1].FirstName, storedUsers[1].LastName, storedUsers[1].UpdatedAt,
-				storedUsers[1].CreatedAt)
+				storedUsers[1].CreatedAt, storedUsers[1].Version)
 
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
 		mock.ExpectQuery("SELECT").WillReturnRows(rows)
 
 		userRepo := repos.NewUserRepository(db)
 
-		users, _ := userRepo.List(context.Background())
+		users, page, _ := userRepo.List(context.Background(), repos.ListOptions{})
 
 		assert.ElementsMatch(t, users, storedUsers, "List's don't match")
+		assert.Equal(t, 2, page.Total, "Total doesn't match")
+		assert.False(t, page.HasNext, "There shouldn't be a next page")
+		assert.False(t, page.HasPrev, "There shouldn't be a previous page")
 
 	})
 
@@ -65,11 +71,12 @@ func TestUserRepository_List(t *testing.T) {
 		}
 		defer db.Close()
 
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
 		mock.ExpectQuery("SELECT").WillReturnError(fmt.Errorf("error"))
 
 		userRepo := repos.NewUserRepository(db)
 
-		_, err = userRepo.List(context.Background())
+		_, _, err = userRepo.List(context.Background(), repos.ListOptions{})
 
 		assert.Error(t, err, "should have returned an error")
 		assert.Contains(t, err.Error(), "error", "Error message doesn't match")
@@ -83,15 +90,16 @@ func TestUserRepository_List(t *testing.T) {
 		}
 		defer db.Close()
 
-		rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at"}).
+		rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at", "version"}).
 			AddRow(nil, storedUsers[0].FirstName, storedUsers[0].LastName, storedUsers[0].UpdatedAt,
-				storedUsers[0].CreatedAt)
+				storedUsers[0].CreatedAt, storedUsers[0].Version)
 
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 		mock.ExpectQuery("SELECT").WillReturnRows(rows)
 
 		userRepo := repos.NewUserRepository(db)
 
-		_, err = userRepo.List(context.Background())
+		_, _, err = userRepo.List(context.Background(), repos.ListOptions{})
 
 		log.Println(err)
 
@@ -100,6 +108,92 @@ func TestUserRepository_List(t *testing.T) {
 
 	})
 
+	t.Run("test that the extra row fetched to detect a next page isn't returned", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at", "version"}).
+			AddRow(storedUsers[0].ID, storedUsers[0].FirstName, storedUsers[0].LastName, storedUsers[0].UpdatedAt,
+				storedUsers[0].CreatedAt, storedUsers[0].Version).
+			AddRow(storedUsers[1].ID, storedUsers[1].FirstName, storedUsers[1].LastName, storedUsers[1].UpdatedAt,
+				storedUsers[1].CreatedAt, storedUsers[1].Version)
+
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery("SELECT (.+) ORDER BY created_at, id LIMIT \\$1 OFFSET \\$2").WithArgs(2, 0).
+			WillReturnRows(rows)
+
+		userRepo := repos.NewUserRepository(db)
+
+		users, page, err := userRepo.List(context.Background(), repos.ListOptions{Limit: 1})
+
+		assert.NoError(t, err)
+		assert.Equal(t, storedUsers[:1], users, "List's don't match")
+		assert.True(t, page.HasNext, "There should be a next page")
+		assert.Equal(t, &repos.Cursor{ID: storedUsers[0].ID, CreatedAt: storedUsers[0].CreatedAt}, page.Next)
+		assert.Nil(t, page.Prev, "There shouldn't be a previous page")
+	})
+
+	t.Run("test that a cursor resumes the listing after the row it points to", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at", "version"}).
+			AddRow(storedUsers[1].ID, storedUsers[1].FirstName, storedUsers[1].LastName, storedUsers[1].UpdatedAt,
+				storedUsers[1].CreatedAt, storedUsers[1].Version)
+
+		cursor := &repos.Cursor{ID: storedUsers[0].ID, CreatedAt: storedUsers[0].CreatedAt}
+
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery("SELECT (.+) WHERE \\(created_at, id\\) > \\(\\$1, \\$2\\)").
+			WithArgs(cursor.CreatedAt, cursor.ID, 2).
+			WillReturnRows(rows)
+
+		userRepo := repos.NewUserRepository(db)
+
+		users, page, err := userRepo.List(context.Background(), repos.ListOptions{Limit: 1, Cursor: cursor})
+
+		assert.NoError(t, err)
+		assert.Equal(t, storedUsers[1:], users, "List's don't match")
+		assert.False(t, page.HasNext, "There shouldn't be a next page")
+		assert.Equal(t, &repos.Cursor{ID: storedUsers[1].ID, CreatedAt: storedUsers[1].CreatedAt, Before: true},
+			page.Prev)
+	})
+
+	t.Run("test that a cursor pointing backwards keeps the rows closest to it", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at", "version"}).
+			AddRow(storedUsers[0].ID, storedUsers[0].FirstName, storedUsers[0].LastName, storedUsers[0].UpdatedAt,
+				storedUsers[0].CreatedAt, storedUsers[0].Version).
+			AddRow(storedUsers[1].ID, storedUsers[1].FirstName, storedUsers[1].LastName, storedUsers[1].UpdatedAt,
+				storedUsers[1].CreatedAt, storedUsers[1].Version)
+
+		cursor := &repos.Cursor{ID: 3, CreatedAt: time.Now(), Before: true}
+
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery("WHERE \\(created_at, id\\) < \\(\\$1, \\$2\\) ORDER BY created_at DESC").
+			WillReturnRows(rows)
+
+		userRepo := repos.NewUserRepository(db)
+
+		users, page, err := userRepo.List(context.Background(), repos.ListOptions{Limit: 1, Cursor: cursor})
+
+		assert.NoError(t, err)
+		assert.Equal(t, storedUsers[1:], users, "List's don't match")
+		assert.True(t, page.HasPrev, "There should be a previous page")
+		assert.True(t, page.HasNext, "There should be a next page")
+	})
+
 }
 
 func TestUserRepository_Create(t *testing.T) {
@@ -122,9 +216,9 @@ func TestUserRepository_Create(t *testing.T) {
 		}
 		defer db.Close()
 
-		rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at"}).
+		rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at", "version"}).
 			AddRow(1, storedUser.FirstName, storedUser.LastName, storedUser.UpdatedAt,
-			storedUser.CreatedAt)
+			storedUser.CreatedAt, storedUser.Version)
 
 		mock.ExpectQuery("INSERT").WillReturnRows(rows)
 
@@ -172,9 +266,9 @@ func TestUserRepository_Create(t *testing.T) {
 		}
 		defer db.Close()
 
-		rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at"}).
+		rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at", "version"}).
 			AddRow(nil, storedUser.FirstName, storedUser.LastName, storedUser.UpdatedAt,
-				storedUser.CreatedAt)
+				storedUser.CreatedAt, storedUser.Version)
 
 		mock.ExpectQuery("INSERT").WillReturnRows(rows)
 
@@ -210,9 +304,9 @@ func TestUserRepository_Update(t *testing.T) {
 		}
 		defer db.Close()
 
-		rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at"}).
+		rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at", "version"}).
 			AddRow(1, storedUser.FirstName, storedUser.LastName, storedUser.UpdatedAt,
-				storedUser.CreatedAt)
+				storedUser.CreatedAt, storedUser.Version)
 
 		mock.ExpectQuery("UPDATE").WillReturnRows(rows)
 
@@ -261,9 +355,9 @@ func TestUserRepository_Update(t *testing.T) {
 			}
 			defer db.Close()
 
-			rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at"}).
+			rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at", "version"}).
 				AddRow(nil, storedUser.FirstName, storedUser.LastName, storedUser.UpdatedAt,
-					storedUser.CreatedAt)
+					storedUser.CreatedAt, storedUser.Version)
 
 			mock.ExpectQuery("UPDATE").WillReturnRows(rows)
 
@@ -274,8 +368,114 @@ func TestUserRepository_Update(t *testing.T) {
 			assert.Error(t, err, "should have returned an error")
 			assert.Contains(t, err.Error(), "failed to map row to user", "Error message doesn't match")
 
+			assert.False(t, errors.Is(err, repos.ErrNotFound), "a mapping failure isn't a missing user")
+
 		})
 
+	t.Run("updating a user that changed since the expected version fails the precondition", func(t *testing.T) {
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("UPDATE (.+) WHERE id = \\$3 AND \\(\\$4::bigint = 0 OR version = \\$4\\)").
+			WithArgs("John", "Cena", 1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at", "version"}))
+
+		userRepo := repos.NewUserRepository(db)
+
+		_, err = userRepo.Update(context.Background(), &obj.User{ID: 1, FirstName: "John", LastName: "Cena", Version: 2})
+
+		assert.True(t, errors.Is(err, repos.ErrPreconditionFailed), "should have returned a precondition error")
+		assert.False(t, errors.Is(err, repos.ErrNotFound), "a changed user isn't reported as missing")
+	})
+
+	t.Run("a missing user is reported as not found", func(t *testing.T) {
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT").WillReturnRows(
+			sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at", "version"}))
+
+		userRepo := repos.NewUserRepository(db)
+
+		_, err = userRepo.Get(context.Background(), 1)
+
+		assert.True(t, errors.Is(err, repos.ErrNotFound), "should have returned a not found error")
+	})
+
+	t.Run("a database outage is reported as unavailable", func(t *testing.T) {
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		cause := &net.OpError{Op: "dial", Net: "tcp", Err: fmt.Errorf("connection refused")}
+		mock.ExpectQuery("SELECT").WillReturnError(cause)
+
+		userRepo := repos.NewUserRepository(db)
+
+		_, err = userRepo.Get(context.Background(), 1)
+
+		assert.True(t, errors.Is(err, repos.ErrUnavailable), "should have returned an unavailable error")
+		assert.False(t, errors.Is(err, repos.ErrNotFound), "an outage isn't a missing user")
+		assert.True(t, errors.Is(err, cause), "the cause should be wrapped")
+	})
+
+}
+
+func TestUserRepository_Patch(t *testing.T) {
+
+	t.Run("only the columns of the patched fields are updated", func(t *testing.T) {
+		storedUser := obj.User{ID: 1, FirstName: "John", LastName: "Cena", CreatedAt: time.Now(),
+			UpdatedAt: time.Now()}
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at", "version"}).
+			AddRow(1, storedUser.FirstName, storedUser.LastName, storedUser.UpdatedAt, storedUser.CreatedAt, storedUser.Version)
+
+		mock.ExpectQuery("UPDATE \"contactsApi\".\"users\" SET \"firstName\" = \\$1 WHERE id = \\$2").
+			WithArgs("John", 1, 0).
+			WillReturnRows(rows)
+
+		userRepo := repos.NewUserRepository(db)
+
+		user, err := userRepo.Patch(context.Background(), &obj.User{ID: 1, FirstName: "John"},
+			[]string{"first_name"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, storedUser, *user, "Results's don't match")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("read-only fields can't be patched", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		userRepo := repos.NewUserRepository(db)
+
+		_, err = userRepo.Patch(context.Background(), &obj.User{ID: 1}, []string{"created_at"})
+
+		assert.Error(t, err, "should have returned an error")
+		assert.Contains(t, err.Error(), "failed to patch field created_at", "Error message doesn't match")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 func TestUserRepository_Get(t *testing.T) {
@@ -299,9 +499,9 @@ func TestUserRepository_Get(t *testing.T) {
 		}
 		defer db.Close()
 
-		rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at"}).
+		rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at", "version"}).
 			AddRow(1, storedUser.FirstName, storedUser.LastName, storedUser.UpdatedAt,
-				storedUser.CreatedAt)
+				storedUser.CreatedAt, storedUser.Version)
 
 
 		mock.ExpectQuery("SELECT").WillReturnRows(rows)
@@ -350,9 +550,9 @@ func TestUserRepository_Get(t *testing.T) {
 			}
 			defer db.Close()
 
-			rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at"}).
+			rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "updated_at", "created_at", "version"}).
 				AddRow(nil, storedUser.FirstName, storedUser.LastName, storedUser.UpdatedAt,
-					storedUser.CreatedAt)
+					storedUser.CreatedAt, storedUser.Version)
 
 
 			mock.ExpectQuery("SELECT").WillReturnRows(rows)
@@ -382,19 +582,37 @@ func TestUserRepository_Delete(t *testing.T) {
 		}
 		defer db.Close()
 
-		result := sqlmock.NewResult(0, 0)
+		result := sqlmock.NewResult(0, 1)
 
 
-		mock.ExpectExec("DELETE").WithArgs(1).WillReturnResult(result)
+		mock.ExpectExec("DELETE").WithArgs(1, 0).WillReturnResult(result)
 
 		userRepo := repos.NewUserRepository(db)
 
-		users, _ := userRepo.Delete(context.Background(), 1)
+		users, _ := userRepo.Delete(context.Background(), 1, 0)
 
 		assert.True(t, users, "Shoudl have returned a value of true")
 
 	})
 
+	t.Run("deleting an unexisting user is reported as not found", func(t *testing.T) {
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error while opening a new database connection")
+		}
+		defer db.Close()
+
+		mock.ExpectExec("DELETE").WithArgs(1, 0).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		userRepo := repos.NewUserRepository(db)
+
+		deleted, err := userRepo.Delete(context.Background(), 1, 0)
+
+		assert.False(t, deleted)
+		assert.True(t, errors.Is(err, repos.ErrNotFound), "should have returned a not found error")
+	})
+
 	t.Run("handle a error while executing the delete query", func(t *testing.T) {
 
 		db, mock, err := sqlmock.New()
@@ -403,11 +621,11 @@ func TestUserRepository_Delete(t *testing.T) {
 		}
 		defer db.Close()
 
-		mock.ExpectExec("DELETE").WithArgs(1).WillReturnError(fmt.Errorf("error"))
+		mock.ExpectExec("DELETE").WithArgs(1, 0).WillReturnError(fmt.Errorf("error"))
 
 		userRepo := repos.NewUserRepository(db)
 
-		_, err = userRepo.Delete(context.Background(), 1)
+		_, err = userRepo.Delete(context.Background(), 1, 0)
 
 		assert.Error(t, err, "should have returned an error")
 		assert.Contains(t, err.Error(), "error", "Error message doesn't match")
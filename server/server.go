@@ -0,0 +1,148 @@
+// Package server runs the contactsApi http server, applying timeouts to every connection and draining in-flight
+// requests before the process exits.
+package server
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Server wraps an http.Server that stops gracefully when it receives SIGINT or SIGTERM
+type Server struct {
+	http            *http.Server
+	shutdownTimeout time.Duration
+	closers         []io.Closer
+	signals         []os.Signal
+}
+
+// Option type func used to configure the server implementing the Functional Options pattern
+type Option func(s *Server)
+
+// WithReadTimeout set's the maximum duration for reading an entire request, including the body
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.http.ReadTimeout = timeout
+	}
+}
+
+// WithReadHeaderTimeout set's the maximum duration for reading the request headers
+func WithReadHeaderTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.http.ReadHeaderTimeout = timeout
+	}
+}
+
+// WithWriteTimeout set's the maximum duration before timing out writes of the response
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.http.WriteTimeout = timeout
+	}
+}
+
+// WithIdleTimeout set's the maximum amount of time to wait for the next request on a keep-alive connection
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.http.IdleTimeout = timeout
+	}
+}
+
+// WithShutdownTimeout set's how long in-flight requests are given to finish once the server starts shutting down
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.shutdownTimeout = timeout
+	}
+}
+
+// WithCloser registers a resource, like the database connection pool, that is closed only after the in-flight
+// requests finished. Resources are closed in the order they were registered.
+func WithCloser(closer io.Closer) Option {
+	return func(s *Server) {
+		s.closers = append(s.closers, closer)
+	}
+}
+
+// withSignals replaces the signals that trigger the shutdown, it's used by the tests
+func withSignals(signals ...os.Signal) Option {
+	return func(s *Server) {
+		s.signals = signals
+	}
+}
+
+// New instantiates a server that listens on addr and serves the requests with the handler
+func New(addr string, handler http.Handler, opts ...Option) *Server {
+	s := &Server{
+		http:            &http.Server{Addr: addr, Handler: handler},
+		shutdownTimeout: 30 * time.Second,
+		signals:         []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Run listens on the server address and serves requests until the context is cancelled or a shutdown signal is
+// received, see Serve.
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.http.Addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves requests accepted by the listener until the context is cancelled or a shutdown signal is received.
+// The server then stops accepting connections and waits up to the shutdown timeout for the in-flight requests to
+// finish, connections still active after that are closed. The registered closers are closed last.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, s.signals...)
+	defer signal.Stop(signals)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.http.Serve(listener)
+	}()
+
+	select {
+	case err := <-errs:
+		s.close()
+		return err
+	case <-ctx.Done():
+		log.Println("Shutting down the webserver")
+	case sig := <-signals:
+		log.Printf("Received %s, shutting down the webserver", sig)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	err := s.http.Shutdown(shutdownCtx)
+	if err != nil {
+		log.Printf("In-flight requests didn't finish in %s, closing their connections: %s", s.shutdownTimeout, err)
+		_ = s.http.Close()
+	}
+
+	s.close()
+	return nil
+}
+
+// close closes every registered closer logging the ones that fail
+func (s *Server) close() {
+	for _, closer := range s.closers {
+		err := closer.Close()
+		if err != nil {
+			log.Printf("Failed to close resource: %s", err)
+		}
+	}
+}
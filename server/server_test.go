@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingCloser records when it was closed
+type recordingCloser struct {
+	mu     sync.Mutex
+	closed bool
+}
+
+func (c *recordingCloser) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *recordingCloser) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+func TestNew(t *testing.T) {
+
+	t.Run("options are applied to the http server", func(t *testing.T) {
+		s := New(":3000", http.NotFoundHandler(),
+			WithReadTimeout(time.Second),
+			WithReadHeaderTimeout(2*time.Second),
+			WithWriteTimeout(3*time.Second),
+			WithIdleTimeout(4*time.Second),
+			WithShutdownTimeout(5*time.Second),
+		)
+
+		assert.Equal(t, ":3000", s.http.Addr)
+		assert.Equal(t, time.Second, s.http.ReadTimeout)
+		assert.Equal(t, 2*time.Second, s.http.ReadHeaderTimeout)
+		assert.Equal(t, 3*time.Second, s.http.WriteTimeout)
+		assert.Equal(t, 4*time.Second, s.http.IdleTimeout)
+		assert.Equal(t, 5*time.Second, s.shutdownTimeout)
+	})
+}
+
+func TestServer_Serve(t *testing.T) {
+
+	t.Run("in-flight requests finish before the closers are closed", func(t *testing.T) {
+		closer := &recordingCloser{}
+		started := make(chan struct{})
+		closedDuringRequest := true
+
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			closedDuringRequest = closer.isClosed()
+			_, _ = w.Write([]byte("done"))
+		})
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("error while listening: %s", err)
+		}
+
+		s := New("", handler, WithCloser(closer), WithShutdownTimeout(5*time.Second))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		served := make(chan error, 1)
+		go func() {
+			served <- s.Serve(ctx, listener)
+		}()
+
+		responses := make(chan string, 1)
+		go func() {
+			response, err := http.Get("http://" + listener.Addr().String())
+			if err != nil {
+				responses <- err.Error()
+				return
+			}
+			defer response.Body.Close()
+			body, _ := ioutil.ReadAll(response.Body)
+			responses <- string(body)
+		}()
+
+		<-started
+		cancel()
+
+		assert.Equal(t, "done", <-responses, "the in-flight request should have been completed")
+		assert.NoError(t, <-served)
+		assert.False(t, closedDuringRequest, "closers shouldn't be closed while requests are in-flight")
+		assert.True(t, closer.isClosed(), "closers should be closed once the server stops")
+	})
+
+	t.Run("a shutdown signal stops the server", func(t *testing.T) {
+		closer := &recordingCloser{}
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("error while listening: %s", err)
+		}
+
+		s := New("", http.NotFoundHandler(), WithCloser(closer), withSignals(syscall.SIGUSR1))
+
+		served := make(chan error, 1)
+		go func() {
+			served <- s.Serve(context.Background(), listener)
+		}()
+
+		// Waits for the server to accept connections so that the signal isn't sent before it's being handled
+		response, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			t.Fatalf("error while requesting the server: %s", err)
+		}
+		response.Body.Close()
+
+		err = syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+		if err != nil {
+			t.Fatalf("error while sending the signal: %s", err)
+		}
+
+		select {
+		case err := <-served:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("the server didn't stop after receiving the signal")
+		}
+
+		assert.True(t, closer.isClosed(), "closers should be closed once the server stops")
+	})
+}
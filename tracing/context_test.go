@@ -0,0 +1,75 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/tracing"
+	"github.com/pedrorochaorg/contactsApi/tracing/tracingtest"
+)
+
+func TestParseTraceparent(t *testing.T) {
+
+	t.Run("parse a sampled span context", func(t *testing.T) {
+		sc, err := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+		assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+		assert.True(t, sc.Sampled)
+		assert.True(t, sc.Remote)
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+	})
+
+	t.Run("parse future versions as version 00", func(t *testing.T) {
+		sc, err := tracing.ParseTraceparent("cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra")
+
+		assert.NoError(t, err)
+		assert.False(t, sc.Sampled)
+	})
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+	}
+	for _, value := range invalid {
+		_, err := tracing.ParseTraceparent(value)
+		assert.Error(t, err, "%q should be invalid", value)
+	}
+}
+
+func TestExtract(t *testing.T) {
+
+	tracer := tracing.NewTracer(&tracingtest.Recorder{})
+	defer tracer.Shutdown(context.Background())
+
+	header := http.Header{}
+	header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	header.Set(tracing.TracestateHeader, "vendor=value")
+
+	ctx, span := tracer.Start(tracing.Extract(context.Background(), header), "GET /users")
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID.String(),
+		"The span continues the propagated trace")
+
+	outgoing := http.Header{}
+	tracing.Inject(ctx, outgoing)
+
+	sc, err := tracing.ParseTraceparent(outgoing.Get(tracing.TraceparentHeader))
+	assert.NoError(t, err)
+	assert.Equal(t, span.SpanContext().SpanID, sc.SpanID, "The span is propagated as the parent")
+	assert.Equal(t, "vendor=value", outgoing.Get(tracing.TracestateHeader))
+
+	ignored := tracing.Extract(context.Background(), http.Header{tracing.TraceparentHeader: {"invalid"}})
+	_, root := tracer.Start(ignored, "GET /users")
+	assert.NotEqual(t, "4bf92f3577b34da6a3ce929d0e0e4736", root.SpanContext().TraceID.String())
+}
@@ -0,0 +1,295 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Exporter sends finished spans to a tracing backend
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+// WriterExporter writes every span as a JSON line, to the standard output or a file for example
+type WriterExporter struct {
+	mu     sync.Mutex
+	out    io.Writer
+	closer io.Closer
+}
+
+// NewWriterExporter instantiates an exporter that writes the spans to out
+func NewWriterExporter(out io.Writer) *WriterExporter {
+	return &WriterExporter{out: out}
+}
+
+// NewFileExporter instantiates an exporter that appends the spans to the file, which is created when it doesn't
+// exist and closed when the exporter is shut down
+func NewFileExporter(path string) (*WriterExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the traces file: %s", err)
+	}
+	return &WriterExporter{out: file, closer: file}, nil
+}
+
+// writtenSpan JSON representation of the spans written by the WriterExporter
+type writtenSpan struct {
+	TraceID       string                 `json:"trace_id"`
+	SpanID        string                 `json:"span_id"`
+	ParentSpanID  string                 `json:"parent_span_id,omitempty"`
+	Name          string                 `json:"name"`
+	Kind          string                 `json:"kind"`
+	Start         time.Time              `json:"start"`
+	End           time.Time              `json:"end"`
+	DurationMs    float64                `json:"duration_ms"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty"`
+	Status        string                 `json:"status"`
+	StatusMessage string                 `json:"status_message,omitempty"`
+}
+
+var statusNames = map[StatusCode]string{StatusUnset: "unset", StatusOK: "ok", StatusError: "error"}
+
+func (w *WriterExporter) Export(ctx context.Context, spans []SpanData) error {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+
+	for _, span := range spans {
+		written := writtenSpan{
+			TraceID:       span.Context.TraceID.String(),
+			SpanID:        span.Context.SpanID.String(),
+			Name:          span.Name,
+			Kind:          span.Kind.String(),
+			Start:         span.Start.UTC(),
+			End:           span.End.UTC(),
+			DurationMs:    float64(span.End.Sub(span.Start)) / float64(time.Millisecond),
+			Status:        statusNames[span.Status],
+			StatusMessage: span.StatusMessage,
+		}
+		if span.Parent.IsValid() {
+			written.ParentSpanID = span.Parent.String()
+		}
+		if len(span.Attributes) > 0 {
+			written.Attributes = make(map[string]interface{}, len(span.Attributes))
+			for _, attribute := range span.Attributes {
+				written.Attributes[attribute.Key] = attribute.Value
+			}
+		}
+
+		err := encoder.Encode(written)
+		if err != nil {
+			return fmt.Errorf("failed to encode span %s: %s", span.Name, err)
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, err := buf.WriteTo(w.out)
+	if err != nil {
+		return fmt.Errorf("failed to write spans: %s", err)
+	}
+	return nil
+}
+
+func (w *WriterExporter) Shutdown(ctx context.Context) error {
+	if w.closer == nil {
+		return nil
+	}
+	return w.closer.Close()
+}
+
+// OTLPExporter sends the spans to an OpenTelemetry collector with the OTLP/HTTP protocol, encoded as JSON
+type OTLPExporter struct {
+	endpoint    string
+	headers     map[string]string
+	serviceName string
+	client      *http.Client
+}
+
+// OTLPOption configures the OTLPExporter
+type OTLPOption func(o *OTLPExporter)
+
+// WithHeaders adds the headers, like the credentials of the collector, to every export request
+func WithHeaders(headers map[string]string) OTLPOption {
+	return func(o *OTLPExporter) {
+		o.headers = headers
+	}
+}
+
+// WithServiceName sets the service.name resource attribute of the spans, by default contactsApi
+func WithServiceName(name string) OTLPOption {
+	return func(o *OTLPExporter) {
+		o.serviceName = name
+	}
+}
+
+// WithHTTPClient sends the export requests with the client, by default a client with a 10 seconds timeout
+func WithHTTPClient(client *http.Client) OTLPOption {
+	return func(o *OTLPExporter) {
+		o.client = client
+	}
+}
+
+// NewOTLPExporter instantiates an exporter that posts the spans to the traces endpoint of a collector, like
+// http://localhost:4318/v1/traces
+func NewOTLPExporter(endpoint string, opts ...OTLPOption) *OTLPExporter {
+	exporter := &OTLPExporter{
+		endpoint:    endpoint,
+		serviceName: "contactsApi",
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(exporter)
+	}
+	return exporter
+}
+
+// Types of the OTLP JSON encoding of an export request
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		TraceState        string          `json:"traceState,omitempty"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              SpanKind        `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+
+	otlpStatus struct {
+		Code    StatusCode `json:"code,omitempty"`
+		Message string     `json:"message,omitempty"`
+	}
+)
+
+// ScopeName instrumentation scope of the spans sent by the OTLP exporter
+const ScopeName = "github.com/pedrorochaorg/contactsApi"
+
+func (o *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	request := otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{
+			{Key: "service.name", Value: otlpAttributeValue(o.serviceName)},
+		}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: ScopeName}, Spans: make([]otlpSpan, len(spans))}},
+	}}}
+
+	for i, span := range spans {
+		encoded := otlpSpan{
+			TraceID:           span.Context.TraceID.String(),
+			SpanID:            span.Context.SpanID.String(),
+			TraceState:        span.Context.TraceState,
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Status:            otlpStatus{Code: span.Status, Message: span.StatusMessage},
+		}
+		if span.Parent.IsValid() {
+			encoded.ParentSpanID = span.Parent.String()
+		}
+		for _, attribute := range span.Attributes {
+			encoded.Attributes = append(encoded.Attributes,
+				otlpAttribute{Key: attribute.Key, Value: otlpAttributeValue(attribute.Value)})
+		}
+		request.ResourceSpans[0].ScopeSpans[0].Spans[i] = encoded
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %s", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, o.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build the export request: %s", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range o.headers {
+		req.Header.Set(name, value)
+	}
+
+	response, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %s", err)
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("failed to export spans: the collector replied %s", response.Status)
+	}
+	return nil
+}
+
+func (o *OTLPExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// otlpAttributeValue encodes an attribute value, values of other types are sent as their fmt representation
+func otlpAttributeValue(value interface{}) otlpValue {
+	switch v := value.(type) {
+	case string:
+		return otlpValue{StringValue: &v}
+	case bool:
+		return otlpValue{BoolValue: &v}
+	case int:
+		s := strconv.Itoa(v)
+		return otlpValue{IntValue: &s}
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		return otlpValue{IntValue: &s}
+	case float64:
+		return otlpValue{DoubleValue: &v}
+	default:
+		s := fmt.Sprint(v)
+		return otlpValue{StringValue: &s}
+	}
+}
@@ -0,0 +1,353 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pedrorochaorg/contactsApi/logging"
+)
+
+// SpanKind role of a span in the trace
+type SpanKind int
+
+// Span kinds, with the values used by OTLP
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case KindServer:
+		return "server"
+	case KindClient:
+		return "client"
+	default:
+		return "internal"
+	}
+}
+
+// StatusCode outcome of a span, with the values used by OTLP
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+// Attribute a key and a value describing a span, values are strings, bools, integers or floats
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// SpanData a finished span, as handed to the exporters
+type SpanData struct {
+	Name          string
+	Kind          SpanKind
+	Context       SpanContext
+	Parent        SpanID
+	Start         time.Time
+	End           time.Time
+	Attributes    []Attribute
+	Status        StatusCode
+	StatusMessage string
+}
+
+// Span an operation of a trace, it's exported when it ends and can't be changed afterwards. Every method of a nil span
+// does nothing.
+type Span struct {
+	mu     sync.Mutex
+	tracer *Tracer
+	data   SpanData
+	ended  bool
+}
+
+// SpanContext returns the span context that identifies the span
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.Context
+}
+
+// SetName replaces the name the span was started with
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Name = name
+	}
+}
+
+// SetAttribute sets the value of the attribute, replacing the previous value of the key
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+
+	for i, attribute := range s.data.Attributes {
+		if attribute.Key == key {
+			s.data.Attributes[i].Value = value
+			return
+		}
+	}
+	s.data.Attributes = append(s.data.Attributes, Attribute{Key: key, Value: value})
+}
+
+// Attribute returns the value of the attribute, it's nil when the span doesn't have it
+func (s *Span) Attribute(key string) interface{} {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, attribute := range s.data.Attributes {
+		if attribute.Key == key {
+			return attribute.Value
+		}
+	}
+	return nil
+}
+
+// SetError marks the span as failed with the message of the error
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.data.Status = StatusError
+	s.data.StatusMessage = err.Error()
+}
+
+// End finishes the span and hands it to the tracer for exporting, calls after the first one do nothing
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = s.tracer.now()
+	data := s.data
+	s.mu.Unlock()
+
+	if data.Context.Sampled {
+		s.tracer.enqueue(data)
+	}
+}
+
+// SpanOption configures a span when it's started
+type SpanOption func(s *SpanData)
+
+// WithKind sets the kind of the span, spans are internal by default
+func WithKind(kind SpanKind) SpanOption {
+	return func(s *SpanData) {
+		s.Kind = kind
+	}
+}
+
+// WithAttributes sets attributes of the span when it's started
+func WithAttributes(attributes ...Attribute) SpanOption {
+	return func(s *SpanData) {
+		s.Attributes = append(s.Attributes, attributes...)
+	}
+}
+
+// Tracer starts spans and exports them in batches once they end, it's safe to use by concurrent goroutines
+type Tracer struct {
+	exporter     Exporter
+	batchSize    int
+	batchTimeout time.Duration
+	now          func() time.Time
+	onError      func(err error)
+
+	mu      sync.Mutex
+	batch   []SpanData
+	flush   chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// Option configures the Tracer
+type Option func(t *Tracer)
+
+// WithBatchSize exports the spans as soon as the number of finished spans reaches size, by default 512
+func WithBatchSize(size int) Option {
+	return func(t *Tracer) {
+		t.batchSize = size
+	}
+}
+
+// WithBatchTimeout exports the finished spans at least as often as the timeout, by default every 5 seconds
+func WithBatchTimeout(timeout time.Duration) Option {
+	return func(t *Tracer) {
+		t.batchTimeout = timeout
+	}
+}
+
+// WithClock sets the function that returns the start and end times of the spans
+func WithClock(now func() time.Time) Option {
+	return func(t *Tracer) {
+		t.now = now
+	}
+}
+
+// WithErrorHandler sets the function called with the errors of the exports done in the background, by default they're
+// logged
+func WithErrorHandler(onError func(err error)) Option {
+	return func(t *Tracer) {
+		t.onError = onError
+	}
+}
+
+// NewTracer instantiates a tracer that exports the spans with the exporter, it must be shut down to export the last
+// spans
+func NewTracer(exporter Exporter, opts ...Option) *Tracer {
+	tracer := &Tracer{
+		exporter:     exporter,
+		batchSize:    512,
+		batchTimeout: 5 * time.Second,
+		now:          time.Now,
+		onError:      logError,
+		flush:        make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(tracer)
+	}
+
+	go tracer.run()
+	return tracer
+}
+
+// Start starts a span that is a child of the span of the context, or of the span propagated by the caller of the
+// service, and returns a copy of the context that carries it. Spans without a parent start a new trace, which is
+// always sampled, while the others follow the sampling decision of their parent.
+func (t *Tracer) Start(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	data := SpanData{Name: name, Kind: KindInternal, Start: t.now()}
+	for _, opt := range opts {
+		opt(&data)
+	}
+
+	var parent SpanContext
+	if span := SpanFromContext(ctx); span != nil {
+		parent = span.SpanContext()
+	} else if remote, ok := remoteFromContext(ctx); ok {
+		parent = remote
+	}
+
+	if parent.IsValid() {
+		data.Context = SpanContext{TraceID: parent.TraceID, SpanID: newSpanID(), Sampled: parent.Sampled,
+			TraceState: parent.TraceState}
+		data.Parent = parent.SpanID
+	} else {
+		data.Context = SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}
+	}
+
+	span := &Span{tracer: t, data: data}
+	return ContextWithSpan(ctx, span), span
+}
+
+// enqueue adds a finished span to the batch, waking up the exporting goroutine when the batch is full
+func (t *Tracer) enqueue(data SpanData) {
+	t.mu.Lock()
+	t.batch = append(t.batch, data)
+	full := len(t.batch) >= t.batchSize
+	t.mu.Unlock()
+
+	if full {
+		select {
+		case t.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run exports the batches until the tracer is shut down
+func (t *Tracer) run() {
+	defer close(t.stopped)
+
+	ticker := time.NewTicker(t.batchTimeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-t.flush:
+		case <-t.stop:
+			return
+		}
+		err := t.export(context.Background())
+		if err != nil {
+			t.onError(err)
+		}
+	}
+}
+
+func logError(err error) {
+	ctx := context.Background()
+	logging.FromContext(ctx).Error(ctx, "failed to export spans", logging.Fields{"error": err})
+}
+
+// export hands the spans finished so far to the exporter
+func (t *Tracer) export(ctx context.Context) error {
+	t.mu.Lock()
+	batch := t.batch
+	t.batch = nil
+	t.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	return t.exporter.Export(ctx, batch)
+}
+
+// Shutdown exports the spans that are still waiting in the batch and shuts the exporter down, the spans that end
+// afterwards aren't exported
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.once.Do(func() {
+		close(t.stop)
+	})
+	<-t.stopped
+
+	err := t.export(ctx)
+	if shutdownErr := t.exporter.Shutdown(ctx); err == nil {
+		err = shutdownErr
+	}
+	return err
+}
+
+// Close shuts the tracer down giving the exporter at most 10 seconds, so that it can be registered as a closer of
+// the server
+func (t *Tracer) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return t.Shutdown(ctx)
+}
@@ -0,0 +1,174 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pedrorochaorg/contactsApi/tracing"
+	"github.com/pedrorochaorg/contactsApi/tracing/tracingtest"
+)
+
+func TestTracer(t *testing.T) {
+
+	t.Run("children share the trace of their parent", func(t *testing.T) {
+		exporter := &tracingtest.Recorder{}
+		tracer := tracing.NewTracer(exporter)
+
+		ctx, parent := tracer.Start(context.Background(), "GET /users", tracing.WithKind(tracing.KindServer))
+		_, child := tracing.Start(ctx, "UserRepository.List")
+		child.SetAttribute("db.rows", 2)
+		child.SetAttribute("db.rows", 3)
+		child.SetError(errors.New("timeout"))
+		child.End()
+		parent.End()
+		parent.End()
+
+		assert.NoError(t, tracer.Shutdown(context.Background()))
+		assert.True(t, exporter.Shut(), "The exporter is shut down with the tracer")
+
+		spans := exporter.Spans()
+		if !assert.Len(t, spans, 2, "Every span is exported once") {
+			return
+		}
+		exportedChild, exportedParent := spans[0], spans[1]
+
+		assert.Equal(t, exportedParent.Context.TraceID, exportedChild.Context.TraceID)
+		assert.Equal(t, exportedParent.Context.SpanID, exportedChild.Parent)
+		assert.False(t, exportedParent.Parent.IsValid(), "The root span has no parent")
+		assert.Equal(t, tracing.KindServer, exportedParent.Kind)
+		assert.Equal(t, []tracing.Attribute{{Key: "db.rows", Value: 3}}, exportedChild.Attributes)
+		assert.Equal(t, tracing.StatusError, exportedChild.Status)
+		assert.Equal(t, "timeout", exportedChild.StatusMessage)
+	})
+
+	t.Run("spans aren't started without a parent span", func(t *testing.T) {
+		ctx, span := tracing.Start(context.Background(), "UserRepository.List")
+
+		assert.Nil(t, span)
+		assert.Nil(t, tracing.SpanFromContext(ctx))
+
+		// Every method of a nil span is safe to call
+		span.SetAttribute("db.rows", 1)
+		span.SetError(errors.New("timeout"))
+		span.End()
+		assert.False(t, span.SpanContext().IsValid())
+	})
+
+	t.Run("spans of traces that weren't sampled aren't exported", func(t *testing.T) {
+		exporter := &tracingtest.Recorder{}
+		tracer := tracing.NewTracer(exporter)
+
+		header := http.Header{}
+		header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+		_, span := tracer.Start(tracing.Extract(context.Background(), header), "GET /users")
+		span.End()
+
+		assert.NoError(t, tracer.Shutdown(context.Background()))
+		assert.Empty(t, exporter.Spans())
+	})
+
+	t.Run("full batches are exported without waiting for the timeout", func(t *testing.T) {
+		exporter := &tracingtest.Recorder{}
+		tracer := tracing.NewTracer(exporter, tracing.WithBatchSize(2), tracing.WithBatchTimeout(time.Hour))
+		defer tracer.Shutdown(context.Background())
+
+		for i := 0; i < 2; i++ {
+			_, span := tracer.Start(context.Background(), "GET /users")
+			span.End()
+		}
+
+		assert.Eventually(t, func() bool {
+			return len(exporter.Spans()) == 2
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestWriterExporter(t *testing.T) {
+
+	start := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+	clock := start
+
+	out := &bytes.Buffer{}
+	tracer := tracing.NewTracer(tracing.NewWriterExporter(out), tracing.WithClock(func() time.Time {
+		clock = clock.Add(time.Millisecond)
+		return clock
+	}))
+
+	ctx, parent := tracer.Start(context.Background(), "GET /users/{id:int}", tracing.WithKind(tracing.KindServer))
+	_, child := tracing.Start(ctx, "UserRepository.Get")
+	child.SetAttribute("db.statement", "SELECT 1")
+	child.End()
+	parent.End()
+
+	assert.NoError(t, tracer.Shutdown(context.Background()))
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	if !assert.Len(t, lines, 2) {
+		return
+	}
+
+	written := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(lines[0], &written))
+	assert.Equal(t, "UserRepository.Get", written["name"])
+	assert.Equal(t, "internal", written["kind"])
+	assert.Equal(t, parent.SpanContext().SpanID.String(), written["parent_span_id"])
+	assert.Equal(t, map[string]interface{}{"db.statement": "SELECT 1"}, written["attributes"])
+	assert.Equal(t, float64(1), written["duration_ms"])
+	assert.Equal(t, "unset", written["status"])
+}
+
+func TestOTLPExporter(t *testing.T) {
+
+	var received map[string]interface{}
+	var headers http.Header
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		body, _ := ioutil.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	exporter := tracing.NewOTLPExporter(collector.URL+"/v1/traces",
+		tracing.WithHeaders(map[string]string{"Authorization": "Bearer token"}), tracing.WithServiceName("contacts"))
+	tracer := tracing.NewTracer(exporter)
+
+	_, span := tracer.Start(context.Background(), "GET /users", tracing.WithKind(tracing.KindServer),
+		tracing.WithAttributes(tracing.Attribute{Key: "http.response.status_code", Value: 200}))
+	span.End()
+
+	assert.NoError(t, tracer.Shutdown(context.Background()))
+	assert.Equal(t, "application/json", headers.Get("Content-Type"))
+	assert.Equal(t, "Bearer token", headers.Get("Authorization"))
+
+	resourceSpans := received["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"attributes": []interface{}{map[string]interface{}{
+		"key": "service.name", "value": map[string]interface{}{"stringValue": "contacts"}}}},
+		resourceSpans["resource"])
+
+	scopeSpans := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})
+	exported := scopeSpans["spans"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, span.SpanContext().TraceID.String(), exported["traceId"])
+	assert.Equal(t, "GET /users", exported["name"])
+	assert.Equal(t, float64(tracing.KindServer), exported["kind"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"key": "http.response.status_code",
+		"value": map[string]interface{}{"intValue": "200"}}}, exported["attributes"])
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	err := tracing.NewOTLPExporter(failing.URL).Export(context.Background(), []tracing.SpanData{{Name: "GET /"}})
+	assert.Error(t, err, "Replies other than 2xx are errors")
+}
@@ -0,0 +1,48 @@
+// Package tracingtest helps testing the code that records spans: a Recorder exports them to memory, where the tests
+// can look at them once the tracer flushed them.
+package tracingtest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pedrorochaorg/contactsApi/tracing"
+)
+
+// Recorder exporter that keeps the spans it's given in memory, in the order they were exported. It's safe for
+// concurrent use, as the tracer exports from it's own goroutine.
+type Recorder struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+	shut  bool
+}
+
+func (r *Recorder) Export(ctx context.Context, spans []tracing.SpanData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func (r *Recorder) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.shut = true
+	return nil
+}
+
+// Spans returns a copy of the spans exported so far
+func (r *Recorder) Spans() []tracing.SpanData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	spans := make([]tracing.SpanData, len(r.spans))
+	copy(spans, r.spans)
+	return spans
+}
+
+// Shut reports whether the recorder was shut down, which the tracer does when it's shut down
+func (r *Recorder) Shut() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.shut
+}